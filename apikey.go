@@ -0,0 +1,261 @@
+package accessgo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// apiKeyTokenPrefix - префикс, по которому API ключ можно отличить от других токенов
+const apiKeyTokenPrefix = "ag"
+
+// CreateServiceAccount создает сервисный аккаунт без пароля для машинных клиентов
+func (s *AccessGoService) CreateServiceAccount(email, name string) (*User, error) {
+	return s.CreateServiceAccountCtx(s.ctx, email, name)
+}
+
+// CreateServiceAccountCtx - CreateServiceAccount с контекстом ctx
+func (s *AccessGoService) CreateServiceAccountCtx(ctx context.Context, email, name string) (*User, error) {
+	s = s.WithContext(ctx)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		Email:         email,
+		EmailValidate: true,
+		Password:      string(hashedPassword),
+		Name:          name,
+		UserType:      string(UserTypeService),
+		Source:        string(UserSourceLocal),
+	}
+
+	err = s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(UserCreated{User: newEventUser(user)}); err != nil {
+			return err
+		}
+		if err := tx.db.Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(UserCreated{User: newEventUser(user)}); err != nil {
+			return err
+		}
+		return tx.audit(AuditUserCreate, AuditTargetUser, user.ID, nil, userSnapshot(user))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CreateAPIKey создает API ключ пользователя.
+// Если scopes не указаны, ключ получает все права пользователя, иначе - только указанные.
+// Возвращает токен (показывается один раз) и сохраненный ключ
+func (s *AccessGoService) CreateAPIKey(userID uint, name string, expiresAt *time.Time, scopes ...string) (string, *APIKey, error) {
+	return s.CreateAPIKeyCtx(s.ctx, userID, name, expiresAt, scopes...)
+}
+
+// CreateAPIKeyCtx - CreateAPIKey с контекстом ctx
+func (s *AccessGoService) CreateAPIKeyCtx(ctx context.Context, userID uint, name string, expiresAt *time.Time, scopes ...string) (string, *APIKey, error) {
+	s = s.WithContext(ctx)
+	if _, err := s.GetUserByID(userID); err != nil {
+		return "", nil, err
+	}
+
+	var accesses []Access
+	if len(scopes) > 0 {
+		userAccesses, err := s.GetUserSummaryAccessLevels(userID)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(userAccesses, scope) {
+				return "", nil, errors.New("у пользователя нет права доступа " + scope)
+			}
+		}
+		if err := s.db.Where("name IN ?", scopes).Find(&accesses).Error; err != nil {
+			return "", nil, err
+		}
+	}
+
+	prefix, err := randomToken(4)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	apiKey := &APIKey{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: hashToken(secret),
+		ExpiresAt:  expiresAt,
+		Scoped:     len(scopes) > 0,
+		Scopes:     accesses,
+	}
+
+	err = s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Create(apiKey).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditAPIKeyCreate, AuditTargetAPIKey, apiKey.ID, nil, apiKeySnapshot(apiKey))
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return apiKeyTokenPrefix + "_" + prefix + "_" + secret, apiKey, nil
+}
+
+// ListAPIKeys возвращает API ключи пользователя
+func (s *AccessGoService) ListAPIKeys(userID uint) ([]APIKey, error) {
+	return s.ListAPIKeysCtx(s.ctx, userID)
+}
+
+// ListAPIKeysCtx - ListAPIKeys с контекстом ctx
+func (s *AccessGoService) ListAPIKeysCtx(ctx context.Context, userID uint) ([]APIKey, error) {
+	s = s.WithContext(ctx)
+	var keys []APIKey
+	if err := s.db.Preload("Scopes").Where("user_id = ?", userID).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает API ключ пользователя
+func (s *AccessGoService) RevokeAPIKey(userID, keyID uint) error {
+	return s.RevokeAPIKeyCtx(s.ctx, userID, keyID)
+}
+
+// RevokeAPIKeyCtx - RevokeAPIKey с контекстом ctx
+func (s *AccessGoService) RevokeAPIKeyCtx(ctx context.Context, userID, keyID uint) error {
+	s = s.WithContext(ctx)
+	var apiKey APIKey
+	if err := s.db.Preload("Scopes").Where("user_id = ?", userID).First(&apiKey, keyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("API ключ не найден")
+		}
+		return err
+	}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Delete(&apiKey).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditAPIKeyRevoke, AuditTargetAPIKey, apiKey.ID, apiKeySnapshot(&apiKey), nil)
+	})
+}
+
+// AuthenticateAPIKey проверяет API ключ и возвращает его владельца и эффективные права ключа.
+// Эффективные права - пересечение прав ключа с текущими правами пользователя
+func (s *AccessGoService) AuthenticateAPIKey(token string) (*User, []string, error) {
+	return s.AuthenticateAPIKeyCtx(s.ctx, token)
+}
+
+// AuthenticateAPIKeyCtx - AuthenticateAPIKey с контекстом ctx
+func (s *AccessGoService) AuthenticateAPIKeyCtx(ctx context.Context, token string) (*User, []string, error) {
+	s = s.WithContext(ctx)
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTokenPrefix {
+		return nil, nil, errors.New("неверный формат API ключа")
+	}
+
+	var apiKey APIKey
+	if err := s.db.Preload("User").Preload("Scopes").Where("prefix = ?", parts[1]).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("API ключ не найден")
+		}
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.SecretHash), []byte(hashToken(parts[2]))) != 1 {
+		return nil, nil, errors.New("API ключ не найден")
+	}
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		return nil, nil, errors.New("срок действия API ключа истек")
+	}
+	if apiKey.User.UserType == string(UserTypeBlocked) {
+		return nil, nil, errors.New("пользователь заблокирован")
+	}
+
+	userAccesses, err := s.GetUserSummaryAccessLevels(apiKey.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Ключ, созданный до появления Scoped, считается ограниченным, если у него есть Scopes
+	scopes := userAccesses
+	if apiKey.Scoped || len(apiKey.Scopes) > 0 {
+		scopes = make([]string, 0, len(apiKey.Scopes))
+		for _, access := range apiKey.Scopes {
+			if slices.Contains(userAccesses, access.Name) {
+				scopes = append(scopes, access.Name)
+			}
+		}
+	}
+
+	if err := s.db.Model(&apiKey).UpdateColumn("last_used_at", time.Now()).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &apiKey.User, scopes, nil
+}
+
+// CheckScopedAccess проверяет право доступа пользователя с учетом ограничений API ключа.
+// scopes - эффективные права, полученные из AuthenticateAPIKey
+func (s *AccessGoService) CheckScopedAccess(userID uint, scopes []string, accessName string) (bool, error) {
+	return s.CheckScopedAccessCtx(s.ctx, userID, scopes, accessName)
+}
+
+// CheckScopedAccessCtx - CheckScopedAccess с контекстом ctx
+func (s *AccessGoService) CheckScopedAccessCtx(ctx context.Context, userID uint, scopes []string, accessName string) (bool, error) {
+	s = s.WithContext(ctx)
+	if !slices.Contains(scopes, accessName) {
+		return false, nil
+	}
+	return s.CheckUserAccess(userID, accessName)
+}
+
+func apiKeySnapshot(apiKey *APIKey) map[string]interface{} {
+	scopes := make([]string, 0, len(apiKey.Scopes))
+	for _, access := range apiKey.Scopes {
+		scopes = append(scopes, access.Name)
+	}
+	snapshot := map[string]interface{}{
+		"user_id": apiKey.UserID,
+		"name":    apiKey.Name,
+		"prefix":  apiKey.Prefix,
+		"scopes":  scopes,
+	}
+	if apiKey.ExpiresAt != nil {
+		snapshot["expires_at"] = apiKey.ExpiresAt
+	}
+	return snapshot
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,107 @@
+package accessgo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndAuthenticateAPIKey(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+
+	account, err := service.CreateServiceAccount("ci@service.local", "CI")
+	require.NoError(t, err)
+	assert.Equal(t, string(UserTypeService), account.UserType)
+
+	require.NoError(t, service.AddUserAccessLevel(account.ID, "user:read"))
+	require.NoError(t, service.AddUserAccessLevel(account.ID, "user:update"))
+
+	token, key, err := service.CreateAPIKey(account.ID, "deploy", nil)
+	require.NoError(t, err)
+	assert.Contains(t, token, key.Prefix)
+	assert.NotContains(t, key.SecretHash, token)
+
+	user, scopes, err := service.AuthenticateAPIKey(token)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, user.ID)
+	assert.ElementsMatch(t, []string{"user:read", "user:update"}, scopes)
+
+	keys, err := service.ListAPIKeys(account.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].LastUsedAt)
+
+	// Неверный секрет
+	_, _, err = service.AuthenticateAPIKey(token + "0")
+	assert.Error(t, err)
+	_, _, err = service.AuthenticateAPIKey("garbage")
+	assert.Error(t, err)
+}
+
+func TestScopedAPIKey(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+
+	user, err := service.CreateUser("scoped@example.com", "password", "Scoped User", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "user:read"))
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "user:delete"))
+
+	// Нельзя выдать ключу право, которого нет у пользователя
+	_, _, err = service.CreateAPIKey(user.ID, "bad", nil, "group:delete")
+	assert.Error(t, err)
+
+	token, _, err := service.CreateAPIKey(user.ID, "read-only", nil, "user:read")
+	require.NoError(t, err)
+
+	_, scopes, err := service.AuthenticateAPIKey(token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:read"}, scopes)
+
+	hasAccess, err := service.CheckScopedAccess(user.ID, scopes, "user:read")
+	require.NoError(t, err)
+	assert.True(t, hasAccess)
+
+	hasAccess, err = service.CheckScopedAccess(user.ID, scopes, "user:delete")
+	require.NoError(t, err)
+	assert.False(t, hasAccess)
+
+	// После отзыва права у пользователя ключ его тоже теряет
+	require.NoError(t, service.RemoveUserAccessLevel(user.ID, "user:read"))
+	_, scopes, err = service.AuthenticateAPIKey(token)
+	require.NoError(t, err)
+	assert.Empty(t, scopes)
+
+	// Ключ, все права которого удалены, не получает права пользователя
+	access, err := service.CreateAccess("tmp:scope", "")
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "tmp:scope"))
+	token, apiKey, err := service.CreateAPIKey(user.ID, "tmp", nil, "tmp:scope")
+	require.NoError(t, err)
+	assert.True(t, apiKey.Scoped)
+	require.NoError(t, service.DeleteAccess(access.ID))
+	_, scopes, err = service.AuthenticateAPIKey(token)
+	require.NoError(t, err)
+	assert.Empty(t, scopes)
+}
+
+func TestExpiredAndRevokedAPIKey(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+
+	user, err := service.CreateUser("expired@example.com", "password", "Expired User", UserTypeUser)
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(-time.Minute)
+	token, _, err := service.CreateAPIKey(user.ID, "old", &expiresAt)
+	require.NoError(t, err)
+	_, _, err = service.AuthenticateAPIKey(token)
+	assert.Error(t, err)
+
+	token, key, err := service.CreateAPIKey(user.ID, "revoked", nil)
+	require.NoError(t, err)
+	require.NoError(t, service.RevokeAPIKey(user.ID, key.ID))
+	_, _, err = service.AuthenticateAPIKey(token)
+	assert.Error(t, err)
+	assert.Error(t, service.RevokeAPIKey(user.ID, key.ID))
+}
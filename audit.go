@@ -0,0 +1,698 @@
+package accessgo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuditAction - тип действия, записанного в журнал аудита
+type AuditAction string
+
+const (
+	AuditUserCreate        AuditAction = "user.create"
+	AuditUserUpdate        AuditAction = "user.update"
+	AuditUserDelete        AuditAction = "user.delete"
+	AuditUserValidateEmail AuditAction = "user.validate_email"
+	AuditUserGroupsSet     AuditAction = "user.groups_set"
+	AuditUserAccessGrant   AuditAction = "user.access_grant"
+	AuditUserAccessRevoke  AuditAction = "user.access_revoke"
+	AuditGroupCreate       AuditAction = "group.create"
+	AuditGroupUpdate       AuditAction = "group.update"
+	AuditGroupDelete       AuditAction = "group.delete"
+	AuditGroupMemberAdd    AuditAction = "group.member_add"
+	AuditGroupMemberRemove AuditAction = "group.member_remove"
+	AuditGroupAccessGrant  AuditAction = "group.access_grant"
+	AuditGroupAccessRevoke AuditAction = "group.access_revoke"
+	AuditAccessCreate      AuditAction = "access.create"
+	AuditAccessUpdate      AuditAction = "access.update"
+	AuditAccessDelete      AuditAction = "access.delete"
+	AuditAPIKeyCreate      AuditAction = "api_key.create"
+	AuditAPIKeyRevoke      AuditAction = "api_key.revoke"
+	AuditInvitationCreate  AuditAction = "invitation.create"
+	AuditInvitationAccept  AuditAction = "invitation.accept"
+	AuditInvitationRevoke  AuditAction = "invitation.revoke"
+	AuditLoginSuccess      AuditAction = "auth.login"
+	AuditLoginFailure      AuditAction = "auth.login_failed"
+	AuditSessionCreate     AuditAction = "session.create"
+	AuditSessionRevoke     AuditAction = "session.revoke"
+	AuditWebhookCreate     AuditAction = "webhook.create"
+	AuditWebhookUpdate     AuditAction = "webhook.update"
+	AuditWebhookDelete     AuditAction = "webhook.delete"
+)
+
+// Типы объектов, над которыми выполняются действия
+const (
+	AuditTargetUser    = "user"
+	AuditTargetGroup   = "group"
+	AuditTargetAccess  = "access"
+	AuditTargetAPIKey  = "api_key"
+	AuditTargetSession = "session"
+	AuditTargetWebhook = "webhook"
+	// AuditTargetEmail используется для неудачных входов с неизвестным email
+	AuditTargetEmail = "email"
+)
+
+// Способы входа, записываемые в Details событий auth.*
+const (
+	LoginMethodPassword = "password"
+	LoginMethodPasskey  = "passkey"
+	LoginMethodOIDC     = "oidc"
+)
+
+// Виды нарушений цепочки журнала аудита
+const (
+	// AuditProblemModified - содержимое записи не соответствует ее хешу
+	AuditProblemModified = "modified"
+	// AuditProblemGap - пропущены номера записей
+	AuditProblemGap = "gap"
+	// AuditProblemBrokenLink - запись ссылается не на хеш предыдущей записи
+	AuditProblemBrokenLink = "broken_link"
+	// AuditProblemTruncated - последние записи журнала удалены или заменены:
+	// они не совпадают с AuditChainHead
+	AuditProblemTruncated = "truncated"
+)
+
+// AuditEvent представляет запись журнала аудита.
+// Записи только добавляются: изменение и удаление запрещены хуками модели.
+// Каждая запись содержит хеш предыдущей, поэтому изменение или удаление записи
+// в обход сервиса обнаруживается VerifyAuditChain
+type AuditEvent struct {
+	ID         uint        `gorm:"primaryKey" json:"-"`
+	Sequence   uint64      `gorm:"not null;uniqueIndex:idx_audit_sequence" json:"sequence"`
+	CreatedAt  time.Time   `gorm:"not null;index:idx_audit_created_at" json:"created_at"`
+	ActorID    *uint       `gorm:"index:idx_audit_actor" json:"actor_id"` // nil - системное действие
+	Action     AuditAction `gorm:"size:64;not null;index:idx_audit_action" json:"action"`
+	TargetType string      `gorm:"size:32;not null;index:idx_audit_target" json:"target_type"`
+	TargetID   string      `gorm:"size:255;index:idx_audit_target" json:"target_id"`
+	Success    bool        `gorm:"not null" json:"success"`
+	Before     string      `gorm:"type:text" json:"before,omitempty"` // JSON состояния до изменения
+	After      string      `gorm:"type:text" json:"after,omitempty"`  // JSON состояния после изменения
+	Details    string      `gorm:"type:text" json:"details,omitempty"`
+	RequestID  string      `gorm:"size:64;index:idx_audit_request" json:"request_id,omitempty"`
+	PrevHash   string      `gorm:"size:64" json:"prev_hash"`
+	Hash       string      `gorm:"size:64;not null" json:"hash"`
+}
+
+// AuditChainHead - номер и хеш последней записи журнала аудита. Единственная
+// строка блокируется (SELECT ... FOR UPDATE) до фиксации транзакции, добавляющей
+// запись, поэтому экземпляры сервиса с общей базой не выдают один номер дважды
+type AuditChainHead struct {
+	ID       uint   `gorm:"primaryKey"`
+	Sequence uint64 `gorm:"not null"`
+	Hash     string `gorm:"size:64"`
+}
+
+// auditChainHeadID - идентификатор строки AuditChainHead
+const auditChainHeadID = 1
+
+// AuditChainReport - результат проверки цепочки журнала аудита
+type AuditChainReport struct {
+	Checked       int                 `json:"checked"`
+	FirstSequence uint64              `json:"first_sequence"`
+	LastSequence  uint64              `json:"last_sequence"`
+	LastHash      string              `json:"last_hash"` // сохраненный вне базы, позволяет обнаружить удаление последних записей
+	Problems      []AuditChainProblem `json:"problems"`
+}
+
+// AuditChainProblem описывает нарушение цепочки у записи Sequence
+type AuditChainProblem struct {
+	Sequence uint64 `json:"sequence"`
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+}
+
+// Valid сообщает, что нарушений не найдено
+func (r *AuditChainReport) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// auditChain связывает записи журнала в цепочку хешей. Общий для копий сервиса
+type auditChain struct {
+	// mu упорядочивает добавление записей внутри процесса. Между процессами
+	// их упорядочивает блокировка строки AuditChainHead
+	mu  sync.Mutex
+	key []byte
+	// hookGoroutine - номер горутины, в которой под блокировкой mu выполняется
+	// обработчик BeforeHook, или 0
+	hookGoroutine atomic.Uint64
+}
+
+// lock захватывает mu. Вызов из обработчика BeforeHook ждал бы фиксации транзакции,
+// которая удерживает mu и сама ждет обработчик, поэтому он сразу получает ErrBeforeHookCall
+func (c *auditChain) lock() error {
+	if id := c.hookGoroutine.Load(); id != 0 && id == goroutineID() {
+		return ErrBeforeHookCall
+	}
+	c.mu.Lock()
+	return nil
+}
+
+// AuditChange - изменение одного поля объекта
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditQuery задает фильтр журнала аудита. Пустые поля не ограничивают выборку
+type AuditQuery struct {
+	ActorID    *uint
+	TargetType string
+	TargetID   string
+	Action     AuditAction
+	From       time.Time // включительно
+	To         time.Time // не включительно
+	Limit      int
+	Offset     int
+}
+
+var errAuditAppendOnly = errors.New("журнал аудита не допускает изменения записей")
+
+// BeforeUpdate запрещает изменение записей журнала
+func (e *AuditEvent) BeforeUpdate(*gorm.DB) error {
+	return errAuditAppendOnly
+}
+
+// BeforeDelete запрещает удаление записей журнала
+func (e *AuditEvent) BeforeDelete(*gorm.DB) error {
+	return errAuditAppendOnly
+}
+
+// Diff возвращает поля, отличающиеся в состояниях Before и After
+func (e *AuditEvent) Diff() (map[string]AuditChange, error) {
+	before, err := decodeAuditState(e.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeAuditState(e.After)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]AuditChange)
+	for key, value := range before {
+		if !reflect.DeepEqual(value, after[key]) {
+			changes[key] = AuditChange{Before: value, After: after[key]}
+		}
+	}
+	for key, value := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = AuditChange{After: value}
+		}
+	}
+	return changes, nil
+}
+
+type actorContextKey struct{}
+type requestIDContextKey struct{}
+
+// ContextWithActor возвращает контекст с пользователем, от имени которого выполняются изменения
+func ContextWithActor(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, userID)
+}
+
+// ActorFromContext возвращает инициатора изменений: пользователя из ContextWithActor,
+// а если он не задан - пользователя сессии из ContextWithSession
+func ActorFromContext(ctx context.Context) (uint, bool) {
+	if userID, ok := ctx.Value(actorContextKey{}).(uint); ok {
+		return userID, true
+	}
+	return UserIDFromContext(ctx)
+}
+
+// ContextWithRequestID возвращает контекст с идентификатором запроса
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext возвращает идентификатор запроса из ContextWithRequestID
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// QueryAuditLog возвращает записи журнала аудита по фильтру, начиная с новых
+func (s *AccessGoService) QueryAuditLog(q AuditQuery) ([]AuditEvent, error) {
+	return s.QueryAuditLogCtx(s.ctx, q)
+}
+
+// QueryAuditLogCtx - QueryAuditLog с контекстом ctx
+func (s *AccessGoService) QueryAuditLogCtx(ctx context.Context, q AuditQuery) ([]AuditEvent, error) {
+	s = s.WithContext(ctx)
+	var events []AuditEvent
+	if err := s.auditFilter(q).Order("sequence DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ExportAuditLog записывает в w записи журнала по фильтру в формате JSON Lines
+// в порядке цепочки, включая хеши, для архивирования. Limit и Offset не учитываются
+func (s *AccessGoService) ExportAuditLog(w io.Writer, q AuditQuery) error {
+	return s.ExportAuditLogCtx(s.ctx, w, q)
+}
+
+// ExportAuditLogCtx - ExportAuditLog с контекстом ctx
+func (s *AccessGoService) ExportAuditLogCtx(ctx context.Context, w io.Writer, q AuditQuery) error {
+	s = s.WithContext(ctx)
+	encoder := json.NewEncoder(w)
+	return s.eachAuditEvent(q, func(event *AuditEvent) error {
+		return encoder.Encode(event)
+	})
+}
+
+// SetAuditHMACKey включает подпись записей журнала HMAC-SHA256 с ключом key.
+// Без ключа хеш записи можно пересчитать после подделки, с ключом - нет.
+// Ключ задается до появления записей и должен быть одинаковым у всех экземпляров
+func (s *AccessGoService) SetAuditHMACKey(key []byte) {
+	s.auditChain.mu.Lock()
+	defer s.auditChain.mu.Unlock()
+	s.auditChain.key = key
+}
+
+// VerifyAuditChain проверяет записи журнала, созданные в интервале [from, to):
+// соответствие содержимого хешу, непрерывность номеров и ссылки на предыдущие записи.
+// Если интервал включает конец журнала, последняя запись сверяется с AuditChainHead.
+// Нулевые from и to не ограничивают интервал
+func (s *AccessGoService) VerifyAuditChain(from, to time.Time) (*AuditChainReport, error) {
+	return s.VerifyAuditChainCtx(s.ctx, from, to)
+}
+
+// VerifyAuditChainCtx - VerifyAuditChain с контекстом ctx
+func (s *AccessGoService) VerifyAuditChainCtx(ctx context.Context, from, to time.Time) (*AuditChainReport, error) {
+	s = s.WithContext(ctx)
+	// В транзакции блокировку уже удерживает transaction
+	if !s.inTransaction {
+		if err := s.auditChain.lock(); err != nil {
+			return nil, err
+		}
+		defer s.auditChain.mu.Unlock()
+	}
+	key := s.auditChain.key
+
+	// Голова читается до записей: добавленные позже записи только продлевают журнал
+	var heads []AuditChainHead
+	if err := s.db.Limit(1).Find(&heads, auditChainHeadID).Error; err != nil {
+		return nil, err
+	}
+
+	report := &AuditChainReport{Problems: []AuditChainProblem{}}
+	var prev *AuditEvent
+	err := s.eachAuditEvent(AuditQuery{From: from, To: to}, func(event *AuditEvent) error {
+		if report.Checked == 0 {
+			report.FirstSequence = event.Sequence
+			var err error
+			if prev, err = s.previousAuditEvent(event.Sequence); err != nil {
+				return err
+			}
+		}
+		report.Checked++
+		report.LastSequence = event.Sequence
+		report.LastHash = event.Hash
+
+		if auditHash(key, event) != event.Hash {
+			report.problem(event.Sequence, AuditProblemModified, "содержимое записи не соответствует хешу")
+		}
+		expected := uint64(1)
+		prevHash := ""
+		if prev != nil {
+			expected = prev.Sequence + 1
+			prevHash = prev.Hash
+		}
+		switch {
+		case event.Sequence != expected:
+			report.problem(event.Sequence, AuditProblemGap,
+				fmt.Sprintf("отсутствуют записи %d-%d", expected, event.Sequence-1))
+		case event.PrevHash != prevHash:
+			report.problem(event.Sequence, AuditProblemBrokenLink, "запись ссылается не на предыдущую запись")
+		}
+		prev = event
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(heads) > 0 {
+		if err := s.verifyAuditChainTail(report, &heads[0], to); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// verifyAuditChainTail сверяет последнюю запись журнала с head, если интервал
+// проверки до to включает конец журнала. Так обнаруживается удаление последних
+// записей, которое не оставляет разрывов в цепочке
+func (s *AccessGoService) verifyAuditChainTail(report *AuditChainReport, head *AuditChainHead, to time.Time) error {
+	if !to.IsZero() {
+		var later int64
+		if err := s.db.Model(&AuditEvent{}).Where("created_at >= ?", to).Count(&later).Error; err != nil {
+			return err
+		}
+		if later > 0 {
+			return nil
+		}
+	}
+	last, err := s.previousAuditEvent(math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	var lastSequence uint64
+	lastHash := ""
+	if last != nil {
+		lastSequence, lastHash = last.Sequence, last.Hash
+	}
+	switch {
+	case lastSequence < head.Sequence:
+		report.problem(head.Sequence, AuditProblemTruncated,
+			fmt.Sprintf("отсутствуют последние записи %d-%d", lastSequence+1, head.Sequence))
+	case lastSequence == head.Sequence && lastHash != head.Hash:
+		report.problem(head.Sequence, AuditProblemTruncated, "последняя запись не совпадает с AuditChainHead")
+	}
+	return nil
+}
+
+// auditBatchSize - количество записей, читаемых за один запрос при обходе журнала
+const auditBatchSize = 500
+
+// eachAuditEvent вызывает fn для записей журнала по фильтру в порядке цепочки.
+// Записи читаются частями, Limit и Offset фильтра не учитываются
+func (s *AccessGoService) eachAuditEvent(q AuditQuery, fn func(event *AuditEvent) error) error {
+	q.Limit, q.Offset = 0, 0
+	var last uint64
+	for {
+		var batch []AuditEvent
+		err := s.auditFilter(q).Where("sequence > ?", last).Order("sequence ASC").Limit(auditBatchSize).Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < auditBatchSize {
+			return nil
+		}
+		last = batch[len(batch)-1].Sequence
+	}
+}
+
+func (r *AuditChainReport) problem(sequence uint64, kind, message string) {
+	r.Problems = append(r.Problems, AuditChainProblem{Sequence: sequence, Kind: kind, Message: message})
+}
+
+// previousAuditEvent возвращает запись, предшествующую sequence, или nil
+func (s *AccessGoService) previousAuditEvent(sequence uint64) (*AuditEvent, error) {
+	var events []AuditEvent
+	if err := s.db.Where("sequence < ?", sequence).Order("sequence DESC").Limit(1).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &events[0], nil
+}
+
+func (s *AccessGoService) auditFilter(q AuditQuery) *gorm.DB {
+	query := s.db.Model(&AuditEvent{})
+	if q.ActorID != nil {
+		query = query.Where("actor_id = ?", *q.ActorID)
+	}
+	if q.TargetType != "" {
+		query = query.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetID != "" {
+		query = query.Where("target_id = ?", q.TargetID)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if !q.From.IsZero() {
+		query = query.Where("created_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		query = query.Where("created_at < ?", q.To)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+	if q.Offset > 0 {
+		query = query.Offset(q.Offset)
+	}
+	return query
+}
+
+// audit записывает успешное изменение объекта. before и after - снимки состояния,
+// nil означает отсутствие объекта до или после изменения
+func (s *AccessGoService) audit(action AuditAction, targetType string, targetID uint, before, after map[string]interface{}) error {
+	event := &AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   strconv.FormatUint(uint64(targetID), 10),
+		Success:    true,
+	}
+	var err error
+	if event.Before, err = encodeAuditState(before); err != nil {
+		return err
+	}
+	if event.After, err = encodeAuditState(after); err != nil {
+		return err
+	}
+	return s.recordAudit(event)
+}
+
+// auditLogin записывает попытку входа. user может быть nil, если email не найден
+func (s *AccessGoService) auditLogin(method, email string, user *User, loginErr error) error {
+	event := &AuditEvent{
+		Action:     AuditLoginSuccess,
+		TargetType: AuditTargetEmail,
+		TargetID:   email,
+		Success:    loginErr == nil,
+	}
+	if user != nil {
+		event.TargetType = AuditTargetUser
+		event.TargetID = strconv.FormatUint(uint64(user.ID), 10)
+		if loginErr == nil {
+			event.ActorID = &user.ID
+		}
+	}
+	details := map[string]interface{}{"method": method}
+	if email != "" {
+		details["email"] = email
+	}
+	if loginErr != nil {
+		event.Action = AuditLoginFailure
+		details["error"] = loginErr.Error()
+	}
+	var err error
+	if event.Details, err = encodeAuditState(details); err != nil {
+		return err
+	}
+	return s.recordAudit(event)
+}
+
+// auditSession записывает создание или отзыв сессии. Вместо идентификатора
+// сессии сохраняется его хеш, чтобы журнал нельзя было использовать для входа
+func (s *AccessGoService) auditSession(action AuditAction, session Session) error {
+	userID := uint(session.UserID)
+	return s.recordAudit(&AuditEvent{
+		ActorID:    &userID,
+		Action:     action,
+		TargetType: AuditTargetSession,
+		TargetID:   hashToken(session.ID),
+		Success:    true,
+	})
+}
+
+func (s *AccessGoService) recordAudit(event *AuditEvent) error {
+	if event.ActorID == nil {
+		if actorID, ok := ActorFromContext(s.ctx); ok {
+			event.ActorID = &actorID
+		}
+	}
+	if event.RequestID == "" {
+		event.RequestID = RequestIDFromContext(s.ctx)
+	}
+
+	// В транзакции блокировку уже удерживает transaction
+	if !s.inTransaction {
+		if err := s.auditChain.lock(); err != nil {
+			return err
+		}
+		defer s.auditChain.mu.Unlock()
+		return s.db.Transaction(func(db *gorm.DB) error {
+			clone := *s
+			clone.db = db
+			return clone.appendAudit(event)
+		})
+	}
+	return s.appendAudit(event)
+}
+
+// appendAudit добавляет запись в конец цепочки. Вызывается в транзакции:
+// строка AuditChainHead остается заблокированной до ее фиксации
+func (s *AccessGoService) appendAudit(event *AuditEvent) error {
+	head, err := s.lockAuditChainHead()
+	if err != nil {
+		return err
+	}
+	event.Sequence = head.Sequence + 1
+	event.PrevHash = head.Hash
+	// Время округляется до точности, которую хранят все поддерживаемые базы
+	event.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	event.Hash = auditHash(s.auditChain.key, event)
+	if err := s.db.Create(event).Error; err != nil {
+		return err
+	}
+	head.Sequence = event.Sequence
+	head.Hash = event.Hash
+	return s.db.Save(head).Error
+}
+
+// lockAuditChainHead читает и блокирует строку AuditChainHead. Если ее нет
+// (журнал создан до ее появления), она создается по последней записи журнала
+func (s *AccessGoService) lockAuditChainHead() (*AuditChainHead, error) {
+	var head AuditChainHead
+	err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, auditChainHeadID).Error
+	if err == nil {
+		return &head, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	head = AuditChainHead{ID: auditChainHeadID}
+	prev, err := s.previousAuditEvent(math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		head.Sequence = prev.Sequence
+		head.Hash = prev.Hash
+	}
+	// Другой экземпляр мог создать строку одновременно - тогда используется она
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&head).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, auditChainHeadID).Error; err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// auditHash вычисляет хеш записи: SHA-256 или HMAC-SHA256, если задан ключ
+func auditHash(key []byte, event *AuditEvent) string {
+	payload, _ := json.Marshal(struct {
+		Sequence   uint64      `json:"sequence"`
+		CreatedAt  string      `json:"created_at"`
+		ActorID    *uint       `json:"actor_id"`
+		Action     AuditAction `json:"action"`
+		TargetType string      `json:"target_type"`
+		TargetID   string      `json:"target_id"`
+		Success    bool        `json:"success"`
+		Before     string      `json:"before"`
+		After      string      `json:"after"`
+		Details    string      `json:"details"`
+		RequestID  string      `json:"request_id"`
+		PrevHash   string      `json:"prev_hash"`
+	}{
+		Sequence:   event.Sequence,
+		CreatedAt:  event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ActorID:    event.ActorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Success:    event.Success,
+		Before:     event.Before,
+		After:      event.After,
+		Details:    event.Details,
+		RequestID:  event.RequestID,
+		PrevHash:   event.PrevHash,
+	})
+	if len(key) > 0 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(payload)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func userSnapshot(user *User) map[string]interface{} {
+	return map[string]interface{}{
+		"email":          user.Email,
+		"name":           user.Name,
+		"user_type":      user.UserType,
+		"source":         user.Source,
+		"email_validate": user.EmailValidate,
+	}
+}
+
+func groupSnapshot(group *Group) map[string]interface{} {
+	return map[string]interface{}{"name": group.Name}
+}
+
+func accessSnapshot(access *Access) map[string]interface{} {
+	return map[string]interface{}{"name": access.Name, "description": access.Description}
+}
+
+func memberSnapshot(user *User) map[string]interface{} {
+	return map[string]interface{}{"user_id": user.ID, "email": user.Email}
+}
+
+func grantSnapshot(access *Access) map[string]interface{} {
+	return map[string]interface{}{"access": access.Name}
+}
+
+func conditionalGrantSnapshot(access *Access, condition string) map[string]interface{} {
+	snapshot := grantSnapshot(access)
+	if condition != "" {
+		snapshot["condition"] = condition
+	}
+	return snapshot
+}
+
+func userGroupsSnapshot(groups []Group) map[string]interface{} {
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return map[string]interface{}{"groups": names}
+}
+
+func encodeAuditState(state map[string]interface{}) (string, error) {
+	if state == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func decodeAuditState(data string) (map[string]interface{}, error) {
+	state := map[string]interface{}{}
+	if data == "" {
+		return state, nil
+	}
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,305 @@
+package accessgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuditRecordsMutationsWithActor(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	admin, err := service.CreateUser("admin@example.com", "password", "Admin", UserTypeAdmin)
+	require.NoError(t, err)
+
+	ctx := ContextWithRequestID(ContextWithActor(context.Background(), admin.ID), "req-1")
+	scoped := service.WithContext(ctx)
+
+	user, err := scoped.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	_, err = scoped.UpdateUser(user.ID, "bob@example.com", "", "Robert", UserTypeEmployee)
+	require.NoError(t, err)
+	require.NoError(t, scoped.AddUserAccessLevel(user.ID, "user:read"))
+	require.NoError(t, scoped.DeleteUser(user.ID))
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetUser, TargetID: strconv.Itoa(int(user.ID))})
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, AuditUserDelete, events[0].Action)
+	assert.Equal(t, AuditUserAccessGrant, events[1].Action)
+	assert.Equal(t, AuditUserCreate, events[3].Action)
+	for _, event := range events {
+		require.NotNil(t, event.ActorID)
+		assert.Equal(t, admin.ID, *event.ActorID)
+		assert.Equal(t, "req-1", event.RequestID)
+		assert.True(t, event.Success)
+	}
+
+	update := events[2]
+	assert.Equal(t, AuditUserUpdate, update.Action)
+	diff, err := update.Diff()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]AuditChange{
+		"name":      {Before: "Bob", After: "Robert"},
+		"user_type": {Before: "user", After: "employee"},
+	}, diff)
+	assert.NotContains(t, update.Before, "password")
+
+	// Действия без инициатора в контексте считаются системными
+	events, err = service.QueryAuditLog(AuditQuery{TargetID: strconv.Itoa(int(admin.ID)), TargetType: AuditTargetUser})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Nil(t, events[0].ActorID)
+
+	events, err = service.QueryAuditLog(AuditQuery{ActorID: &admin.ID})
+	require.NoError(t, err)
+	assert.Len(t, events, 4)
+}
+
+func TestAuditFailedMutationIsNotRecorded(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+
+	_, err := service.CreateGroup("Admins")
+	require.NoError(t, err)
+	_, err = service.CreateGroup("Admins")
+	require.Error(t, err)
+	require.Error(t, service.DeleteGroup(999))
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetGroup})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, AuditGroupCreate, events[0].Action)
+}
+
+func TestAuditLoginsAndSessions(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	require.NoError(t, service.CreateDefaultAdminUser("admin@example.com", "secret", "Admin"))
+	admin, err := service.GetUserByEmail("admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(admin.EmailValidationToken))
+
+	_, err = service.AuthenticateUser("admin@example.com", "wrong")
+	require.Error(t, err)
+	_, err = service.AuthenticateUser("ghost@example.com", "secret")
+	require.Error(t, err)
+	_, err = service.AuthenticateUser("admin@example.com", "secret")
+	require.NoError(t, err)
+
+	failures, err := service.QueryAuditLog(AuditQuery{Action: AuditLoginFailure})
+	require.NoError(t, err)
+	require.Len(t, failures, 2)
+	assert.Equal(t, AuditTargetEmail, failures[0].TargetType)
+	assert.Equal(t, "ghost@example.com", failures[0].TargetID)
+	assert.Equal(t, AuditTargetUser, failures[1].TargetType)
+	assert.Contains(t, failures[1].Details, "неверный пароль")
+	assert.False(t, failures[1].Success)
+
+	logins, err := service.QueryAuditLog(AuditQuery{Action: AuditLoginSuccess})
+	require.NoError(t, err)
+	require.Len(t, logins, 1)
+	assert.Equal(t, admin.ID, *logins[0].ActorID)
+
+	sessions := NewSessionService(context.Background())
+	defer sessions.Stop()
+	sessions.EnableAudit(service)
+	sessionID, err := sessions.CreateSession(int(admin.ID), false)
+	require.NoError(t, err)
+	sessions.DeleteSession(sessionID)
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetSession})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, AuditSessionRevoke, events[0].Action)
+	assert.Equal(t, AuditSessionCreate, events[1].Action)
+	assert.Equal(t, hashToken(sessionID), events[0].TargetID)
+}
+
+func TestAuditLogTimeRangeAndAppendOnly(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+
+	// Время записей хранится с точностью до миллисекунды
+	time.Sleep(2 * time.Millisecond)
+	start := time.Now().Truncate(time.Millisecond)
+	_, err := service.CreateAccess("report:read", "Чтение отчетов")
+	require.NoError(t, err)
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetAccess, From: start})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	events, err = service.QueryAuditLog(AuditQuery{TargetType: AuditTargetAccess, To: start})
+	require.NoError(t, err)
+	// Права, созданные SetupDefaultPermissions
+	assert.Len(t, events, 15)
+
+	event := events[0]
+	assert.Error(t, db.Model(&event).Update("action", "tampered").Error)
+	assert.Error(t, db.Delete(&event).Error)
+}
+
+func TestAuditChainDetectsTampering(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := service.CreateGroup(name)
+		require.NoError(t, err)
+	}
+
+	report, err := service.VerifyAuditChain(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, uint64(1), report.FirstSequence)
+	assert.Equal(t, 18, report.Checked)
+
+	groups, err := service.QueryAuditLog(AuditQuery{Action: AuditGroupCreate})
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+
+	// UpdateColumn обходит хуки модели, как и прямое изменение базы
+	require.NoError(t, db.Model(&groups[1]).UpdateColumn("after", `{"name":"z"}`).Error)
+	report, err = service.VerifyAuditChain(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, AuditChainProblem{Sequence: groups[1].Sequence, Kind: AuditProblemModified, Message: "содержимое записи не соответствует хешу"}, report.Problems[0])
+
+	require.NoError(t, db.Exec("DELETE FROM audit_events WHERE sequence = ?", groups[1].Sequence).Error)
+	report, err = service.VerifyAuditChain(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, AuditProblemGap, report.Problems[0].Kind)
+	assert.Equal(t, groups[0].Sequence, report.Problems[0].Sequence)
+
+	// Проверка части журнала учитывает предыдущую запись
+	report, err = service.VerifyAuditChain(groups[0].CreatedAt, time.Time{})
+	require.NoError(t, err)
+	assert.False(t, report.Valid())
+	assert.Equal(t, groups[0].Hash, report.LastHash)
+}
+
+func TestAuditChainDetectsTruncatedTail(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := service.CreateGroup(name)
+		require.NoError(t, err)
+	}
+	groups, err := service.QueryAuditLog(AuditQuery{Action: AuditGroupCreate})
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+	last := groups[0]
+
+	// Удаление последних записей не оставляет разрывов, его выдает только AuditChainHead
+	require.NoError(t, db.Exec("DELETE FROM audit_events WHERE sequence >= ?", groups[1].Sequence).Error)
+	report, err := service.VerifyAuditChain(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, AuditChainProblem{
+		Sequence: last.Sequence,
+		Kind:     AuditProblemTruncated,
+		Message:  fmt.Sprintf("отсутствуют последние записи %d-%d", groups[1].Sequence, last.Sequence),
+	}, report.Problems[0])
+
+	// Интервал, включающий конец журнала, тоже сверяется с головой
+	report, err = service.VerifyAuditChain(time.Time{}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, report.Valid())
+
+	// Интервал, не доходящий до конца журнала, голову не проверяет
+	report, err = service.VerifyAuditChain(time.Time{}, groups[2].CreatedAt)
+	require.NoError(t, err)
+	assert.True(t, report.Valid(), report.Problems)
+}
+
+func TestAuditChainSharedByInstances(t *testing.T) {
+	// Два экземпляра сервиса с общей базой, как несколько реплик приложения
+	dsn := "file:" + filepath.Join(t.TempDir(), "audit.db") + "?_txlock=immediate&_busy_timeout=10000"
+	instances := make([]*AccessGoService, 2)
+	for i := range instances {
+		db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+		require.NoError(t, err)
+		instances[i] = newTestService(t, db)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i, service := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := service.CreateGroup(fmt.Sprintf("group-%d-%d", i, j)); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	report, err := instances[0].VerifyAuditChain(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, 35, report.Checked)
+
+	// Журнал без AuditChainHead продолжается от последней записи
+	require.NoError(t, instances[1].db.Exec("DELETE FROM audit_chain_heads").Error)
+	_, err = instances[1].CreateGroup("after-upgrade")
+	require.NoError(t, err)
+	report, err = instances[0].VerifyAuditChain(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, uint64(36), report.LastSequence)
+}
+
+func TestAuditChainHMACAndExport(t *testing.T) {
+	db := setupTestDB(t)
+	service, err := NewAccessGoService(db)
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	service.SetAuditHMACKey([]byte("audit-secret"))
+
+	group, err := service.CreateGroup("signed")
+	require.NoError(t, err)
+	_, err = service.UpdateGroup(group.ID, "renamed")
+	require.NoError(t, err)
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetGroup})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, events[1].Hash, events[0].PrevHash)
+
+	// Стандартные права записаны до установки ключа, поэтому проверяются только записи групп
+	report, err := service.VerifyAuditChain(events[1].CreatedAt, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, report.Valid(), report.Problems)
+
+	service.SetAuditHMACKey([]byte("other-secret"))
+	report, err = service.VerifyAuditChain(events[1].CreatedAt, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 2)
+	assert.Equal(t, AuditProblemModified, report.Problems[0].Kind)
+
+	var out bytes.Buffer
+	require.NoError(t, service.ExportAuditLog(&out, AuditQuery{TargetType: AuditTargetGroup}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	var first AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, events[1].Sequence, first.Sequence)
+	assert.Equal(t, events[1].Hash, first.Hash)
+	assert.Equal(t, AuditGroupCreate, first.Action)
+}
@@ -0,0 +1,342 @@
+package accessgo
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/mail"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BulkImportStatus - результат обработки строки CSV
+type BulkImportStatus string
+
+const (
+	BulkImportCreated BulkImportStatus = "created" // пользователь создан с паролем из файла
+	BulkImportInvited BulkImportStatus = "invited" // пользователь приглашен (InviteUser)
+	BulkImportValid   BulkImportStatus = "valid"   // строка верна, но не загружена (DryRun)
+	BulkImportInvalid BulkImportStatus = "invalid" // строка не прошла проверку
+	BulkImportFailed  BulkImportStatus = "failed"  // строка верна, но транзакция с ней откатилась
+)
+
+// BulkImportOptions задает режим массовой загрузки пользователей
+type BulkImportOptions struct {
+	// ChunkSize - число строк в одной транзакции. 0 - весь файл в одной транзакции:
+	// если хотя бы одна строка неверна, ничего не загружается. При ChunkSize > 0
+	// неверные строки пропускаются, а ошибка откатывает только свою порцию
+	ChunkSize int
+	// DryRun - только проверить строки, не создавая пользователей
+	DryRun bool
+	// Invite вызывается после фиксации транзакции для каждого пользователя,
+	// созданного без пароля (см. InviteUser), и получает приглашение и его токен.
+	// Ошибка отправки попадает в отчет, но не отменяет создание пользователя.
+	// Без Invite токен можно выдать заново через ResendInvitation
+	Invite func(ctx context.Context, invitation *Invitation, token string) error
+}
+
+// BulkImportRow - результат по одной строке CSV
+type BulkImportRow struct {
+	Line   int              `json:"line"`
+	Email  string           `json:"email"`
+	Status BulkImportStatus `json:"status"`
+	UserID uint             `json:"user_id,omitempty"`
+	Errors []string         `json:"errors,omitempty"`
+}
+
+// BulkImportReport - отчет массовой загрузки по строкам и итоги по статусам
+type BulkImportReport struct {
+	Rows    []BulkImportRow `json:"rows"`
+	Created int             `json:"created"`
+	Invited int             `json:"invited"`
+	Invalid int             `json:"invalid"`
+	Failed  int             `json:"failed"`
+}
+
+var bulkImportColumns = []string{"email", "name", "type", "groups", "password"}
+
+type bulkImportRecord struct {
+	email    string
+	name     string
+	userType UserType
+	groups   []string
+	password string
+	groupIDs []uint
+	row      *BulkImportRow
+}
+
+// ImportUsersCSV создает пользователей из CSV с заголовком. Обязательные колонки:
+// email и name; необязательные: type (по умолчанию user), groups - имена групп
+// через ";" и password. Пользователи с паролем создаются с подтвержденным email,
+// пользователи без пароля приглашаются, как в InviteUser.
+// Ошибка возвращается только для нечитаемого файла, ошибки строк - в отчете
+func (s *AccessGoService) ImportUsersCSV(r io.Reader, opts BulkImportOptions) (*BulkImportReport, error) {
+	return s.ImportUsersCSVCtx(s.ctx, r, opts)
+}
+
+// ImportUsersCSVCtx - ImportUsersCSV с контекстом ctx
+func (s *AccessGoService) ImportUsersCSVCtx(ctx context.Context, r io.Reader, opts BulkImportOptions) (*BulkImportReport, error) {
+	s = s.WithContext(ctx)
+	if opts.ChunkSize < 0 {
+		return nil, errors.New("размер порции не может быть отрицательным")
+	}
+	records, err := readBulkImportCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	report := &BulkImportReport{Rows: make([]BulkImportRow, len(records))}
+	if len(records) == 0 {
+		return report, nil
+	}
+	for i := range records {
+		report.Rows[i] = *records[i].row
+		records[i].row = &report.Rows[i]
+	}
+	if err := s.validateBulkImport(records); err != nil {
+		return nil, err
+	}
+
+	valid := make([]*bulkImportRecord, 0, len(records))
+	for i := range records {
+		if len(records[i].row.Errors) == 0 {
+			records[i].row.Status = BulkImportValid
+			valid = append(valid, &records[i])
+		} else {
+			records[i].row.Status = BulkImportInvalid
+			report.Invalid++
+		}
+	}
+	if opts.DryRun || len(valid) == 0 || (opts.ChunkSize == 0 && report.Invalid > 0) {
+		return report, nil
+	}
+
+	for _, record := range valid {
+		if record.password == "" {
+			continue
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(record.password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		record.password = string(hashed)
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = len(valid)
+	}
+	for chunk := range slices.Chunk(valid, chunkSize) {
+		s.importBulkChunk(ctx, chunk, opts, report)
+	}
+	return report, nil
+}
+
+func readBulkImportCSV(r io.Reader) ([]bulkImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("неверный CSV: нет заголовка")
+		}
+		return nil, fmt.Errorf("неверный CSV: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(bulkImportColumns, name) {
+			return nil, fmt.Errorf("неверный CSV: неизвестная колонка %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("неверный CSV: колонка %q указана дважды", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"email", "name"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("неверный CSV: нет колонки %q", name)
+		}
+	}
+
+	var records []bulkImportRecord
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("неверный CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[i])
+		}
+		record := bulkImportRecord{
+			email:    field("email"),
+			name:     field("name"),
+			userType: UserType(field("type")),
+			password: field("password"),
+			row:      &BulkImportRow{Line: line},
+		}
+		if len(fields) != len(header) {
+			record.row.Errors = append(record.row.Errors, fmt.Sprintf("ожидается %d колонок, получено %d", len(header), len(fields)))
+		}
+		if record.userType == "" {
+			record.userType = UserTypeUser
+		}
+		for _, group := range strings.Split(field("groups"), ";") {
+			if group = strings.TrimSpace(group); group != "" && !slices.Contains(record.groups, group) {
+				record.groups = append(record.groups, group)
+			}
+		}
+		record.row.Email = record.email
+		records = append(records, record)
+	}
+}
+
+// validateBulkImport проверяет все строки и заполняет их ошибки. Пользователи
+// и группы читаются из базы одним запросом каждые
+func (s *AccessGoService) validateBulkImport(records []bulkImportRecord) error {
+	emails := make([]string, 0, len(records))
+	groupNames := make(map[string]uint)
+	for _, record := range records {
+		emails = append(emails, record.email)
+		for _, name := range record.groups {
+			groupNames[name] = 0
+		}
+	}
+
+	// Email удаленных пользователей остается занятым
+	var existing, deleted []string
+	if err := s.db.Model(&User{}).Where("email IN ?", emails).Pluck("email", &existing).Error; err != nil {
+		return err
+	}
+	if err := s.db.Unscoped().Model(&User{}).Where("email IN ? AND deleted_at IS NOT NULL", emails).Pluck("email", &deleted).Error; err != nil {
+		return err
+	}
+	if len(groupNames) > 0 {
+		var groups []Group
+		if err := s.db.Where("name IN ?", slices.Collect(maps.Keys(groupNames))).Find(&groups).Error; err != nil {
+			return err
+		}
+		for _, group := range groups {
+			groupNames[group.Name] = group.ID
+		}
+	}
+
+	seen := make(map[string]int, len(records))
+	for i := range records {
+		record := &records[i]
+		addError := func(format string, args ...any) {
+			record.row.Errors = append(record.row.Errors, fmt.Sprintf(format, args...))
+		}
+		switch address, err := mail.ParseAddress(record.email); {
+		case record.email == "":
+			addError("не указан email")
+		case err != nil || address.Address != record.email:
+			addError("неверный email %q", record.email)
+		case slices.Contains(existing, record.email):
+			addError("пользователь %q уже существует", record.email)
+		case slices.Contains(deleted, record.email):
+			addError("пользователь %q удален, его email занят", record.email)
+		case seen[record.email] != 0:
+			addError("email %q повторяет строку %d", record.email, seen[record.email])
+		default:
+			seen[record.email] = record.row.Line
+		}
+		if record.name == "" {
+			addError("не указано имя")
+		}
+		switch record.userType {
+		case UserTypeAdmin, UserTypeEmployee, UserTypeUser, UserTypeService:
+		default:
+			addError("неверный тип пользователя %q", record.userType)
+		}
+		for _, name := range record.groups {
+			if groupNames[name] == 0 {
+				addError("группа %q не найдена", name)
+				continue
+			}
+			record.groupIDs = append(record.groupIDs, groupNames[name])
+		}
+	}
+	return nil
+}
+
+// importBulkChunk создает пользователей порции в одной транзакции и после
+// фиксации рассылает приглашения. При ошибке откатывается вся порция
+func (s *AccessGoService) importBulkChunk(ctx context.Context, chunk []*bulkImportRecord, opts BulkImportOptions, report *BulkImportReport) {
+	userIDs := make([]uint, len(chunk))
+	invitations := make([]*Invitation, len(chunk))
+	tokens := make([]string, len(chunk))
+	var failed *bulkImportRecord
+	err := s.transaction(func(tx *AccessGoService) error {
+		for i, record := range chunk {
+			failed = record
+			if record.password == "" {
+				token, invitation, err := tx.invitePendingUser(record.email, record.name, record.userType, record.groupIDs)
+				if err != nil {
+					return err
+				}
+				userIDs[i], invitations[i], tokens[i] = invitation.UserID, invitation, token
+				continue
+			}
+			var user User
+			imported := ExportUser{
+				Email:         record.email,
+				Name:          record.name,
+				UserType:      record.userType,
+				EmailValidate: true,
+				PasswordHash:  record.password,
+			}
+			if err := tx.createImportedUser(&user, imported); err != nil {
+				return err
+			}
+			if len(record.groupIDs) > 0 {
+				if err := tx.SetUserGroups(user.ID, record.groupIDs...); err != nil {
+					return err
+				}
+			}
+			userIDs[i] = user.ID
+		}
+		return nil
+	})
+	if err != nil {
+		for _, record := range chunk {
+			record.row.Status = BulkImportFailed
+			if record == failed {
+				record.row.Errors = append(record.row.Errors, err.Error())
+			} else {
+				record.row.Errors = append(record.row.Errors, fmt.Sprintf("транзакция откатилась из-за ошибки в строке %d", failed.row.Line))
+			}
+			report.Failed++
+		}
+		return
+	}
+
+	for i, record := range chunk {
+		record.row.UserID = userIDs[i]
+		if invitations[i] == nil {
+			record.row.Status = BulkImportCreated
+			report.Created++
+			continue
+		}
+		record.row.Status = BulkImportInvited
+		report.Invited++
+		if opts.Invite != nil {
+			if err := opts.Invite(ctx, invitations[i], tokens[i]); err != nil {
+				record.row.Errors = append(record.row.Errors, "приглашение не отправлено: "+err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUsersCSV = `email,name,type,groups,password
+ann@example.com,Ann,employee,sales;support,secret1
+bob@example.com,Bob,,sales,
+"carol@example.com", "Carol, Jr.",user,,
+not-an-email,Dan,user,,
+eve@example.com,,root,marketing,secret2
+ann@example.com,Ann Again,user,,
+`
+
+func TestImportUsersCSV(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	sales, err := service.CreateGroup("sales")
+	require.NoError(t, err)
+	_, err = service.CreateGroup("support")
+	require.NoError(t, err)
+	_, err = service.CreateUser("dup@example.com", "password", "Dup", UserTypeUser)
+	require.NoError(t, err)
+	removed, err := service.CreateUser("removed@example.com", "password", "Removed", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteUser(removed.ID))
+
+	// Одна транзакция: при неверных строках ничего не создается
+	report, err := service.ImportUsersCSV(strings.NewReader(testUsersCSV+"dup@example.com,Dup,user,,\nremoved@example.com,Removed,user,,\n"), BulkImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Invalid)
+	assert.Zero(t, report.Created+report.Invited)
+	statuses := make([]BulkImportStatus, 0, len(report.Rows))
+	for _, row := range report.Rows {
+		statuses = append(statuses, row.Status)
+	}
+	assert.Equal(t, []BulkImportStatus{BulkImportValid, BulkImportValid, BulkImportValid,
+		BulkImportInvalid, BulkImportInvalid, BulkImportInvalid, BulkImportInvalid, BulkImportInvalid}, statuses)
+	assert.Equal(t, 5, report.Rows[3].Line)
+	assert.Equal(t, []string{`неверный email "not-an-email"`}, report.Rows[3].Errors)
+	assert.Equal(t, []string{"не указано имя", `неверный тип пользователя "root"`, `группа "marketing" не найдена`}, report.Rows[4].Errors)
+	assert.Equal(t, []string{`email "ann@example.com" повторяет строку 2`}, report.Rows[5].Errors)
+	assert.Equal(t, []string{`пользователь "dup@example.com" уже существует`}, report.Rows[6].Errors)
+	assert.Equal(t, []string{`пользователь "removed@example.com" удален, его email занят`}, report.Rows[7].Errors)
+	_, err = service.GetUserByEmail("ann@example.com")
+	assert.Error(t, err)
+
+	// Порции: неверные строки пропускаются, для строк без пароля отправляются приглашения
+	var invited []string
+	tokens := make(map[string]string)
+	report, err = service.ImportUsersCSV(strings.NewReader(testUsersCSV), BulkImportOptions{
+		ChunkSize: 2,
+		Invite: func(ctx context.Context, invitation *Invitation, token string) error {
+			invited = append(invited, invitation.User.Email)
+			tokens[invitation.User.Email] = token
+			if invitation.User.Email == "carol@example.com" {
+				return errors.New("почтовый сервер недоступен")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 2, report.Invited)
+	assert.Equal(t, 3, report.Invalid)
+	assert.Equal(t, []string{"bob@example.com", "carol@example.com"}, invited)
+	assert.Equal(t, BulkImportCreated, report.Rows[0].Status)
+	assert.Equal(t, BulkImportInvited, report.Rows[2].Status)
+	assert.Equal(t, []string{"приглашение не отправлено: почтовый сервер недоступен"}, report.Rows[2].Errors)
+
+	ann, err := service.AuthenticateUser("ann@example.com", "secret1")
+	require.NoError(t, err)
+	assert.Equal(t, report.Rows[0].UserID, ann.ID)
+	assert.Equal(t, string(UserTypeEmployee), ann.UserType)
+	groups, err := service.GetUserGroups(ann.ID)
+	require.NoError(t, err)
+	assert.Len(t, groups, 2)
+
+	bob, err := service.GetUserByEmail("bob@example.com")
+	require.NoError(t, err)
+	assert.False(t, bob.EmailValidate)
+	assert.Equal(t, string(UserTypeUser), bob.UserType)
+	_, err = service.AcceptInvitation(tokens["bob@example.com"], "secret3", "")
+	require.NoError(t, err)
+	_, err = service.AuthenticateUser("bob@example.com", "secret3")
+	require.NoError(t, err)
+	members, err := service.GetGroupUsers(sales.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+	carol, err := service.GetUserByEmail("carol@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Carol, Jr.", carol.Name)
+}
+
+func TestImportUsersCSVChunkRollback(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	service.AddBeforeHook(EventUserCreated, func(ctx context.Context, event Event) error {
+		if event.(UserCreated).User.Email == "c@example.com" {
+			return errors.New("запрещено")
+		}
+		return nil
+	})
+
+	report, err := service.ImportUsersCSV(strings.NewReader("email,name\na@example.com,A\nb@example.com,B\nc@example.com,C\n"),
+		BulkImportOptions{ChunkSize: 2, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, BulkImportValid, report.Rows[2].Status)
+	users, err := service.GetAllUsers()
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	report, err = service.ImportUsersCSV(strings.NewReader("email,name\na@example.com,A\nb@example.com,B\nd@example.com,D\nc@example.com,C\n"),
+		BulkImportOptions{ChunkSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Invited)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, []string{"транзакция откатилась из-за ошибки в строке 5"}, report.Rows[2].Errors)
+	assert.Equal(t, []string{"запрещено"}, report.Rows[3].Errors)
+	users, err = service.GetAllUsers()
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	for data, message := range map[string]string{
+		"":                    "неверный CSV: нет заголовка",
+		"email,name,role\n":   `неверный CSV: неизвестная колонка "role"`,
+		"name,password\n":     `неверный CSV: нет колонки "email"`,
+		"email,name,email\n":  `неверный CSV: колонка "email" указана дважды`,
+		"email,name\n\"a,b\n": `неверный CSV: parse error on line 2, column 6: extraneous or missing " in quoted-field`,
+	} {
+		_, err := service.ImportUsersCSV(strings.NewReader(data), BulkImportOptions{})
+		assert.EqualError(t, err, message, data)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/axgrid/accessgo"
+)
+
+// auditRange разбирает флаги -from и -to в формате RFC 3339
+func auditRange(name string) (*flag.FlagSet, func() (time.Time, time.Time, error)) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	from := fs.String("from", "", "начало интервала (RFC 3339), включительно")
+	to := fs.String("to", "", "конец интервала (RFC 3339), не включительно")
+	return fs, func() (time.Time, time.Time, error) {
+		var start, end time.Time
+		var err error
+		if *from != "" {
+			if start, err = time.Parse(time.RFC3339, *from); err != nil {
+				return start, end, fmt.Errorf("неверное значение -from: %w", err)
+			}
+		}
+		if *to != "" {
+			if end, err = time.Parse(time.RFC3339, *to); err != nil {
+				return start, end, fmt.Errorf("неверное значение -to: %w", err)
+			}
+		}
+		return start, end, nil
+	}
+}
+
+// auditVerify проверяет цепочку журнала аудита и завершается ошибкой при нарушениях
+func (c *cli) auditVerify(args []string) error {
+	fs, parseRange := auditRange("audit verify")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	from, to, err := parseRange()
+	if err != nil {
+		return err
+	}
+
+	report, err := c.svc.VerifyAuditChain(from, to)
+	if err != nil {
+		return err
+	}
+
+	if c.format == "json" {
+		if err := c.printJSON(report); err != nil {
+			return err
+		}
+	} else if report.Valid() {
+		if _, err := fmt.Fprintf(c.out, "цепочка не нарушена: проверено записей %d (%d-%d), последний хеш %s\n",
+			report.Checked, report.FirstSequence, report.LastSequence, report.LastHash); err != nil {
+			return err
+		}
+	} else {
+		rows := make([][]string, 0, len(report.Problems))
+		for _, problem := range report.Problems {
+			rows = append(rows, []string{strconv.FormatUint(problem.Sequence, 10), problem.Kind, problem.Message})
+		}
+		if err := c.printTable([]string{"SEQUENCE", "PROBLEM", "MESSAGE"}, rows); err != nil {
+			return err
+		}
+	}
+
+	if !report.Valid() {
+		return fmt.Errorf("цепочка журнала аудита нарушена: найдено нарушений %d", len(report.Problems))
+	}
+	return nil
+}
+
+// auditExport выгружает журнал аудита в формате JSON Lines в файл или на стандартный вывод
+func (c *cli) auditExport(args []string) error {
+	fs, parseRange := auditRange("audit export")
+	output := fs.String("o", "", "файл для выгрузки (по умолчанию стандартный вывод)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	from, to, err := parseRange()
+	if err != nil {
+		return err
+	}
+
+	query := accessgo.AuditQuery{From: from, To: to}
+	if *output == "" {
+		return c.svc.ExportAuditLog(c.out, query)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := c.svc.ExportAuditLog(file, query); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return c.done("журнал аудита выгружен в " + *output)
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/axgrid/accessgo"
+	"gorm.io/gorm"
+)
+
+type cli struct {
+	db     *gorm.DB
+	svc    *accessgo.AccessGoService
+	out    io.Writer
+	format string
+}
+
+type command func(c *cli, args []string) error
+
+// commands сопоставляет команду (одно или два слова) с ее обработчиком
+var commands = map[string]command{
+	"migrate":        (*cli).migrate,
+	"seed":           (*cli).seed,
+	"create-admin":   (*cli).createAdmin,
+	"user create":    (*cli).userCreate,
+	"user list":      (*cli).userList,
+	"user delete":    (*cli).userDelete,
+	"user import":    (*cli).userImport,
+	"invite create":  (*cli).inviteCreate,
+	"invite list":    (*cli).inviteList,
+	"invite resend":  (*cli).inviteResend,
+	"invite revoke":  (*cli).inviteRevoke,
+	"group create":   (*cli).groupCreate,
+	"group list":     (*cli).groupList,
+	"group delete":   (*cli).groupDelete,
+	"access create":  (*cli).accessCreate,
+	"access list":    (*cli).accessList,
+	"access delete":  (*cli).accessDelete,
+	"grant user":     (*cli).grantUser,
+	"grant group":    (*cli).grantGroup,
+	"revoke user":    (*cli).revokeUser,
+	"revoke group":   (*cli).revokeGroup,
+	"member add":     (*cli).memberAdd,
+	"member remove":  (*cli).memberRemove,
+	"member list":    (*cli).memberList,
+	"check":          (*cli).check,
+	"explain":        (*cli).explain,
+	"permissions":    (*cli).permissions,
+	"audit verify":   (*cli).auditVerify,
+	"audit export":   (*cli).auditExport,
+	"manifest apply": (*cli).manifestApply,
+	"export":         (*cli).exportAll,
+	"import":         (*cli).importAll,
+}
+
+type permissionRow struct {
+	Access string `json:"access"`
+	Source string `json:"source"`
+}
+
+func (c *cli) dispatch(args []string) error {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd(c, args[2:])
+		}
+	}
+	if cmd, ok := commands[args[0]]; ok {
+		return cmd(c, args[1:])
+	}
+	return fmt.Errorf("неизвестная команда %q, см. accessgo -h", strings.Join(args, " "))
+}
+
+func (c *cli) migrate(args []string) error {
+	// Основные таблицы создает NewAccessGoService, здесь - таблицы дополнительных сервисов
+	if err := c.db.AutoMigrate(
+		&accessgo.PasskeyCredential{},
+		&accessgo.ExternalIdentity{},
+		&accessgo.OAuthClient{},
+		&accessgo.OAuthAuthorizationCode{},
+		&accessgo.OAuthToken{},
+		&accessgo.OAuthConsent{},
+		&accessgo.OutboxEvent{},
+		&accessgo.WebhookDelivery{},
+		&accessgo.WebhookDeadLetter{},
+	); err != nil {
+		return err
+	}
+	return c.done("миграция выполнена")
+}
+
+func (c *cli) seed(args []string) error {
+	if err := c.svc.SetupDefaultPermissions(); err != nil {
+		return err
+	}
+	return c.done("стандартные права доступа созданы")
+}
+
+func (c *cli) createAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email администратора")
+	name := fs.String("name", "Administrator", "имя администратора")
+	password := fs.String("password", os.Getenv("ACCESSGO_ADMIN_PASSWORD"), "пароль администратора")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *password == "" {
+		return errors.New("необходимо указать -email и -password")
+	}
+
+	if err := c.svc.CreateDefaultAdminUser(*email, *password, *name); err != nil {
+		return err
+	}
+	// Администратор, созданный вручную, не подтверждает email письмом
+	admin, err := c.svc.GetUserByEmail(*email)
+	if err != nil {
+		return err
+	}
+	if err := c.svc.ValidateEmail(admin.EmailValidationToken); err != nil {
+		return err
+	}
+	admin.EmailValidate = true
+	return c.printUsers([]accessgo.User{*admin})
+}
+
+func (c *cli) userCreate(args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	email := fs.String("email", "", "email пользователя")
+	name := fs.String("name", "", "имя пользователя")
+	password := fs.String("password", "", "пароль пользователя")
+	userType := fs.String("type", string(accessgo.UserTypeUser), "тип пользователя: admin, employee, user, service")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *name == "" || *password == "" {
+		return errors.New("необходимо указать -email, -name и -password")
+	}
+
+	user, err := c.svc.CreateUser(*email, *password, *name, accessgo.UserType(*userType))
+	if err != nil {
+		return err
+	}
+	if err := c.svc.ValidateEmail(user.EmailValidationToken); err != nil {
+		return err
+	}
+	user.EmailValidate = true
+	return c.printUsers([]accessgo.User{*user})
+}
+
+func (c *cli) userList(args []string) error {
+	users, err := c.svc.GetAllUsers()
+	if err != nil {
+		return err
+	}
+	return c.printUsers(users)
+}
+
+func (c *cli) userDelete(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: user delete <пользователь>")
+	}
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.svc.DeleteUser(user.ID); err != nil {
+		return err
+	}
+	return c.done("пользователь " + user.Email + " удален")
+}
+
+// userImport создает пользователей из CSV и выводит результат по каждой строке
+func (c *cli) userImport(args []string) error {
+	if len(args) == 0 {
+		return errors.New("использование: user import <файл.csv> [-chunk N] [-dry-run]")
+	}
+	fs := flag.NewFlagSet("user import", flag.ContinueOnError)
+	chunk := fs.Int("chunk", 0, "строк в одной транзакции (0 - весь файл в одной транзакции)")
+	dryRun := fs.Bool("dry-run", false, "только проверить файл")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	report, err := c.svc.ImportUsersCSV(file, accessgo.BulkImportOptions{ChunkSize: *chunk, DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+
+	if c.format == "json" {
+		return c.printJSON(report)
+	}
+	rows := make([][]string, 0, len(report.Rows))
+	for _, row := range report.Rows {
+		rows = append(rows, []string{strconv.Itoa(row.Line), row.Email, string(row.Status), strings.Join(row.Errors, "; ")})
+	}
+	if err := c.printTable([]string{"LINE", "EMAIL", "STATUS", "ERRORS"}, rows); err != nil {
+		return err
+	}
+	return c.done(fmt.Sprintf("создано: %d, приглашено: %d, с ошибками: %d, откачено: %d",
+		report.Created, report.Invited, report.Invalid, report.Failed))
+}
+
+func (c *cli) groupCreate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: group create <имя>")
+	}
+	group, err := c.svc.CreateGroup(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printGroups([]accessgo.Group{*group})
+}
+
+func (c *cli) groupList(args []string) error {
+	groups, err := c.svc.GetAllGroups()
+	if err != nil {
+		return err
+	}
+	return c.printGroups(groups)
+}
+
+func (c *cli) groupDelete(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: group delete <группа>")
+	}
+	group, err := c.findGroup(args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.svc.DeleteGroup(group.ID); err != nil {
+		return err
+	}
+	return c.done("группа " + group.Name + " удалена")
+}
+
+func (c *cli) accessCreate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("использование: access create <имя> [-description D]")
+	}
+	fs := flag.NewFlagSet("access create", flag.ContinueOnError)
+	description := fs.String("description", "", "описание права доступа")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	access, err := c.svc.CreateAccess(args[0], *description)
+	if err != nil {
+		return err
+	}
+	return c.printAccesses([]accessgo.Access{*access})
+}
+
+func (c *cli) accessList(args []string) error {
+	accesses, err := c.svc.ListAccesses()
+	if err != nil {
+		return err
+	}
+	return c.printAccesses(accesses)
+}
+
+func (c *cli) accessDelete(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: access delete <право>")
+	}
+	access, err := c.svc.GetAccessByName(args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.svc.DeleteAccess(access.ID); err != nil {
+		return err
+	}
+	return c.done("право доступа " + access.Name + " удалено")
+}
+
+func (c *cli) grantUser(args []string) error {
+	return c.changeUserAccess(args, "grant user", c.svc.AddUserAccessLevel, "выдано")
+}
+
+func (c *cli) revokeUser(args []string) error {
+	return c.changeUserAccess(args, "revoke user", c.svc.RemoveUserAccessLevel, "отозвано")
+}
+
+func (c *cli) grantGroup(args []string) error {
+	return c.changeGroupAccess(args, "grant group", c.svc.AddGroupAccessLevel, "выдано")
+}
+
+func (c *cli) revokeGroup(args []string) error {
+	return c.changeGroupAccess(args, "revoke group", c.svc.RemoveGroupAccessLevel, "отозвано")
+}
+
+func (c *cli) changeUserAccess(args []string, name string, change func(uint, string) error, verb string) error {
+	if len(args) != 2 {
+		return errors.New("использование: " + name + " <пользователь> <право>")
+	}
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return err
+	}
+	if err := change(user.ID, args[1]); err != nil {
+		return err
+	}
+	return c.done("право " + args[1] + " " + verb + " пользователю " + user.Email)
+}
+
+func (c *cli) changeGroupAccess(args []string, name string, change func(uint, string) error, verb string) error {
+	if len(args) != 2 {
+		return errors.New("использование: " + name + " <группа> <право>")
+	}
+	group, err := c.findGroup(args[0])
+	if err != nil {
+		return err
+	}
+	if err := change(group.ID, args[1]); err != nil {
+		return err
+	}
+	return c.done("право " + args[1] + " " + verb + " группе " + group.Name)
+}
+
+func (c *cli) memberAdd(args []string) error {
+	if len(args) != 2 {
+		return errors.New("использование: member add <пользователь> <группа>")
+	}
+	user, group, err := c.findMembership(args)
+	if err != nil {
+		return err
+	}
+	if err := c.svc.AssignUserToGroup(user.ID, group.ID); err != nil {
+		return err
+	}
+	return c.done("пользователь " + user.Email + " добавлен в группу " + group.Name)
+}
+
+func (c *cli) memberRemove(args []string) error {
+	if len(args) != 2 {
+		return errors.New("использование: member remove <пользователь> <группа>")
+	}
+	user, group, err := c.findMembership(args)
+	if err != nil {
+		return err
+	}
+	if err := c.svc.ExcludeUserFromGroup(user.ID, group.ID); err != nil {
+		return err
+	}
+	return c.done("пользователь " + user.Email + " исключен из группы " + group.Name)
+}
+
+func (c *cli) memberList(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: member list <группа>")
+	}
+	group, err := c.findGroup(args[0])
+	if err != nil {
+		return err
+	}
+	users, err := c.svc.GetGroupUsers(group.ID)
+	if err != nil {
+		return err
+	}
+	return c.printUsers(users)
+}
+
+func (c *cli) check(args []string) error {
+	if len(args) != 2 {
+		return errors.New("использование: check <пользователь> <право>")
+	}
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return err
+	}
+	allowed, err := c.svc.CheckUserAccess(user.ID, args[1])
+	if err != nil {
+		return err
+	}
+
+	if c.format == "json" {
+		return c.printJSON(map[string]interface{}{"user": user.Email, "access": args[1], "allowed": allowed})
+	}
+	result := "запрещено"
+	if allowed {
+		result = "разрешено"
+	}
+	_, err = fmt.Fprintln(c.out, result)
+	return err
+}
+
+// explain выводит решение о доступе и причины: прямые выдачи и группы
+func (c *cli) explain(args []string) error {
+	if len(args) != 2 {
+		return errors.New("использование: explain <пользователь> <право>")
+	}
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return err
+	}
+	explanation, err := c.svc.ExplainUserAccess(user.ID, args[1])
+	if err != nil {
+		return err
+	}
+
+	if c.format == "json" {
+		return c.printJSON(explanation)
+	}
+	result := "запрещено"
+	if explanation.Allowed {
+		result = "разрешено"
+	}
+	if _, err := fmt.Fprintln(c.out, result); err != nil {
+		return err
+	}
+	for _, reason := range explanation.Reasons {
+		line := "  - " + reason.Description
+		if reason.GrantedAt != nil {
+			line += ", " + reason.GrantedAt.Format("2006-01-02 15:04:05")
+		}
+		if _, err := fmt.Fprintln(c.out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// permissions выводит эффективные права пользователя с их источником:
+// "direct" для прямых прав или "group:<имя>" для прав группы
+func (c *cli) permissions(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: permissions <пользователь>")
+	}
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return err
+	}
+
+	rows := []permissionRow{}
+	direct, err := c.svc.GetUserAccessLevels(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, access := range direct {
+		rows = append(rows, permissionRow{Access: access, Source: "direct"})
+	}
+	groups, err := c.svc.GetUserGroups(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		accesses, err := c.svc.GetGroupAccessLevels(group.ID)
+		if err != nil {
+			return err
+		}
+		for _, access := range accesses {
+			rows = append(rows, permissionRow{Access: access, Source: "group:" + group.Name})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Access != rows[j].Access {
+			return rows[i].Access < rows[j].Access
+		}
+		return rows[i].Source < rows[j].Source
+	})
+
+	if c.format == "json" {
+		return c.printJSON(rows)
+	}
+	table := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		table = append(table, []string{row.Access, row.Source})
+	}
+	return c.printTable([]string{"ACCESS", "SOURCE"}, table)
+}
+
+func (c *cli) findUser(ref string) (*accessgo.User, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return c.svc.GetUserByID(uint(id))
+	}
+	return c.svc.GetUserByEmail(ref)
+}
+
+func (c *cli) findGroup(ref string) (*accessgo.Group, error) {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return c.svc.GetGroupByID(uint(id))
+	}
+	groups, err := c.svc.GetAllGroups()
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.Name == ref {
+			return &group, nil
+		}
+	}
+	return nil, errors.New("группа не найдена")
+}
+
+func (c *cli) findMembership(args []string) (*accessgo.User, *accessgo.Group, error) {
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	group, err := c.findGroup(args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, group, nil
+}
+
+func (c *cli) printUsers(users []accessgo.User) error {
+	if c.format == "json" {
+		type userJSON struct {
+			ID             uint   `json:"id"`
+			Email          string `json:"email"`
+			Name           string `json:"name"`
+			UserType       string `json:"user_type"`
+			Source         string `json:"source"`
+			EmailValidated bool   `json:"email_validated"`
+		}
+		result := make([]userJSON, 0, len(users))
+		for _, u := range users {
+			result = append(result, userJSON{u.ID, u.Email, u.Name, u.UserType, u.Source, u.EmailValidate})
+		}
+		return c.printJSON(result)
+	}
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []string{strconv.FormatUint(uint64(u.ID), 10), u.Email, u.Name, u.UserType, u.Source, strconv.FormatBool(u.EmailValidate)})
+	}
+	return c.printTable([]string{"ID", "EMAIL", "NAME", "TYPE", "SOURCE", "VALIDATED"}, rows)
+}
+
+func (c *cli) printGroups(groups []accessgo.Group) error {
+	if c.format == "json" {
+		type groupJSON struct {
+			ID   uint   `json:"id"`
+			Name string `json:"name"`
+		}
+		result := make([]groupJSON, 0, len(groups))
+		for _, g := range groups {
+			result = append(result, groupJSON{g.ID, g.Name})
+		}
+		return c.printJSON(result)
+	}
+	rows := make([][]string, 0, len(groups))
+	for _, g := range groups {
+		rows = append(rows, []string{strconv.FormatUint(uint64(g.ID), 10), g.Name})
+	}
+	return c.printTable([]string{"ID", "NAME"}, rows)
+}
+
+func (c *cli) printAccesses(accesses []accessgo.Access) error {
+	if c.format == "json" {
+		type accessJSON struct {
+			ID          uint   `json:"id"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		}
+		result := make([]accessJSON, 0, len(accesses))
+		for _, a := range accesses {
+			result = append(result, accessJSON{a.ID, a.Name, a.Description})
+		}
+		return c.printJSON(result)
+	}
+	rows := make([][]string, 0, len(accesses))
+	for _, a := range accesses {
+		rows = append(rows, []string{strconv.FormatUint(uint64(a.ID), 10), a.Name, a.Description})
+	}
+	return c.printTable([]string{"ID", "NAME", "DESCRIPTION"}, rows)
+}
+
+func (c *cli) printTable(headers []string, rows [][]string) error {
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (c *cli) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// done сообщает об успешном выполнении команды без результата
+func (c *cli) done(message string) error {
+	if c.format == "json" {
+		return c.printJSON(map[string]string{"status": "ok", "message": message})
+	}
+	_, err := fmt.Fprintln(c.out, message)
+	return err
+}
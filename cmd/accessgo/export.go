@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/axgrid/accessgo"
+)
+
+// exportAll выгружает права, группы и пользователей в JSON в файл или на стандартный вывод
+func (c *cli) exportAll(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "", "файл для выгрузки (по умолчанию стандартный вывод)")
+	hashes := fs.Bool("hashes", false, "выгрузить хеши паролей")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	doc, err := c.svc.ExportAll(accessgo.ExportOptions{IncludePasswordHashes: *hashes})
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		_, err = c.out.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o600); err != nil {
+		return err
+	}
+	return c.done("данные выгружены в " + *output)
+}
+
+// importAll загружает выгрузку команды export
+func (c *cli) importAll(args []string) error {
+	if len(args) == 0 {
+		return errors.New("использование: import <файл> [-on-conflict fail|skip|overwrite]")
+	}
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	onConflict := fs.String("on-conflict", string(accessgo.ImportConflictFail), "fail, skip или overwrite")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var doc accessgo.ExportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("неверный файл выгрузки: %w", err)
+	}
+	result, err := c.svc.ImportAll(&doc, accessgo.ImportOptions{OnConflict: accessgo.ImportConflictStrategy(*onConflict)})
+	if err != nil {
+		return err
+	}
+
+	if c.format == "json" {
+		return c.printJSON(result)
+	}
+	rows := make([][]string, 0, 3)
+	for _, row := range []struct {
+		kind   string
+		counts accessgo.ImportCounts
+	}{{"accesses", result.Accesses}, {"groups", result.Groups}, {"users", result.Users}} {
+		rows = append(rows, []string{row.kind, fmt.Sprint(row.counts.Created), fmt.Sprint(row.counts.Updated),
+			fmt.Sprint(row.counts.Unchanged), fmt.Sprint(row.counts.Skipped)})
+	}
+	return c.printTable([]string{"KIND", "CREATED", "UPDATED", "UNCHANGED", "SKIPPED"}, rows)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axgrid/accessgo"
+)
+
+type invitationRow struct {
+	UserID    uint      `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// inviteCreate приглашает пользователя и выводит токен приглашения
+func (c *cli) inviteCreate(args []string) error {
+	fs := flag.NewFlagSet("invite create", flag.ContinueOnError)
+	email := fs.String("email", "", "email пользователя")
+	name := fs.String("name", "", "имя пользователя")
+	userType := fs.String("type", string(accessgo.UserTypeUser), "тип пользователя: admin, employee, user, service")
+	groups := fs.String("groups", "", "группы через запятую")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *name == "" {
+		return errors.New("необходимо указать -email и -name")
+	}
+	var groupIDs []uint
+	for _, ref := range strings.Split(*groups, ",") {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			continue
+		}
+		group, err := c.findGroup(ref)
+		if err != nil {
+			return err
+		}
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	token, invitation, err := c.svc.InviteUser(*email, *name, accessgo.UserType(*userType), groupIDs)
+	if err != nil {
+		return err
+	}
+	return c.printInvitations([]invitationRow{{invitation.UserID, invitation.User.Email, token, invitation.ExpiresAt}})
+}
+
+func (c *cli) inviteList(args []string) error {
+	invitations, err := c.svc.ListInvitations()
+	if err != nil {
+		return err
+	}
+	rows := make([]invitationRow, 0, len(invitations))
+	for _, invitation := range invitations {
+		rows = append(rows, invitationRow{UserID: invitation.UserID, Email: invitation.User.Email, ExpiresAt: invitation.ExpiresAt})
+	}
+	return c.printInvitations(rows)
+}
+
+// inviteResend выдает приглашенному пользователю новый токен
+func (c *cli) inviteResend(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: invite resend <пользователь>")
+	}
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return err
+	}
+	token, invitation, err := c.svc.ResendInvitation(user.ID)
+	if err != nil {
+		return err
+	}
+	return c.printInvitations([]invitationRow{{invitation.UserID, user.Email, token, invitation.ExpiresAt}})
+}
+
+func (c *cli) inviteRevoke(args []string) error {
+	if len(args) != 1 {
+		return errors.New("использование: invite revoke <пользователь>")
+	}
+	user, err := c.findUser(args[0])
+	if err != nil {
+		return err
+	}
+	if err := c.svc.RevokeInvitation(user.ID); err != nil {
+		return err
+	}
+	return c.done("приглашение " + user.Email + " отозвано")
+}
+
+func (c *cli) printInvitations(invitations []invitationRow) error {
+	if c.format == "json" {
+		return c.printJSON(invitations)
+	}
+	headers := []string{"USER ID", "EMAIL", "EXPIRES"}
+	for _, invitation := range invitations {
+		if invitation.Token != "" {
+			headers = append(headers, "TOKEN")
+			break
+		}
+	}
+	rows := make([][]string, 0, len(invitations))
+	for _, invitation := range invitations {
+		row := []string{strconv.FormatUint(uint64(invitation.UserID), 10), invitation.Email, invitation.ExpiresAt.Format(time.RFC3339)}
+		if invitation.Token != "" {
+			row = append(row, invitation.Token)
+		}
+		rows = append(rows, row)
+	}
+	return c.printTable(headers, rows)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/axgrid/accessgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func runCLI(t *testing.T, dsn string, args ...string) string {
+	var out bytes.Buffer
+	require.NoError(t, run(append([]string{"-dsn", dsn}, args...), &out), out.String())
+	return out.String()
+}
+
+func TestCLIBootstrapAndPermissions(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "access.db")
+
+	runCLI(t, dsn, "migrate")
+	db, err := openDB(dsn)
+	require.NoError(t, err)
+	for _, table := range []string{"outbox_events", "webhook_deliveries", "webhook_dead_letters", "o_auth_consents"} {
+		assert.True(t, db.Migrator().HasTable(table), table)
+	}
+	runCLI(t, dsn, "seed")
+	runCLI(t, dsn, "create-admin", "-email", "admin@example.com", "-password", "secret")
+	runCLI(t, dsn, "user", "create", "-email", "bob@example.com", "-name", "Bob", "-password", "password")
+	runCLI(t, dsn, "group", "create", "deployers")
+	runCLI(t, dsn, "access", "create", "deploy", "-description", "Выкладка")
+	runCLI(t, dsn, "grant", "group", "deployers", "deploy")
+	runCLI(t, dsn, "grant", "user", "bob@example.com", "user:read")
+	runCLI(t, dsn, "member", "add", "bob@example.com", "deployers")
+
+	var rows []permissionRow
+	require.NoError(t, json.Unmarshal([]byte(runCLI(t, dsn, "-format", "json", "permissions", "bob@example.com")), &rows))
+	assert.Equal(t, []permissionRow{
+		{Access: "deploy", Source: "group:deployers"},
+		{Access: "user:read", Source: "direct"},
+	}, rows)
+
+	assert.Equal(t, "разрешено\n", runCLI(t, dsn, "check", "bob@example.com", "deploy"))
+	explanation := runCLI(t, dsn, "explain", "bob@example.com", "deploy")
+	assert.True(t, strings.HasPrefix(explanation, "разрешено\n"), explanation)
+	assert.Contains(t, explanation, `право выдано группе "deployers"`)
+	runCLI(t, dsn, "member", "remove", "bob@example.com", "deployers")
+	assert.Equal(t, "запрещено\n", runCLI(t, dsn, "check", "bob@example.com", "deploy"))
+	var explained accessgo.AccessExplanation
+	require.NoError(t, json.Unmarshal([]byte(runCLI(t, dsn, "-format", "json", "explain", "bob@example.com", "deploy")), &explained))
+	assert.False(t, explained.Allowed)
+	assert.Equal(t, accessgo.AccessReasonNotGranted, explained.Reasons[0].Kind)
+
+	users := runCLI(t, dsn, "user", "list")
+	assert.Contains(t, users, "admin@example.com")
+	assert.Contains(t, users, "bob@example.com")
+
+	runCLI(t, dsn, "user", "delete", "bob@example.com")
+	assert.NotContains(t, runCLI(t, dsn, "user", "list"), "bob@example.com")
+}
+
+func TestCLIErrors(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "access.db")
+	var out bytes.Buffer
+
+	assert.Error(t, run([]string{"-dsn", dsn, "unknown"}, &out))
+	assert.Error(t, run([]string{"-dsn", dsn, "grant", "user", "nobody@example.com", "user:read"}, &out))
+	assert.Error(t, run([]string{"-dsn", "redis://localhost", "migrate"}, &out))
+	assert.Error(t, run([]string{"-dsn", "", "migrate"}, &out))
+}
+
+func TestCLIAuditVerifyAndExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.db")
+	dsn := "sqlite://" + path
+	runCLI(t, dsn, "group", "create", "auditors")
+
+	assert.Contains(t, runCLI(t, dsn, "audit", "verify"), "цепочка не нарушена")
+
+	exported := filepath.Join(t.TempDir(), "audit.jsonl")
+	runCLI(t, dsn, "audit", "export", "-o", exported)
+	data, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// Стандартные права и группа
+	assert.Len(t, lines, 16)
+	assert.Contains(t, lines[15], `"action":"group.create"`)
+
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("UPDATE audit_events SET target_id = '0' WHERE sequence = 3").Error)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	var out bytes.Buffer
+	err = run([]string{"-dsn", dsn, "audit", "verify"}, &out)
+	assert.Error(t, err)
+	assert.Contains(t, out.String(), "modified")
+}
+
+func TestCLIManifestApply(t *testing.T) {
+	dir := t.TempDir()
+	dsn := "sqlite://" + filepath.Join(dir, "access.db")
+	manifest := filepath.Join(dir, "permissions.yaml")
+	require.NoError(t, os.WriteFile(manifest, []byte(`
+accesses:
+  - name: deploy
+    description: Выкладка
+groups:
+  - name: deployers
+    accesses: [deploy]
+`), 0o600))
+
+	out := runCLI(t, dsn, "manifest", "apply", manifest, "-dry-run")
+	assert.Contains(t, out, "create grant deploy -> deployers")
+	assert.Contains(t, out, "изменений в плане: 3, не применены (-dry-run)")
+	assert.NotContains(t, runCLI(t, dsn, "group", "list"), "deployers")
+
+	assert.Contains(t, runCLI(t, dsn, "manifest", "apply", manifest), "применено изменений: 3")
+	assert.Equal(t, "база соответствует манифесту\n", runCLI(t, dsn, "manifest", "apply", manifest))
+	assert.Contains(t, runCLI(t, dsn, "group", "list"), "deployers")
+}
+
+func TestCLIExportImport(t *testing.T) {
+	dir := t.TempDir()
+	source := "sqlite://" + filepath.Join(dir, "staging.db")
+	target := "sqlite://" + filepath.Join(dir, "production.db")
+	runCLI(t, source, "group", "create", "deployers")
+	runCLI(t, source, "access", "create", "deploy")
+	runCLI(t, source, "grant", "group", "deployers", "deploy")
+
+	exported := filepath.Join(dir, "export.json")
+	runCLI(t, source, "export", "-o", exported)
+	out := runCLI(t, target, "import", exported)
+	assert.Regexp(t, `groups\s+1\s+0\s+0\s+0`, out)
+	assert.Regexp(t, `accesses\s+1\s+0\s+15\s+0`, out)
+
+	runCLI(t, target, "revoke", "group", "deployers", "deploy")
+	assert.Error(t, run([]string{"-dsn", target, "import", exported}, &bytes.Buffer{}))
+	runCLI(t, target, "import", exported, "-on-conflict", "overwrite")
+	assert.Contains(t, runCLI(t, target, "export"), `"access": "deploy"`)
+}
+
+func TestCLIUserImport(t *testing.T) {
+	dir := t.TempDir()
+	dsn := "sqlite://" + filepath.Join(dir, "access.db")
+	runCLI(t, dsn, "group", "create", "sales")
+	users := filepath.Join(dir, "users.csv")
+	require.NoError(t, os.WriteFile(users, []byte("email,name,groups,password\n"+
+		"ann@example.com,Ann,sales,secret\nbob@example.com,Bob,,\nbad,Dan,marketing,\n"), 0o600))
+
+	out := runCLI(t, dsn, "user", "import", users)
+	assert.Contains(t, out, `группа "marketing" не найдена`)
+	assert.Contains(t, out, "создано: 0, приглашено: 0, с ошибками: 1, откачено: 0")
+
+	out = runCLI(t, dsn, "user", "import", users, "-chunk", "10")
+	assert.Regexp(t, `2\s+ann@example.com\s+created`, out)
+	assert.Regexp(t, `3\s+bob@example.com\s+invited`, out)
+	assert.Contains(t, out, "создано: 1, приглашено: 1, с ошибками: 1, откачено: 0")
+	assert.Contains(t, runCLI(t, dsn, "member", "list", "sales"), "ann@example.com")
+}
+
+func TestCLIInvite(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "access.db")
+	runCLI(t, dsn, "group", "create", "sales")
+
+	out := runCLI(t, dsn, "-format", "json", "invite", "create", "-email", "bob@example.com", "-name", "Bob", "-groups", "sales")
+	var invitations []struct {
+		Email string `json:"email"`
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &invitations))
+	require.Len(t, invitations, 1)
+	assert.Len(t, invitations[0].Token, 64)
+	assert.Contains(t, runCLI(t, dsn, "member", "list", "sales"), "bob@example.com")
+
+	out = runCLI(t, dsn, "invite", "list")
+	assert.Contains(t, out, "bob@example.com")
+	assert.NotContains(t, out, invitations[0].Token)
+	out = runCLI(t, dsn, "invite", "resend", "bob@example.com")
+	assert.Contains(t, out, "TOKEN")
+	assert.NotContains(t, out, invitations[0].Token)
+
+	assert.Equal(t, "приглашение bob@example.com отозвано\n", runCLI(t, dsn, "invite", "revoke", "bob@example.com"))
+	assert.NotContains(t, runCLI(t, dsn, "user", "list"), "bob@example.com")
+}
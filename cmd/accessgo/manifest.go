@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/axgrid/accessgo"
+)
+
+// manifestApply применяет манифест прав из файла YAML или JSON и выводит план
+func (c *cli) manifestApply(args []string) error {
+	if len(args) == 0 {
+		return errors.New("использование: manifest apply <файл> [-dry-run]")
+	}
+	fs := flag.NewFlagSet("manifest apply", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "только показать план, не применяя его")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	manifest, err := accessgo.ParseManifest(data)
+	if err != nil {
+		return err
+	}
+	plan, err := c.svc.ApplyManifest(manifest, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if c.format == "json" {
+		return c.printJSON(plan)
+	}
+	for _, change := range plan.Changes {
+		if _, err := fmt.Fprintln(c.out, change); err != nil {
+			return err
+		}
+	}
+	switch {
+	case len(plan.Changes) == 0:
+		return c.done("база соответствует манифесту")
+	case *dryRun:
+		return c.done(fmt.Sprintf("изменений в плане: %d, не применены (-dry-run)", len(plan.Changes)))
+	}
+	return c.done(fmt.Sprintf("применено изменений: %d", len(plan.Changes)))
+}
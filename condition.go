@@ -0,0 +1,730 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Условия выдачи прав - выражения над атрибутами запроса, например
+//
+//	cidr(request.ip, "10.0.0.0/8") && hour(now) >= 9 && hour(now) < 18
+//	user.department in ["sales", "support"]
+//	resource.owner_id == user.id
+//
+// Поддерживаются строки, числа, true/false, списки [...], атрибуты через точку,
+// операторы == != < <= > >= in ! && || и скобки. Функции:
+// cidr(ip, сеть), hour(время), weekday(время) (0 - воскресенье), lower(строка).
+// Выражение не может выполнить произвольный код и не обращается к базе
+
+// maxConditionLength ограничивает длину условия (и глубину вложенности)
+const maxConditionLength = 1024
+
+type conditionExpr interface {
+	eval(attrs map[string]any) (any, error)
+}
+
+type conditionFunc struct {
+	arity int
+	call  func(args []any) (any, error)
+	// check проверяет аргументы-литералы при выдаче права
+	check func(args []conditionExpr) error
+}
+
+var conditionFuncs = map[string]conditionFunc{
+	"cidr": {
+		arity: 2,
+		call: func(args []any) (any, error) {
+			address, ok1 := args[0].(string)
+			network, ok2 := args[1].(string)
+			if !ok1 || !ok2 {
+				return nil, errors.New("cidr ожидает строки")
+			}
+			_, ipNet, err := net.ParseCIDR(network)
+			if err != nil {
+				return nil, err
+			}
+			if host, _, err := net.SplitHostPort(address); err == nil {
+				address = host
+			}
+			ip := net.ParseIP(address)
+			return ip != nil && ipNet.Contains(ip), nil
+		},
+		check: func(args []conditionExpr) error {
+			if literal, ok := args[1].(*conditionLiteral); ok {
+				network, ok := literal.value.(string)
+				if !ok {
+					return errors.New("cidr ожидает сеть строкой")
+				}
+				if _, _, err := net.ParseCIDR(network); err != nil {
+					return fmt.Errorf("неверная сеть %q", network)
+				}
+			}
+			return nil
+		},
+	},
+	"hour": {
+		arity: 1,
+		call: func(args []any) (any, error) {
+			t, ok := args[0].(time.Time)
+			if !ok {
+				return nil, errors.New("hour ожидает время")
+			}
+			return float64(t.Hour()), nil
+		},
+	},
+	"weekday": {
+		arity: 1,
+		call: func(args []any) (any, error) {
+			t, ok := args[0].(time.Time)
+			if !ok {
+				return nil, errors.New("weekday ожидает время")
+			}
+			return float64(t.Weekday()), nil
+		},
+	},
+	"lower": {
+		arity: 1,
+		call: func(args []any) (any, error) {
+			s, ok := args[0].(string)
+			if !ok {
+				return nil, errors.New("lower ожидает строку")
+			}
+			return strings.ToLower(s), nil
+		},
+	},
+}
+
+// maxCachedConditions ограничивает число условий в conditionCache
+const maxCachedConditions = 1024
+
+// conditionCache хранит разобранные условия выдачи прав по их тексту. Условия,
+// переданные в EvaluateCondition и ValidateCondition, не кэшируются, а при
+// переполнении кэш очищается, поэтому его размер не растет без ограничений
+var conditionCache = struct {
+	sync.RWMutex
+	exprs map[string]conditionExpr
+}{exprs: make(map[string]conditionExpr)}
+
+// ValidateCondition проверяет синтаксис условия
+func ValidateCondition(condition string) error {
+	_, err := compileCondition(condition)
+	return err
+}
+
+// EvaluateCondition вычисляет условие над атрибутами attrs. Условие выполнено,
+// только если результат равен true; отсутствующий атрибут - ошибка
+func EvaluateCondition(condition string, attrs map[string]any) (bool, error) {
+	expr, err := compileCondition(condition)
+	if err != nil {
+		return false, err
+	}
+	return evaluateCondition(expr, attrs)
+}
+
+func evaluateCondition(expr conditionExpr, attrs map[string]any) (bool, error) {
+	value, err := expr.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, errors.New("результат условия не логическое значение")
+	}
+	return result, nil
+}
+
+// grantCondition возвращает разобранное условие выдачи права из conditionCache
+func grantCondition(condition string) (conditionExpr, error) {
+	if len(condition) > maxConditionLength {
+		return compileCondition(condition)
+	}
+	conditionCache.RLock()
+	expr, ok := conditionCache.exprs[condition]
+	conditionCache.RUnlock()
+	if ok {
+		return expr, nil
+	}
+
+	expr, err := compileCondition(condition)
+	if err != nil {
+		return nil, err
+	}
+	conditionCache.Lock()
+	if len(conditionCache.exprs) >= maxCachedConditions {
+		clear(conditionCache.exprs)
+	}
+	conditionCache.exprs[condition] = expr
+	conditionCache.Unlock()
+	return expr, nil
+}
+
+func compileCondition(condition string) (conditionExpr, error) {
+	if len(condition) > maxConditionLength {
+		return nil, fmt.Errorf("неверное условие: длиннее %d символов", maxConditionLength)
+	}
+	if strings.TrimSpace(condition) == "" {
+		return nil, errors.New("неверное условие: пустое выражение")
+	}
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return nil, fmt.Errorf("неверное условие: %w", err)
+	}
+	parser := &conditionParser{tokens: tokens}
+	expr, err := parser.parseOr()
+	if err == nil && parser.peek().kind != conditionTokenEOF {
+		err = fmt.Errorf("лишний %q в позиции %d", parser.peek().text, parser.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("неверное условие: %w", err)
+	}
+	return expr, nil
+}
+
+type conditionTokenKind int
+
+const (
+	conditionTokenEOF conditionTokenKind = iota
+	conditionTokenIdent
+	conditionTokenNumber
+	conditionTokenString
+	conditionTokenOp
+)
+
+type conditionToken struct {
+	kind  conditionTokenKind
+	text  string
+	value any
+	pos   int
+}
+
+func tokenizeCondition(src string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			var value strings.Builder
+			end := i + 1
+			for ; end < len(src) && src[end] != src[i]; end++ {
+				if src[end] == '\\' && end+1 < len(src) {
+					end++
+				}
+				value.WriteByte(src[end])
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("незакрытая строка в позиции %d", i)
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenString, text: src[i : end+1], value: value.String(), pos: i})
+			i = end + 1
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.') {
+				end++
+			}
+			value, err := strconv.ParseFloat(src[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("неверное число %q", src[i:end])
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenNumber, text: src[i:end], value: value, pos: i})
+			i = end
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := i
+			for end < len(src) && isConditionIdentByte(src[end]) {
+				end++
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenIdent, text: src[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("неожиданный символ %q в позиции %d", c, i)
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, conditionToken{kind: conditionTokenEOF, text: "конец выражения", pos: len(src)}), nil
+}
+
+func isConditionIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+var conditionComparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) peek() conditionToken {
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() conditionToken {
+	token := p.tokens[p.pos]
+	if token.kind != conditionTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// accept пропускает оператор op, если он следующий
+func (p *conditionParser) accept(op string) bool {
+	token := p.peek()
+	if token.kind == conditionTokenOp && token.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) expect(op string) error {
+	if !p.accept(op) {
+		token := p.peek()
+		return fmt.Errorf("ожидается %q вместо %q в позиции %d", op, token.text, token.pos)
+	}
+	return nil
+}
+
+func (p *conditionParser) parseOr() (conditionExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var right conditionExpr
+		if right, err = p.parseAnd(); err == nil {
+			left = &conditionLogical{or: true, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *conditionParser) parseAnd() (conditionExpr, error) {
+	left, err := p.parseNot()
+	for err == nil && p.accept("&&") {
+		var right conditionExpr
+		if right, err = p.parseNot(); err == nil {
+			left = &conditionLogical{left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *conditionParser) parseNot() (conditionExpr, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionNot{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *conditionParser) parseCompare() (conditionExpr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	token := p.peek()
+	if token.kind == conditionTokenOp && conditionComparisons[token.text] || token.kind == conditionTokenIdent && token.text == "in" {
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionCompare{op: token.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parsePrimary() (conditionExpr, error) {
+	token := p.next()
+	switch token.kind {
+	case conditionTokenNumber, conditionTokenString:
+		return &conditionLiteral{value: token.value}, nil
+	case conditionTokenIdent:
+		switch token.text {
+		case "true", "false":
+			return &conditionLiteral{value: token.text == "true"}, nil
+		case "in":
+			return nil, fmt.Errorf("неожиданный \"in\" в позиции %d", token.pos)
+		}
+		if !p.accept("(") {
+			return &conditionAttr{path: token.text}, nil
+		}
+		fn, ok := conditionFuncs[token.text]
+		if !ok {
+			return nil, fmt.Errorf("неизвестная функция %q", token.text)
+		}
+		args, err := p.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+		if len(args) != fn.arity {
+			return nil, fmt.Errorf("функция %s ожидает %d аргумент(а)", token.text, fn.arity)
+		}
+		if fn.check != nil {
+			if err := fn.check(args); err != nil {
+				return nil, err
+			}
+		}
+		return &conditionCall{fn: fn, args: args}, nil
+	case conditionTokenOp:
+		switch token.text {
+		case "(":
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &conditionList{items: items}, nil
+		}
+	}
+	return nil, fmt.Errorf("неожиданный %q в позиции %d", token.text, token.pos)
+}
+
+// parseList разбирает выражения через запятую до закрывающей скобки end
+func (p *conditionParser) parseList(end string) ([]conditionExpr, error) {
+	var items []conditionExpr
+	if p.accept(end) {
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.accept(end) {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type conditionLiteral struct {
+	value any
+}
+
+func (e *conditionLiteral) eval(map[string]any) (any, error) {
+	return e.value, nil
+}
+
+type conditionAttr struct {
+	path string
+}
+
+// eval ищет атрибут сначала по полному имени ("resource.owner_id"), затем во вложенных картах
+func (e *conditionAttr) eval(attrs map[string]any) (any, error) {
+	if value, ok := attrs[e.path]; ok {
+		return value, nil
+	}
+	var current any = attrs
+	for _, part := range strings.Split(e.path, ".") {
+		var ok bool
+		switch m := current.(type) {
+		case map[string]any:
+			current, ok = m[part]
+		case map[string]string:
+			current, ok = m[part]
+		}
+		if !ok {
+			return nil, fmt.Errorf("атрибут %q не задан", e.path)
+		}
+	}
+	return current, nil
+}
+
+type conditionList struct {
+	items []conditionExpr
+}
+
+func (e *conditionList) eval(attrs map[string]any) (any, error) {
+	values := make([]any, 0, len(e.items))
+	for _, item := range e.items {
+		value, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type conditionNot struct {
+	operand conditionExpr
+}
+
+func (e *conditionNot) eval(attrs map[string]any) (any, error) {
+	value, err := evalConditionBool(e.operand, attrs)
+	return !value, err
+}
+
+type conditionLogical struct {
+	or          bool
+	left, right conditionExpr
+}
+
+func (e *conditionLogical) eval(attrs map[string]any) (any, error) {
+	left, err := evalConditionBool(e.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	if left == e.or {
+		return left, nil
+	}
+	return evalConditionBool(e.right, attrs)
+}
+
+type conditionCompare struct {
+	op          string
+	left, right conditionExpr
+}
+
+func (e *conditionCompare) eval(attrs map[string]any) (any, error) {
+	left, err := e.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==":
+		return conditionEqual(left, right), nil
+	case "!=":
+		return !conditionEqual(left, right), nil
+	case "in":
+		list := reflect.ValueOf(right)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return nil, errors.New("in ожидает список")
+		}
+		for i := 0; i < list.Len(); i++ {
+			if conditionEqual(left, list.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	cmp, err := conditionOrder(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type conditionCall struct {
+	fn   conditionFunc
+	args []conditionExpr
+}
+
+func (e *conditionCall) eval(attrs map[string]any) (any, error) {
+	args := make([]any, 0, len(e.args))
+	for _, arg := range e.args {
+		value, err := arg.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, normalizeConditionValue(value))
+	}
+	return e.fn.call(args)
+}
+
+func evalConditionBool(expr conditionExpr, attrs map[string]any) (bool, error) {
+	value, err := expr.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("ожидается логическое значение вместо %v", value)
+	}
+	return result, nil
+}
+
+// normalizeConditionValue приводит числа к float64, а IP и строковые типы - к string
+func normalizeConditionValue(value any) any {
+	switch v := value.(type) {
+	case time.Time, string, bool, float64:
+		return v
+	case net.IP:
+		return v.String()
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return value
+}
+
+// conditionEqual сравнивает значения; значения разных типов не равны
+func conditionEqual(left, right any) bool {
+	left, right = normalizeConditionValue(left), normalizeConditionValue(right)
+	switch l := left.(type) {
+	case time.Time:
+		r, ok := right.(time.Time)
+		return ok && l.Equal(r)
+	case string, bool, float64:
+		return left == right
+	}
+	return false
+}
+
+func conditionOrder(left, right any) (int, error) {
+	left, right = normalizeConditionValue(left), normalizeConditionValue(right)
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return compareOrdered(l, r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	case time.Time:
+		if r, ok := right.(time.Time); ok {
+			return l.Compare(r), nil
+		}
+	}
+	return 0, fmt.Errorf("значения %v и %v нельзя сравнить", left, right)
+}
+
+func compareOrdered(left, right float64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
+
+// conditionsQuery выбирает условия прав accessName, выданных пользователю напрямую
+// и через группы. Безусловные выдачи проверяет userPermissions
+const conditionsQuery = `
+SELECT access_levels.access_condition
+FROM access_levels
+JOIN accesses ON accesses.id = access_levels.access_id AND accesses.deleted_at IS NULL
+WHERE accesses.name = ? AND access_levels.deleted_at IS NULL AND access_levels.access_condition <> ''
+AND (access_levels.user_id = ? OR access_levels.group_id IN (
+	SELECT user_groups.group_id
+	FROM user_groups
+	JOIN ? ON g.id = user_groups.group_id AND g.deleted_at IS NULL
+	WHERE user_groups.user_id = ?
+))`
+
+// CheckUserAccessWithContext проверяет право с учетом условий выдачи. Условия
+// вычисляются над attrs, к которым добавляются user.id, user.email, user.name,
+// user.type из базы и now - текущее время, если оно не передано. Условие, которое
+// не удалось вычислить (например, из-за отсутствующего атрибута), не выполнено
+func (s *AccessGoService) CheckUserAccessWithContext(userID uint, accessName string, attrs map[string]any) (bool, error) {
+	return s.CheckUserAccessWithContextCtx(s.ctx, userID, accessName, attrs)
+}
+
+// CheckUserAccessWithContextCtx - CheckUserAccessWithContext с контекстом ctx
+func (s *AccessGoService) CheckUserAccessWithContextCtx(ctx context.Context, userID uint, accessName string, attrs map[string]any) (bool, error) {
+	s = s.WithContext(ctx)
+	permissions, err := s.userPermissions(userID)
+	if err != nil {
+		return false, err
+	}
+	if permissions[accessName] {
+		return true, nil
+	}
+
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return false, ErrUserNotFound
+	}
+	var conditions []string
+	groupsTable := clause.Table{Name: "groups", Alias: "g"}
+	if err := s.db.Raw(conditionsQuery, accessName, userID, groupsTable, userID).Scan(&conditions).Error; err != nil {
+		return false, err
+	}
+	if len(conditions) == 0 {
+		return false, nil
+	}
+
+	attrs = conditionAttrs(&user, attrs)
+	for _, condition := range conditions {
+		expr, err := grantCondition(condition)
+		if err != nil {
+			continue
+		}
+		if ok, err := evaluateCondition(expr, attrs); err == nil && ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// conditionAttrs дополняет атрибуты запроса данными пользователя и текущим временем.
+// Данные пользователя из базы заменяют переданные с теми же именами
+func conditionAttrs(user *User, attrs map[string]any) map[string]any {
+	result := maps.Clone(attrs)
+	if result == nil {
+		result = make(map[string]any)
+	}
+	userAttrs := make(map[string]any)
+	if provided, ok := attrs["user"].(map[string]any); ok {
+		maps.Copy(userAttrs, provided)
+	}
+	userAttrs["id"] = user.ID
+	userAttrs["email"] = user.Email
+	userAttrs["name"] = user.Name
+	userAttrs["type"] = user.UserType
+	result["user"] = userAttrs
+	for _, key := range []string{"user.id", "user.email", "user.name", "user.type"} {
+		delete(result, key)
+	}
+	if _, ok := result["now"]; !ok {
+		result["now"] = time.Now()
+	}
+	return result
+}
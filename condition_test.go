@@ -0,0 +1,152 @@
+package accessgo
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	attrs := map[string]any{
+		"request":     map[string]any{"ip": "10.1.2.3:51234"},
+		"user":        map[string]any{"id": uint(7), "department": "Sales"},
+		"resource.id": 42,
+		"now":         time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC),
+		"tags":        []string{"beta", "internal"},
+	}
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{`cidr(request.ip, "10.0.0.0/8")`, true},
+		{`cidr(request.ip, '192.168.0.0/16')`, false},
+		{`hour(now) >= 9 && hour(now) < 18 && weekday(now) != 0`, true},
+		{`lower(user.department) in ["sales", "support"]`, true},
+		{`user.id == 7 && resource.id > 40.5`, true},
+		{`!(user.id == 7) || "internal" in tags`, true},
+		{`user.department == 7`, false},
+		{`user.department != "sales"`, true},
+		{`now < now`, false},
+		{`"It's" == 'It\'s'`, true},
+	}
+	for _, test := range tests {
+		got, err := EvaluateCondition(test.condition, attrs)
+		require.NoError(t, err, test.condition)
+		assert.Equal(t, test.want, got, test.condition)
+	}
+
+	_, err := EvaluateCondition(`user.office == "HQ"`, attrs)
+	assert.EqualError(t, err, `атрибут "user.office" не задан`)
+	_, err = EvaluateCondition(`user.department`, attrs)
+	assert.EqualError(t, err, "результат условия не логическое значение")
+	_, err = EvaluateCondition(`user.department < 3`, attrs)
+	assert.Error(t, err)
+	// Второй операнд || не вычисляется, если первый истинен
+	ok, err := EvaluateCondition(`true || missing.attr`, attrs)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	for condition, message := range map[string]string{
+		``:                         "неверное условие: пустое выражение",
+		`user.id ==`:               `неверное условие: неожиданный "конец выражения" в позиции 10`,
+		`exec("rm -rf /")`:         `неверное условие: неизвестная функция "exec"`,
+		`cidr(request.ip, "10.0")`: `неверное условие: неверная сеть "10.0"`,
+		`hour()`:                   "неверное условие: функция hour ожидает 1 аргумент(а)",
+		`(user.id == 1`:            `неверное условие: ожидается ")" вместо "конец выражения" в позиции 13`,
+		`user.id = 1`:              `неверное условие: неожиданный символ '=' в позиции 8`,
+		`"open`:                    "неверное условие: незакрытая строка в позиции 0",
+		`true false`:               `неверное условие: лишний "false" в позиции 5`,
+	} {
+		assert.EqualError(t, ValidateCondition(condition), message, condition)
+	}
+}
+
+func TestConditionCache(t *testing.T) {
+	// Произвольные условия из EvaluateCondition не кэшируются
+	_, err := EvaluateCondition(`user.id == 123456`, map[string]any{"user": map[string]any{"id": 1}})
+	require.NoError(t, err)
+	conditionCache.RLock()
+	_, cached := conditionCache.exprs[`user.id == 123456`]
+	conditionCache.RUnlock()
+	assert.False(t, cached)
+
+	for i := 0; i <= maxCachedConditions; i++ {
+		_, err := grantCondition("user.id == " + strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+	conditionCache.RLock()
+	assert.LessOrEqual(t, len(conditionCache.exprs), maxCachedConditions)
+	conditionCache.RUnlock()
+
+	_, err = grantCondition("true || " + strings.Repeat("true || ", maxConditionLength/8) + "true")
+	assert.EqualError(t, err, "неверное условие: длиннее 1024 символов")
+}
+
+func TestCheckUserAccessWithContext(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	bob, err := service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	alice, err := service.CreateUser("alice@example.com", "password", "Alice", UserTypeUser)
+	require.NoError(t, err)
+	office, err := service.CreateGroup("office")
+	require.NoError(t, err)
+	require.NoError(t, service.AssignUserToGroup(bob.ID, office.ID))
+
+	assert.EqualError(t, service.AddUserAccessLevelWithCondition(bob.ID, "user:update", "user.id =="),
+		`неверное условие: неожиданный "конец выражения" в позиции 10`)
+	require.NoError(t, service.AddGroupAccessLevelWithCondition(office.ID, "user:read", `cidr(request.ip, "10.0.0.0/8")`))
+	require.NoError(t, service.AddUserAccessLevelWithCondition(bob.ID, "user:update", "resource.owner_id == user.id"))
+	require.NoError(t, service.AddUserAccessLevel(alice.ID, "user:update"))
+
+	// Без контекста условные выдачи не действуют
+	allowed, err := service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	checks, err := service.CheckUsersAccess([]uint{bob.ID, alice.ID}, "user:update")
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]bool{bob.ID: false, alice.ID: true}, checks)
+
+	allowed, err = service.CheckUserAccessWithContext(bob.ID, "user:read", map[string]any{"request": map[string]any{"ip": "10.0.0.5"}})
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = service.CheckUserAccessWithContext(bob.ID, "user:read", map[string]any{"request.ip": "8.8.8.8"})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = service.CheckUserAccessWithContext(bob.ID, "user:read", nil)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// user.id берется из базы, а не из переданных атрибутов
+	allowed, err = service.CheckUserAccessWithContext(bob.ID, "user:update", map[string]any{"resource": map[string]any{"owner_id": bob.ID}})
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = service.CheckUserAccessWithContext(bob.ID, "user:update", map[string]any{
+		"resource": map[string]any{"owner_id": alice.ID},
+		"user":     map[string]any{"id": alice.ID},
+	})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Безусловная выдача действует при любых атрибутах
+	allowed, err = service.CheckUserAccessWithContext(alice.ID, "user:update", nil)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	_, err = service.CheckUserAccessWithContext(999, "user:update", nil)
+	assert.EqualError(t, err, "пользователь не найден")
+
+	explanation, err := service.ExplainUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	assert.False(t, explanation.Allowed)
+	require.Len(t, explanation.Reasons, 2)
+	assert.Equal(t, `cidr(request.ip, "10.0.0.0/8")`, explanation.Reasons[0].Condition)
+	assert.Equal(t, AccessReasonNotGranted, explanation.Reasons[1].Kind)
+
+	events, err := service.QueryAuditLog(AuditQuery{Action: AuditGroupAccessGrant})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0].After, "cidr")
+}
@@ -0,0 +1,309 @@
+package accessgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// EventType - тип доменного события
+type EventType string
+
+const (
+	EventUserCreated            EventType = "user.created"
+	EventUserUpdated            EventType = "user.updated"
+	EventUserDeleted            EventType = "user.deleted"
+	EventGroupMembershipChanged EventType = "group.membership_changed"
+	EventAccessGranted          EventType = "access.granted"
+	EventAccessRevoked          EventType = "access.revoked"
+	EventLoginSucceeded         EventType = "login.succeeded"
+	EventLoginFailed            EventType = "login.failed"
+)
+
+// Event - доменное событие AccessGoService. Инициатор и идентификатор запроса
+// передаются обработчикам в контексте (ActorFromContext, RequestIDFromContext)
+type Event interface {
+	EventType() EventType
+}
+
+// EventUser - данные пользователя в событии, без пароля и токенов
+type EventUser struct {
+	ID            uint   `json:"id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	UserType      string `json:"user_type"`
+	Source        string `json:"source"`
+	EmailValidate bool   `json:"email_validate"`
+}
+
+func newEventUser(user *User) EventUser {
+	return EventUser{
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Name,
+		UserType:      user.UserType,
+		Source:        user.Source,
+		EmailValidate: user.EmailValidate,
+	}
+}
+
+// UserCreated - создан пользователь. До создания у User еще нет ID
+type UserCreated struct {
+	User EventUser `json:"user"`
+}
+
+// UserUpdated - изменены данные пользователя
+type UserUpdated struct {
+	Before EventUser `json:"before"`
+	After  EventUser `json:"after"`
+}
+
+// UserDeleted - пользователь удален
+type UserDeleted struct {
+	User EventUser `json:"user"`
+}
+
+// GroupMembershipChanged - пользователь добавлен в группы Added и исключен из групп Removed
+type GroupMembershipChanged struct {
+	UserID  uint   `json:"user_id"`
+	Added   []uint `json:"added,omitempty"`
+	Removed []uint `json:"removed,omitempty"`
+}
+
+// AccessGranted - право Access выдано пользователю UserID или группе GroupID
+type AccessGranted struct {
+	Access    string `json:"access"`
+	UserID    *uint  `json:"user_id,omitempty"`
+	GroupID   *uint  `json:"group_id,omitempty"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// AccessRevoked - право Access отозвано у пользователя UserID или группы GroupID
+type AccessRevoked struct {
+	Access  string `json:"access"`
+	UserID  *uint  `json:"user_id,omitempty"`
+	GroupID *uint  `json:"group_id,omitempty"`
+}
+
+// LoginSucceeded - пользователь прошел аутентификацию способом Method.
+// Отказ обработчика OnBefore отклоняет вход
+type LoginSucceeded struct {
+	User   EventUser `json:"user"`
+	Method string    `json:"method"`
+}
+
+// LoginFailed - неудачная попытка входа. UserID не задан, если email не найден.
+// Событие только уведомляет: обработчики OnBefore для него не вызываются
+type LoginFailed struct {
+	Email  string `json:"email"`
+	UserID *uint  `json:"user_id,omitempty"`
+	Method string `json:"method"`
+	Reason string `json:"reason"`
+}
+
+func (UserCreated) EventType() EventType            { return EventUserCreated }
+func (UserUpdated) EventType() EventType            { return EventUserUpdated }
+func (UserDeleted) EventType() EventType            { return EventUserDeleted }
+func (GroupMembershipChanged) EventType() EventType { return EventGroupMembershipChanged }
+func (AccessGranted) EventType() EventType          { return EventAccessGranted }
+func (AccessRevoked) EventType() EventType          { return EventAccessRevoked }
+func (LoginSucceeded) EventType() EventType         { return EventLoginSucceeded }
+func (LoginFailed) EventType() EventType            { return EventLoginFailed }
+
+// BeforeHook вызывается синхронно до изменения в его транзакции.
+// Ошибка отменяет операцию и возвращается вызывающему. Изменять данные из
+// обработчика можно только через сервис с его контекстом: svc.WithContext(ctx)
+// или методы *Ctx(ctx, ...) выполняются в той же транзакции. Изменяющие методы
+// сервиса без этого контекста возвращают ErrBeforeHookCall
+type BeforeHook func(ctx context.Context, event Event) error
+
+// ErrBeforeHookCall возвращается изменяющими методами сервиса, вызванными из
+// обработчика BeforeHook без его контекста. Такой вызов ждал бы фиксации
+// транзакции, которая сама ждет завершения обработчика
+var ErrBeforeHookCall = errors.New("вызов сервиса из обработчика BeforeHook должен использовать его контекст: WithContext(ctx) или методы *Ctx")
+
+// AfterHook вызывается асинхронно после фиксации изменения
+type AfterHook func(ctx context.Context, event Event)
+
+// txContextKey - ключ контекста обработчика BeforeHook, под которым хранится
+// сервис транзакции изменения
+type txContextKey struct{}
+
+// eventBus хранит обработчики событий. Общий для копий сервиса
+type eventBus struct {
+	mu      sync.RWMutex
+	before  map[EventType][]BeforeHook
+	after   map[EventType][]AfterHook
+	running sync.WaitGroup
+	// outbox включает запись событий в таблицу outbox_events (NewOutboxRelay)
+	outbox atomic.Bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		before: make(map[EventType][]BeforeHook),
+		after:  make(map[EventType][]AfterHook),
+	}
+}
+
+// AddBeforeHook регистрирует обработчик, вызываемый до события eventType.
+// Пустой eventType означает все события. Обработчик, которому нужно изменить
+// данные, должен вызывать сервис с полученным контекстом (см. BeforeHook)
+func (s *AccessGoService) AddBeforeHook(eventType EventType, hook BeforeHook) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	s.events.before[eventType] = append(s.events.before[eventType], hook)
+}
+
+// AddAfterHook регистрирует обработчик, вызываемый после события eventType.
+// Пустой eventType означает все события
+func (s *AccessGoService) AddAfterHook(eventType EventType, hook AfterHook) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	s.events.after[eventType] = append(s.events.after[eventType], hook)
+}
+
+// OnBefore регистрирует типизированный обработчик, вызываемый до события E
+func OnBefore[E Event](s *AccessGoService, hook func(ctx context.Context, event E) error) {
+	var zero E
+	s.AddBeforeHook(zero.EventType(), func(ctx context.Context, event Event) error {
+		return hook(ctx, event.(E))
+	})
+}
+
+// OnAfter регистрирует типизированный обработчик, вызываемый после события E
+func OnAfter[E Event](s *AccessGoService, hook func(ctx context.Context, event E)) {
+	var zero E
+	s.AddAfterHook(zero.EventType(), func(ctx context.Context, event Event) {
+		hook(ctx, event.(E))
+	})
+}
+
+// WaitEvents ожидает завершения запущенных обработчиков AddAfterHook
+func (s *AccessGoService) WaitEvents() {
+	s.events.running.Wait()
+}
+
+// beforeEvent вызывает обработчики до события и возвращает первый отказ
+func (s *AccessGoService) beforeEvent(event Event) error {
+	s.events.mu.RLock()
+	hooks := append(append([]BeforeHook{}, s.events.before[""]...), s.events.before[event.EventType()]...)
+	s.events.mu.RUnlock()
+
+	if len(hooks) == 0 {
+		return nil
+	}
+	ctx := s.ctx
+	if s.inTransaction {
+		ctx = context.WithValue(ctx, txContextKey{}, s)
+		// Обработчик выполняется в горутине, удерживающей блокировку цепочки аудита
+		prev := s.auditChain.hookGoroutine.Swap(goroutineID())
+		defer s.auditChain.hookGoroutine.Store(prev)
+	}
+	for _, hook := range hooks {
+		if err := hook(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goroutineID возвращает номер текущей горутины из первой строки ее стека
+// ("goroutine 42 [running]:")
+func goroutineID() uint64 {
+	var buf [64]byte
+	fields := bytes.Fields(buf[:runtime.Stack(buf[:], false)])
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(string(fields[1]), 10, 64)
+	return id
+}
+
+// afterEvent публикует событие: записывает его в outbox и запускает обработчики.
+// В транзакции запуск обработчиков откладывается до ее фиксации, а при откате
+// событие отбрасывается вместе с записью outbox
+func (s *AccessGoService) afterEvent(event Event) error {
+	if s.events.outbox.Load() {
+		if err := s.writeOutbox(event); err != nil {
+			return err
+		}
+	}
+	if s.pendingEvents != nil {
+		*s.pendingEvents = append(*s.pendingEvents, event)
+		return nil
+	}
+	s.dispatchEvent(event)
+	return nil
+}
+
+// dispatchEvent запускает обработчики AddAfterHook
+func (s *AccessGoService) dispatchEvent(event Event) {
+	s.events.mu.RLock()
+	hooks := append(append([]AfterHook{}, s.events.after[""]...), s.events.after[event.EventType()]...)
+	s.events.mu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	// Обработчики работают дольше запроса, поэтому отмена контекста на них не действует
+	ctx := context.WithoutCancel(s.ctx)
+	s.events.running.Add(1)
+	go func() {
+		defer s.events.running.Done()
+		for _, hook := range hooks {
+			hook(ctx, event)
+		}
+	}()
+}
+
+// membershipChange возвращает изменение членства при замене групп before на after
+func membershipChange(userID uint, before, after []Group) GroupMembershipChanged {
+	event := GroupMembershipChanged{UserID: userID}
+	current := make(map[uint]bool, len(before))
+	for _, group := range before {
+		current[group.ID] = true
+	}
+	for _, group := range after {
+		if current[group.ID] {
+			delete(current, group.ID)
+			continue
+		}
+		event.Added = append(event.Added, group.ID)
+	}
+	for _, group := range before {
+		if current[group.ID] {
+			event.Removed = append(event.Removed, group.ID)
+		}
+	}
+	return event
+}
+
+// completeLogin завершает попытку входа: дает обработчикам отклонить успешный
+// вход, записывает попытку в журнал аудита и публикует событие
+func (s *AccessGoService) completeLogin(method, email string, user *User, err error) (*User, error) {
+	if err == nil {
+		err = s.beforeEvent(LoginSucceeded{User: newEventUser(user), Method: method})
+	}
+	if auditErr := s.auditLogin(method, email, user, err); auditErr != nil {
+		return nil, errors.Join(err, auditErr)
+	}
+	if err != nil {
+		failed := LoginFailed{Email: email, Method: method, Reason: err.Error()}
+		if user != nil {
+			failed.UserID = &user.ID
+		}
+		if publishErr := s.afterEvent(failed); publishErr != nil {
+			return nil, errors.Join(err, publishErr)
+		}
+		return nil, err
+	}
+	if err := s.afterEvent(LoginSucceeded{User: newEventUser(user), Method: method}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,240 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder собирает события, переданные обработчикам AddAfterHook
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+	actors []uint
+}
+
+func (r *eventRecorder) hook(ctx context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	if actor, ok := ActorFromContext(ctx); ok {
+		r.actors = append(r.actors, actor)
+	}
+}
+
+func (r *eventRecorder) types() []EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]EventType, 0, len(r.events))
+	for _, event := range r.events {
+		types = append(types, event.EventType())
+	}
+	return types
+}
+
+func TestEventsAfterHooks(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	recorder := &eventRecorder{}
+	service.AddAfterHook("", recorder.hook)
+
+	var updated UserUpdated
+	OnAfter(service, func(ctx context.Context, event UserUpdated) {
+		updated = event
+	})
+
+	ctx := ContextWithActor(context.Background(), 42)
+	user, err := service.CreateUserCtx(ctx, "bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	_, err = service.UpdateUserCtx(ctx, user.ID, "bob@example.com", "", "Robert", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevelCtx(ctx, user.ID, "user:read"))
+	require.NoError(t, service.RemoveUserAccessLevelCtx(ctx, user.ID, "user:read"))
+	require.NoError(t, service.DeleteUserCtx(ctx, user.ID))
+	service.WaitEvents()
+
+	// Обработчики разных событий выполняются параллельно, поэтому порядок не проверяется
+	assert.ElementsMatch(t, []EventType{
+		EventUserCreated, EventUserUpdated, EventAccessGranted, EventAccessRevoked, EventUserDeleted,
+	}, recorder.types())
+	assert.Equal(t, []uint{42, 42, 42, 42, 42}, recorder.actors)
+
+	assert.Equal(t, "Bob", updated.Before.Name)
+	assert.Equal(t, "Robert", updated.After.Name)
+	assert.Equal(t, user.ID, updated.After.ID)
+}
+
+func TestEventsBeforeHookVeto(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	recorder := &eventRecorder{}
+	service.AddAfterHook("", recorder.hook)
+
+	OnBefore(service, func(ctx context.Context, event UserCreated) error {
+		if event.User.Email == "blocked@example.com" {
+			return errors.New("домен запрещен")
+		}
+		return nil
+	})
+	OnBefore(service, func(ctx context.Context, event AccessGranted) error {
+		if event.Access == "access:delete" {
+			return errors.New("выдача права запрещена")
+		}
+		return nil
+	})
+
+	_, err := service.CreateUser("blocked@example.com", "password", "Blocked", UserTypeUser)
+	require.EqualError(t, err, "домен запрещен")
+	_, err = service.GetUserByEmail("blocked@example.com")
+	require.Error(t, err)
+
+	// Отказ во вложенной операции отменяет всю транзакцию вместе с отложенными событиями
+	require.Error(t, service.CreateDefaultAdminUser("admin@example.com", "secret", "Admin"))
+	_, err = service.GetUserByEmail("admin@example.com")
+	require.Error(t, err)
+
+	service.WaitEvents()
+	assert.Empty(t, recorder.types())
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetUser})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestEventsBeforeHookJoinsTransaction(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	OnBefore(service, func(ctx context.Context, event UserCreated) error {
+		if _, err := service.WithContext(ctx).CreateGroup("team-" + event.User.Name); err != nil {
+			return err
+		}
+		if event.User.Email == "blocked@example.com" {
+			return errors.New("домен запрещен")
+		}
+		return nil
+	})
+
+	// Изменения обработчика выполняются в транзакции события и не блокируют сервис
+	_, err := service.CreateUser("ann@example.com", "password", "ann", UserTypeUser)
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, service.db.Model(&Group{}).Where("name = ?", "team-ann").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	// Отказ откатывает и изменения, сделанные обработчиком
+	_, err = service.CreateUser("blocked@example.com", "password", "bob", UserTypeUser)
+	require.EqualError(t, err, "домен запрещен")
+	require.NoError(t, service.db.Model(&Group{}).Where("name = ?", "team-bob").Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestEventsBeforeHookPlainCallFailsFast(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	var hookErr error
+	OnBefore(service, func(ctx context.Context, event UserCreated) error {
+		// Вызов без контекста обработчика не ждет фиксации его транзакции
+		_, hookErr = service.CreateGroup("team-" + event.User.Name)
+		return hookErr
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.CreateUser("ann@example.com", "password", "ann", UserTypeUser)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrBeforeHookCall)
+		assert.ErrorIs(t, hookErr, ErrBeforeHookCall)
+	case <-time.After(5 * time.Second):
+		t.Fatal("вызов сервиса из обработчика BeforeHook заблокировался")
+	}
+	_, err := service.GetUserByEmail("ann@example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// После обработчика сервис доступен для обычных вызовов
+	_, err = service.CreateGroup("ops")
+	assert.NoError(t, err)
+}
+
+func TestEventsGroupMembership(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	var mu sync.Mutex
+	var changes []GroupMembershipChanged
+	OnAfter(service, func(ctx context.Context, event GroupMembershipChanged) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, event)
+	})
+
+	user, err := service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	a, err := service.CreateGroup("a")
+	require.NoError(t, err)
+	b, err := service.CreateGroup("b")
+	require.NoError(t, err)
+	c, err := service.CreateGroup("c")
+	require.NoError(t, err)
+
+	require.NoError(t, service.AssignUserToGroup(user.ID, a.ID))
+	service.WaitEvents()
+	require.NoError(t, service.SetUserGroups(user.ID, b.ID, c.ID))
+	service.WaitEvents()
+	require.NoError(t, service.ExcludeUserFromGroup(user.ID, b.ID))
+	service.WaitEvents()
+
+	assert.Equal(t, []GroupMembershipChanged{
+		{UserID: user.ID, Added: []uint{a.ID}},
+		{UserID: user.ID, Added: []uint{b.ID, c.ID}, Removed: []uint{a.ID}},
+		{UserID: user.ID, Removed: []uint{b.ID}},
+	}, changes)
+}
+
+func TestEventsLogin(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	user, err := service.CreateUser("bob@example.com", "secret", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(user.EmailValidationToken))
+
+	recorder := &eventRecorder{}
+	service.AddAfterHook(EventLoginSucceeded, recorder.hook)
+	service.AddAfterHook(EventLoginFailed, recorder.hook)
+
+	_, err = service.AuthenticateUser("bob@example.com", "secret")
+	require.NoError(t, err)
+	_, err = service.AuthenticateUser("bob@example.com", "wrong")
+	require.Error(t, err)
+
+	OnBefore(service, func(ctx context.Context, event LoginSucceeded) error {
+		return errors.New("вход временно запрещен")
+	})
+	_, err = service.AuthenticateUser("bob@example.com", "secret")
+	require.EqualError(t, err, "вход временно запрещен")
+	service.WaitEvents()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Len(t, recorder.events, 3)
+	var failures []LoginFailed
+	for _, event := range recorder.events {
+		switch event := event.(type) {
+		case LoginSucceeded:
+			assert.Equal(t, user.ID, event.User.ID)
+			assert.Equal(t, LoginMethodPassword, event.Method)
+		case LoginFailed:
+			failures = append(failures, event)
+		}
+	}
+	require.Len(t, failures, 2)
+	for _, failure := range failures {
+		require.NotNil(t, failure.UserID)
+		assert.Equal(t, user.ID, *failure.UserID)
+	}
+
+	logins, err := service.QueryAuditLog(AuditQuery{Action: AuditLoginFailure})
+	require.NoError(t, err)
+	require.Len(t, logins, 2)
+	assert.Contains(t, logins[0].Details, "вход временно запрещен")
+}
@@ -0,0 +1,137 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccessReasonKind определяет вид причины решения о доступе
+type AccessReasonKind string
+
+const (
+	AccessReasonDirect         AccessReasonKind = "direct"           // право выдано пользователю
+	AccessReasonGroup          AccessReasonKind = "group"            // право выдано группе пользователя
+	AccessReasonAccessNotFound AccessReasonKind = "access_not_found" // права с таким именем нет
+	AccessReasonNotGranted     AccessReasonKind = "not_granted"      // право не выдано ни пользователю, ни его группам
+)
+
+// AccessReason - одна из причин решения о доступе
+type AccessReason struct {
+	Kind          AccessReasonKind `json:"kind"`
+	Description   string           `json:"description"`
+	AccessLevelID uint             `json:"access_level_id,omitempty"`
+	GroupID       uint             `json:"group_id,omitempty"`
+	GroupName     string           `json:"group_name,omitempty"`
+	GrantedAt     *time.Time       `json:"granted_at,omitempty"`
+	// Condition - условие выдачи. Такая выдача действует только в CheckUserAccessWithContext
+	Condition string `json:"condition,omitempty"`
+}
+
+// AccessExplanation содержит решение CheckUserAccess и причины, по которым оно принято.
+// Выдачи с условиями перечисляются, но на решение не влияют
+type AccessExplanation struct {
+	UserID  uint           `json:"user_id"`
+	Email   string         `json:"email"`
+	Access  string         `json:"access"`
+	Allowed bool           `json:"allowed"`
+	Reasons []AccessReason `json:"reasons"`
+}
+
+// ExplainUserAccess объясняет, почему пользователь имеет или не имеет право:
+// перечисляет прямые выдачи и группы, через которые право получено, в том числе
+// выдачи с условиями. Права читаются из базы, минуя кэш
+func (s *AccessGoService) ExplainUserAccess(userID uint, accessName string) (*AccessExplanation, error) {
+	return s.ExplainUserAccessCtx(s.ctx, userID, accessName)
+}
+
+// ExplainUserAccessCtx - ExplainUserAccess с контекстом ctx
+func (s *AccessGoService) ExplainUserAccessCtx(ctx context.Context, userID uint, accessName string) (*AccessExplanation, error) {
+	s = s.WithContext(ctx)
+	var user User
+	if err := s.db.Preload("Groups").First(&user, userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	explanation := &AccessExplanation{UserID: user.ID, Email: user.Email, Access: accessName, Reasons: []AccessReason{}}
+
+	var access Access
+	if err := s.db.Where("name = ?", accessName).First(&access).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		explanation.Reasons = append(explanation.Reasons, AccessReason{
+			Kind:        AccessReasonAccessNotFound,
+			Description: fmt.Sprintf("право %q не существует", accessName),
+		})
+		return explanation, nil
+	}
+
+	var direct []AccessLevel
+	if err := s.db.Where("user_id = ? AND access_id = ?", user.ID, access.ID).Find(&direct).Error; err != nil {
+		return nil, err
+	}
+	for _, level := range direct {
+		explanation.Reasons = append(explanation.Reasons, AccessReason{
+			Kind:          AccessReasonDirect,
+			Description:   "право выдано пользователю напрямую" + conditionDescription(level.Condition),
+			AccessLevelID: level.ID,
+			GrantedAt:     &level.CreatedAt,
+			Condition:     level.Condition,
+		})
+	}
+
+	if len(user.Groups) > 0 {
+		groups := make(map[uint]Group, len(user.Groups))
+		groupIDs := make([]uint, 0, len(user.Groups))
+		for _, group := range user.Groups {
+			groups[group.ID] = group
+			groupIDs = append(groupIDs, group.ID)
+		}
+		var levels []AccessLevel
+		if err := s.db.Where("group_id IN ? AND access_id = ?", groupIDs, access.ID).Order("group_id").Find(&levels).Error; err != nil {
+			return nil, err
+		}
+		for _, level := range levels {
+			group := groups[*level.GroupID]
+			explanation.Reasons = append(explanation.Reasons, AccessReason{
+				Kind:          AccessReasonGroup,
+				Description:   fmt.Sprintf("право выдано группе %q", group.Name) + conditionDescription(level.Condition),
+				AccessLevelID: level.ID,
+				GroupID:       group.ID,
+				GroupName:     group.Name,
+				GrantedAt:     &level.CreatedAt,
+				Condition:     level.Condition,
+			})
+		}
+	}
+
+	for _, reason := range explanation.Reasons {
+		if reason.Condition == "" {
+			explanation.Allowed = true
+		}
+	}
+	switch {
+	case explanation.Allowed:
+	case len(explanation.Reasons) > 0:
+		explanation.Reasons = append(explanation.Reasons, AccessReason{
+			Kind:        AccessReasonNotGranted,
+			Description: "право выдано только с условиями, они проверяются CheckUserAccessWithContext",
+		})
+	default:
+		explanation.Reasons = append(explanation.Reasons, AccessReason{
+			Kind:        AccessReasonNotGranted,
+			Description: "право не выдано ни пользователю, ни его группам",
+		})
+	}
+	return explanation, nil
+}
+
+func conditionDescription(condition string) string {
+	if condition == "" {
+		return ""
+	}
+	return fmt.Sprintf(" при условии %s", condition)
+}
@@ -0,0 +1,55 @@
+package accessgo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainUserAccess(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	alice, err := service.CreateUser("alice@example.com", "password", "Alice", UserTypeUser)
+	require.NoError(t, err)
+	admins, err := service.CreateGroup("admins")
+	require.NoError(t, err)
+	support, err := service.CreateGroup("support")
+	require.NoError(t, err)
+	removed, err := service.CreateGroup("removed")
+	require.NoError(t, err)
+	require.NoError(t, service.SetUserGroups(alice.ID, admins.ID, support.ID, removed.ID))
+	require.NoError(t, service.AddUserAccessLevel(alice.ID, "user:delete"))
+	require.NoError(t, service.AddGroupAccessLevel(admins.ID, "user:delete"))
+	require.NoError(t, service.AddGroupAccessLevel(removed.ID, "user:delete"))
+	require.NoError(t, service.AddGroupAccessLevel(support.ID, "user:read"))
+	require.NoError(t, service.DeleteGroup(removed.ID))
+
+	explanation, err := service.ExplainUserAccess(alice.ID, "user:delete")
+	require.NoError(t, err)
+	assert.True(t, explanation.Allowed)
+	assert.Equal(t, "alice@example.com", explanation.Email)
+	require.Len(t, explanation.Reasons, 2)
+	assert.Equal(t, AccessReasonDirect, explanation.Reasons[0].Kind)
+	assert.NotNil(t, explanation.Reasons[0].GrantedAt)
+	assert.Equal(t, AccessReasonGroup, explanation.Reasons[1].Kind)
+	assert.Equal(t, admins.ID, explanation.Reasons[1].GroupID)
+	assert.Equal(t, `право выдано группе "admins"`, explanation.Reasons[1].Description)
+
+	// Решение совпадает с CheckUserAccess
+	for _, accessName := range []string{"user:read", "group:delete", "report:export"} {
+		explanation, err = service.ExplainUserAccess(alice.ID, accessName)
+		require.NoError(t, err)
+		allowed, err := service.CheckUserAccess(alice.ID, accessName)
+		require.NoError(t, err)
+		assert.Equal(t, allowed, explanation.Allowed, accessName)
+	}
+	assert.Equal(t, AccessReasonAccessNotFound, explanation.Reasons[0].Kind)
+
+	explanation, err = service.ExplainUserAccess(alice.ID, "group:delete")
+	require.NoError(t, err)
+	require.Len(t, explanation.Reasons, 1)
+	assert.Equal(t, AccessReasonNotGranted, explanation.Reasons[0].Kind)
+
+	_, err = service.ExplainUserAccess(999, "user:read")
+	assert.EqualError(t, err, "пользователь не найден")
+}
@@ -0,0 +1,482 @@
+package accessgo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ExportVersion - версия формата ExportDocument
+const ExportVersion = 1
+
+// ExportDocument - выгрузка модели прав и пользователей. Связи задаются email
+// пользователей и именами групп и прав, а не ID, поэтому документ переносится
+// между базами. API ключи, сессии, ключи доступа и журнал аудита не выгружаются
+type ExportDocument struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Accesses   []ExportAccess `json:"accesses"`
+	Groups     []ExportGroup  `json:"groups"`
+	Users      []ExportUser   `json:"users"`
+}
+
+// ExportAccess - право доступа в выгрузке
+type ExportAccess struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ExportGrant - выдача права, возможно с условием
+type ExportGrant struct {
+	Access    string `json:"access"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// ExportGroup - группа и ее права
+type ExportGroup struct {
+	Name   string        `json:"name"`
+	Grants []ExportGrant `json:"grants,omitempty"`
+}
+
+// ExportUser - пользователь, его группы и прямые права. PasswordHash заполняется
+// только при ExportOptions.IncludePasswordHashes
+type ExportUser struct {
+	Email         string        `json:"email"`
+	Name          string        `json:"name"`
+	UserType      UserType      `json:"type"`
+	Source        string        `json:"source"`
+	EmailValidate bool          `json:"email_validate"`
+	PasswordHash  string        `json:"password_hash,omitempty"`
+	Groups        []string      `json:"groups,omitempty"`
+	Grants        []ExportGrant `json:"grants,omitempty"`
+}
+
+// ExportOptions содержит настройки выгрузки
+type ExportOptions struct {
+	// IncludePasswordHashes добавляет хеши паролей, чтобы пользователи могли входить
+	// после загрузки. Без них загруженные пользователи не могут войти по паролю
+	IncludePasswordHashes bool
+}
+
+// ImportConflictStrategy определяет, что делать с записью, которая уже есть в базе
+// и отличается от загружаемой. Совпадающие записи не считаются конфликтом
+type ImportConflictStrategy string
+
+const (
+	ImportConflictFail      ImportConflictStrategy = "fail"      // прервать загрузку и откатить изменения
+	ImportConflictSkip      ImportConflictStrategy = "skip"      // оставить запись в базе без изменений
+	ImportConflictOverwrite ImportConflictStrategy = "overwrite" // заменить запись в базе загружаемой
+)
+
+// ImportOptions содержит настройки загрузки
+type ImportOptions struct {
+	OnConflict ImportConflictStrategy // по умолчанию ImportConflictFail
+}
+
+// ImportCounts - число записей одного вида по результату загрузки
+type ImportCounts struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+}
+
+// ImportResult - результат загрузки
+type ImportResult struct {
+	Accesses ImportCounts `json:"accesses"`
+	Groups   ImportCounts `json:"groups"`
+	Users    ImportCounts `json:"users"`
+}
+
+// ExportAll выгружает права доступа, группы с их правами и пользователей с членством
+// в группах и прямыми правами
+func (s *AccessGoService) ExportAll(opts ExportOptions) (*ExportDocument, error) {
+	return s.ExportAllCtx(s.ctx, opts)
+}
+
+// ExportAllCtx - ExportAll с контекстом ctx
+func (s *AccessGoService) ExportAllCtx(ctx context.Context, opts ExportOptions) (*ExportDocument, error) {
+	s = s.WithContext(ctx)
+	doc := &ExportDocument{
+		Version:    ExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Accesses:   []ExportAccess{},
+		Groups:     []ExportGroup{},
+		Users:      []ExportUser{},
+	}
+
+	var accesses []Access
+	if err := s.db.Order("name").Find(&accesses).Error; err != nil {
+		return nil, err
+	}
+	for _, access := range accesses {
+		doc.Accesses = append(doc.Accesses, ExportAccess{Name: access.Name, Description: access.Description})
+	}
+
+	var groups []Group
+	if err := s.db.Preload("Accesses.Access").Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		doc.Groups = append(doc.Groups, ExportGroup{Name: group.Name, Grants: exportGrants(group.Accesses)})
+	}
+
+	var users []User
+	if err := s.db.Preload("Groups").Preload("Accesses.Access").Order("email").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		exported := ExportUser{
+			Email:         user.Email,
+			Name:          user.Name,
+			UserType:      UserType(user.UserType),
+			Source:        user.Source,
+			EmailValidate: user.EmailValidate,
+			Grants:        exportGrants(user.Accesses),
+		}
+		if opts.IncludePasswordHashes {
+			exported.PasswordHash = user.Password
+		}
+		for _, group := range user.Groups {
+			exported.Groups = append(exported.Groups, group.Name)
+		}
+		slices.Sort(exported.Groups)
+		doc.Users = append(doc.Users, exported)
+	}
+	return doc, nil
+}
+
+// exportGrants возвращает выдачи, пропуская выдачи удаленных прав
+func exportGrants(levels []AccessLevel) []ExportGrant {
+	var grants []ExportGrant
+	for _, level := range levels {
+		if level.Access.ID != 0 {
+			grants = append(grants, ExportGrant{Access: level.Access.Name, Condition: level.Condition})
+		}
+	}
+	slices.SortFunc(grants, func(a, b ExportGrant) int {
+		return strings.Compare(a.Access, b.Access)
+	})
+	return grants
+}
+
+// ImportAll загружает выгрузку ExportAll в одной транзакции. Записи сопоставляются
+// по email пользователя, имени группы и имени права. Права групп и пользователей
+// и членство в группах загружаемых записей заменяются выгруженными
+func (s *AccessGoService) ImportAll(doc *ExportDocument, opts ImportOptions) (*ImportResult, error) {
+	return s.ImportAllCtx(s.ctx, doc, opts)
+}
+
+// ImportAllCtx - ImportAll с контекстом ctx
+func (s *AccessGoService) ImportAllCtx(ctx context.Context, doc *ExportDocument, opts ImportOptions) (*ImportResult, error) {
+	s = s.WithContext(ctx)
+	if doc.Version != ExportVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия выгрузки %d", doc.Version)
+	}
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = ImportConflictFail
+	case ImportConflictFail, ImportConflictSkip, ImportConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("неизвестная стратегия конфликтов %q", opts.OnConflict)
+	}
+	for _, user := range doc.Users {
+		if user.PasswordHash != "" {
+			if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+				return nil, fmt.Errorf("неверный хеш пароля пользователя %q", user.Email)
+			}
+		}
+	}
+
+	result := &ImportResult{}
+	err := s.transaction(func(tx *AccessGoService) error {
+		for _, access := range doc.Accesses {
+			if err := tx.importAccess(access, opts.OnConflict, &result.Accesses); err != nil {
+				return err
+			}
+		}
+		for _, group := range doc.Groups {
+			if err := tx.importGroup(group, opts.OnConflict, &result.Groups); err != nil {
+				return err
+			}
+		}
+		for _, user := range doc.Users {
+			if err := tx.importUser(user, opts.OnConflict, &result.Users); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolveConflict возвращает true, если существующую запись нужно перезаписать
+func resolveConflict(strategy ImportConflictStrategy, counts *ImportCounts, kind, key string) (bool, error) {
+	switch strategy {
+	case ImportConflictOverwrite:
+		counts.Updated++
+		return true, nil
+	case ImportConflictSkip:
+		counts.Skipped++
+		return false, nil
+	}
+	return false, fmt.Errorf("%s %q уже существует и отличается от загружаемого", kind, key)
+}
+
+func (s *AccessGoService) importAccess(imported ExportAccess, strategy ImportConflictStrategy, counts *ImportCounts) error {
+	// Удаленное право занимает имя, поэтому оно восстанавливается
+	var access Access
+	err := s.db.Unscoped().Where("name = ?", imported.Name).Limit(1).Find(&access).Error
+	switch {
+	case err != nil:
+		return err
+	case access.ID == 0:
+		counts.Created++
+		_, err = s.CreateAccess(imported.Name, imported.Description)
+		return err
+	case access.DeletedAt.Valid:
+		counts.Created++
+		return s.restoreAccess(&access, imported.Description)
+	case access.Description == imported.Description:
+		counts.Unchanged++
+		return nil
+	}
+	if overwrite, err := resolveConflict(strategy, counts, "право", imported.Name); !overwrite {
+		return err
+	}
+	_, err = s.UpdateAccess(access.ID, access.Name, imported.Description)
+	return err
+}
+
+func (s *AccessGoService) importGroup(imported ExportGroup, strategy ImportConflictStrategy, counts *ImportCounts) error {
+	var group Group
+	if err := s.db.Preload("Accesses.Access").Where("name = ?", imported.Name).Limit(1).Find(&group).Error; err != nil {
+		return err
+	}
+	current := grantConditions(exportGrants(group.Accesses))
+	wanted := grantConditions(imported.Grants)
+	if group.ID == 0 {
+		// Удаленная группа занимает имя, поэтому она восстанавливается без прежних прав
+		if err := s.db.Unscoped().Where("name = ?", imported.Name).Limit(1).Find(&group).Error; err != nil {
+			return err
+		}
+		if group.ID != 0 {
+			if err := s.restoreGroup(&group); err != nil {
+				return err
+			}
+		} else {
+			created, err := s.CreateGroup(imported.Name)
+			if err != nil {
+				return err
+			}
+			group = *created
+		}
+		counts.Created++
+	} else if maps.Equal(current, wanted) {
+		counts.Unchanged++
+		return nil
+	} else if overwrite, err := resolveConflict(strategy, counts, "группа", imported.Name); !overwrite {
+		return err
+	}
+
+	err := syncGrants(current, wanted, func(access string) error {
+		return s.RemoveGroupAccessLevel(group.ID, access)
+	}, func(access, condition string) error {
+		return s.AddGroupAccessLevelWithCondition(group.ID, access, condition)
+	})
+	if err != nil {
+		return fmt.Errorf("группа %q: %w", imported.Name, err)
+	}
+	return nil
+}
+
+func (s *AccessGoService) importUser(imported ExportUser, strategy ImportConflictStrategy, counts *ImportCounts) error {
+	var user User
+	if err := s.db.Preload("Groups").Preload("Accesses.Access").Where("email = ?", imported.Email).Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+	var groupNames []string
+	for _, group := range user.Groups {
+		groupNames = append(groupNames, group.Name)
+	}
+	current := grantConditions(exportGrants(user.Accesses))
+	wanted := grantConditions(imported.Grants)
+
+	if user.ID == 0 {
+		counts.Created++
+		// Удаленный пользователь занимает email, поэтому он восстанавливается
+		var deleted User
+		if err := s.db.Unscoped().Where("email = ?", imported.Email).Limit(1).Find(&deleted).Error; err != nil {
+			return err
+		}
+		if deleted.ID == 0 {
+			if err := s.createImportedUser(&user, imported); err != nil {
+				return err
+			}
+		} else {
+			restored, err := newImportedUser(imported)
+			if err != nil {
+				return err
+			}
+			restored.Model = deleted.Model
+			if err := s.restoreUser(&restored); err != nil {
+				return err
+			}
+			user = restored
+		}
+	} else {
+		same := user.Name == imported.Name && user.UserType == string(imported.UserType) &&
+			user.Source == imported.Source && user.EmailValidate == imported.EmailValidate &&
+			(imported.PasswordHash == "" || user.Password == imported.PasswordHash) &&
+			sameNames(groupNames, imported.Groups) && maps.Equal(current, wanted)
+		if same {
+			counts.Unchanged++
+			return nil
+		}
+		if overwrite, err := resolveConflict(strategy, counts, "пользователь", imported.Email); !overwrite {
+			return err
+		}
+		if err := s.updateImportedUser(&user, imported); err != nil {
+			return err
+		}
+	}
+
+	groupIDs := make([]uint, 0, len(imported.Groups))
+	for _, name := range imported.Groups {
+		var group Group
+		if err := s.db.Where("name = ?", name).First(&group).Error; err != nil {
+			return fmt.Errorf("пользователь %q: группа %q не найдена", imported.Email, name)
+		}
+		groupIDs = append(groupIDs, group.ID)
+	}
+	if !sameNames(groupNames, imported.Groups) {
+		if err := s.SetUserGroups(user.ID, groupIDs...); err != nil {
+			return fmt.Errorf("пользователь %q: %w", imported.Email, err)
+		}
+	}
+	err := syncGrants(current, wanted, func(access string) error {
+		return s.RemoveUserAccessLevel(user.ID, access)
+	}, func(access, condition string) error {
+		return s.AddUserAccessLevelWithCondition(user.ID, access, condition)
+	})
+	if err != nil {
+		return fmt.Errorf("пользователь %q: %w", imported.Email, err)
+	}
+	return nil
+}
+
+func (s *AccessGoService) createImportedUser(user *User, imported ExportUser) error {
+	created, err := newImportedUser(imported)
+	if err != nil {
+		return err
+	}
+	*user = created
+
+	event := UserCreated{User: newEventUser(user)}
+	if err := s.beforeEvent(event); err != nil {
+		return err
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return err
+	}
+	if err := s.afterEvent(UserCreated{User: newEventUser(user)}); err != nil {
+		return err
+	}
+	return s.audit(AuditUserCreate, AuditTargetUser, user.ID, nil, userSnapshot(user))
+}
+
+// newImportedUser возвращает нового пользователя с данными imported
+func newImportedUser(imported ExportUser) (User, error) {
+	password := imported.PasswordHash
+	if password == "" {
+		// Без хеша пользователь не может войти по паролю, пока пароль не задан заново
+		hashed, err := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+		if err != nil {
+			return User{}, err
+		}
+		password = string(hashed)
+	}
+	user := User{
+		Email:                imported.Email,
+		EmailValidate:        imported.EmailValidate,
+		EmailValidationToken: uuid.NewString(),
+		Password:             password,
+		Name:                 imported.Name,
+		UserType:             string(imported.UserType),
+		Source:               imported.Source,
+	}
+	if user.Source == "" {
+		user.Source = string(UserSourceLocal)
+	}
+	if user.EmailValidate {
+		user.EmailValidationToken = ""
+	}
+	return user, nil
+}
+
+func (s *AccessGoService) updateImportedUser(user *User, imported ExportUser) error {
+	previous := newEventUser(user)
+	before := userSnapshot(user)
+	user.Name = imported.Name
+	user.UserType = string(imported.UserType)
+	user.Source = imported.Source
+	user.EmailValidate = imported.EmailValidate
+	if imported.PasswordHash != "" {
+		user.Password = imported.PasswordHash
+	}
+
+	event := UserUpdated{Before: previous, After: newEventUser(user)}
+	if err := s.beforeEvent(event); err != nil {
+		return err
+	}
+	if err := s.db.Omit("Groups", "Accesses").Save(user).Error; err != nil {
+		return err
+	}
+	if err := s.afterEvent(event); err != nil {
+		return err
+	}
+	return s.audit(AuditUserUpdate, AuditTargetUser, user.ID, before, userSnapshot(user))
+}
+
+func grantConditions(grants []ExportGrant) map[string]string {
+	result := make(map[string]string, len(grants))
+	for _, grant := range grants {
+		result[grant.Access] = grant.Condition
+	}
+	return result
+}
+
+// syncGrants приводит выдачи current к wanted: отзывает лишние и измененные, выдает недостающие
+func syncGrants(current, wanted map[string]string, remove func(access string) error, add func(access, condition string) error) error {
+	for _, access := range sortedKeys(current) {
+		if condition, ok := wanted[access]; !ok || condition != current[access] {
+			if err := remove(access); err != nil {
+				return err
+			}
+		}
+	}
+	for _, access := range sortedKeys(wanted) {
+		if condition, ok := current[access]; !ok || condition != wanted[access] {
+			if err := add(access, wanted[access]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sameNames(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
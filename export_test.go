@@ -0,0 +1,177 @@
+package accessgo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func setupExportSource(t *testing.T) *AccessGoService {
+	service := newTestService(t, setupTestDB(t))
+	_, err := service.CreateAccess("report:read", "Чтение отчетов")
+	require.NoError(t, err)
+	group, err := service.CreateGroup("analysts")
+	require.NoError(t, err)
+	require.NoError(t, service.AddGroupAccessLevel(group.ID, "report:read"))
+	require.NoError(t, service.AddGroupAccessLevelWithCondition(group.ID, "user:read", `cidr(request.ip, "10.0.0.0/8")`))
+	user, err := service.CreateUser("ann@example.com", "password", "Ann", UserTypeEmployee)
+	require.NoError(t, err)
+	require.NoError(t, service.AssignUserToGroup(user.ID, group.ID))
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "group:read"))
+	return service
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := setupExportSource(t)
+	doc, err := source.ExportAll(ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ExportVersion, doc.Version)
+	require.Len(t, doc.Users, 1)
+	assert.Empty(t, doc.Users[0].PasswordHash)
+	assert.Equal(t, []string{"analysts"}, doc.Users[0].Groups)
+	assert.Equal(t, []ExportGrant{{Access: "group:read"}}, doc.Users[0].Grants)
+	assert.Equal(t, ExportGroup{Name: "analysts", Grants: []ExportGrant{
+		{Access: "report:read"},
+		{Access: "user:read", Condition: `cidr(request.ip, "10.0.0.0/8")`},
+	}}, doc.Groups[0])
+
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var loaded ExportDocument
+	require.NoError(t, json.Unmarshal(data, &loaded))
+
+	// Стандартные права есть в обеих базах и не считаются конфликтом
+	target := newTestService(t, setupTestDB(t))
+	result, err := target.ImportAll(&loaded, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{
+		Accesses: ImportCounts{Created: 1, Unchanged: 15},
+		Groups:   ImportCounts{Created: 1},
+		Users:    ImportCounts{Created: 1},
+	}, *result)
+
+	exported, err := target.ExportAll(ExportOptions{})
+	require.NoError(t, err)
+	exported.ExportedAt = doc.ExportedAt
+	assert.Equal(t, doc, exported)
+
+	user, err := target.GetUserByEmail("ann@example.com")
+	require.NoError(t, err)
+	allowed, err := target.CheckUserAccess(user.ID, "report:read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Error(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("password")), "без хешей пароль не переносится")
+
+	result, err = target.ImportAll(&loaded, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ImportCounts{Unchanged: 1}, result.Users)
+
+	withHashes, err := source.ExportAll(ExportOptions{IncludePasswordHashes: true})
+	require.NoError(t, err)
+	_, err = target.ImportAll(withHashes, ImportOptions{OnConflict: ImportConflictOverwrite})
+	require.NoError(t, err)
+	user, err = target.GetUserByEmail("ann@example.com")
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("password")))
+}
+
+func TestImportConflicts(t *testing.T) {
+	doc, err := setupExportSource(t).ExportAll(ExportOptions{})
+	require.NoError(t, err)
+
+	target := newTestService(t, setupTestDB(t))
+	_, err = target.CreateAccess("report:read", "Отчеты")
+	require.NoError(t, err)
+	group, err := target.CreateGroup("analysts")
+	require.NoError(t, err)
+	require.NoError(t, target.AddGroupAccessLevel(group.ID, "user:delete"))
+	_, err = target.CreateUser("ann@example.com", "password", "Anna", UserTypeUser)
+	require.NoError(t, err)
+
+	_, err = target.ImportAll(doc, ImportOptions{})
+	assert.EqualError(t, err, `право "report:read" уже существует и отличается от загружаемого`)
+	_, err = target.ImportAll(doc, ImportOptions{OnConflict: "merge"})
+	assert.EqualError(t, err, `неизвестная стратегия конфликтов "merge"`)
+	doc.Version = 2
+	_, err = target.ImportAll(doc, ImportOptions{})
+	assert.EqualError(t, err, "неподдерживаемая версия выгрузки 2")
+	doc.Version = ExportVersion
+
+	result, err := target.ImportAll(doc, ImportOptions{OnConflict: ImportConflictSkip})
+	require.NoError(t, err)
+	assert.Equal(t, ImportCounts{Unchanged: 15, Skipped: 1}, result.Accesses)
+	assert.Equal(t, ImportCounts{Skipped: 1}, result.Groups)
+	assert.Equal(t, ImportCounts{Skipped: 1}, result.Users)
+	user, err := target.GetUserByEmail("ann@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Anna", user.Name)
+
+	result, err = target.ImportAll(doc, ImportOptions{OnConflict: ImportConflictOverwrite})
+	require.NoError(t, err)
+	assert.Equal(t, ImportCounts{Updated: 1}, result.Users)
+	user, err = target.GetUserByEmail("ann@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", user.Name)
+	assert.Equal(t, string(UserTypeEmployee), user.UserType)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("password")), "без хеша в выгрузке пароль сохраняется")
+	permissions, err := target.GetUserSummaryAccessLevels(user.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"group:read", "report:read"}, permissions)
+	access, err := target.GetAccessByName("report:read")
+	require.NoError(t, err)
+	assert.Equal(t, "Чтение отчетов", access.Description)
+}
+
+func TestImportRestoresDeleted(t *testing.T) {
+	doc, err := setupExportSource(t).ExportAll(ExportOptions{})
+	require.NoError(t, err)
+
+	target := newTestService(t, setupTestDB(t))
+	access, err := target.CreateAccess("report:read", "Отчеты")
+	require.NoError(t, err)
+	group, err := target.CreateGroup("analysts")
+	require.NoError(t, err)
+	require.NoError(t, target.AddGroupAccessLevel(group.ID, "user:delete"))
+	user, err := target.CreateUser("ann@example.com", "password", "Anna", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, target.AddUserAccessLevel(user.ID, "user:update"))
+	token, _, err := target.CreateAPIKey(user.ID, "ci", nil)
+	require.NoError(t, err)
+	require.NoError(t, target.DeleteAccess(access.ID))
+	require.NoError(t, target.DeleteGroup(group.ID))
+	require.NoError(t, target.DeleteUser(user.ID))
+
+	// Удаленные записи занимают имена и email, поэтому восстанавливаются с прежними ID
+	result, err := target.ImportAll(doc, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{
+		Accesses: ImportCounts{Created: 1, Unchanged: 15},
+		Groups:   ImportCounts{Created: 1},
+		Users:    ImportCounts{Created: 1},
+	}, *result)
+
+	restored, err := target.GetUserByEmail("ann@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, restored.ID)
+	assert.Equal(t, "Ann", restored.Name)
+	permissions, err := target.GetUserSummaryAccessLevels(user.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"group:read", "report:read"}, permissions)
+	_, _, err = target.AuthenticateAPIKey(token)
+	assert.Error(t, err, "ключи удаленного пользователя не возвращаются")
+	grants, err := target.GetGroupAccessLevels(group.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"report:read", "user:read"}, grants)
+	restoredAccess, err := target.GetAccessByName("report:read")
+	require.NoError(t, err)
+	assert.Equal(t, access.ID, restoredAccess.ID)
+	assert.Equal(t, "Чтение отчетов", restoredAccess.Description)
+
+	exported, err := target.ExportAll(ExportOptions{})
+	require.NoError(t, err)
+	exported.ExportedAt = doc.ExportedAt
+	assert.Equal(t, doc, exported)
+}
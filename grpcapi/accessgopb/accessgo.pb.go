@@ -0,0 +1,1512 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: accessgo/v1/accessgo.proto
+
+package accessgopb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type User struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Email          string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Name           string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	UserType       string                 `protobuf:"bytes,4,opt,name=user_type,json=userType,proto3" json:"user_type,omitempty"`
+	Source         string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`
+	EmailValidated bool                   `protobuf:"varint,6,opt,name=email_validated,json=emailValidated,proto3" json:"email_validated,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *User) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *User) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *User) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *User) GetUserType() string {
+	if x != nil {
+		return x.UserType
+	}
+	return ""
+}
+
+func (x *User) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *User) GetEmailValidated() bool {
+	if x != nil {
+		return x.EmailValidated
+	}
+	return false
+}
+
+func (x *User) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *User) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type Group struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Group) Reset() {
+	*x = Group{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Group) ProtoMessage() {}
+
+func (x *Group) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Group.ProtoReflect.Descriptor instead.
+func (*Group) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Group) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Group) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Access struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Access) Reset() {
+	*x = Access{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Access) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Access) ProtoMessage() {}
+
+func (x *Access) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Access.ProtoReflect.Descriptor instead.
+func (*Access) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Access) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Access) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Access) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type LoginRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	LongTerm      bool                   `protobuf:"varint,3,opt,name=long_term,json=longTerm,proto3" json:"long_term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoginRequest) Reset() {
+	*x = LoginRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoginRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoginRequest) ProtoMessage() {}
+
+func (x *LoginRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoginRequest.ProtoReflect.Descriptor instead.
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LoginRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *LoginRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *LoginRequest) GetLongTerm() bool {
+	if x != nil {
+		return x.LongTerm
+	}
+	return false
+}
+
+type LoginResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	User          *User                  `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoginResponse) Reset() {
+	*x = LoginResponse{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoginResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoginResponse) ProtoMessage() {}
+
+func (x *LoginResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoginResponse.ProtoReflect.Descriptor instead.
+func (*LoginResponse) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LoginResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *LoginResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *LoginResponse) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+type CreateUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	UserType      string                 `protobuf:"bytes,4,opt,name=user_type,json=userType,proto3" json:"user_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateUserRequest) Reset() {
+	*x = CreateUserRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateUserRequest) ProtoMessage() {}
+
+func (x *CreateUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateUserRequest.ProtoReflect.Descriptor instead.
+func (*CreateUserRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateUserRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateUserRequest) GetUserType() string {
+	if x != nil {
+		return x.UserType
+	}
+	return ""
+}
+
+type GetUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserRequest) Reset() {
+	*x = GetUserRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserRequest) ProtoMessage() {}
+
+func (x *GetUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserRequest.ProtoReflect.Descriptor instead.
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetUserRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// UpdateUserRequest заменяет email, имя и тип пользователя; пустой пароль не меняется
+type UpdateUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Password      string                 `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	UserType      string                 `protobuf:"bytes,5,opt,name=user_type,json=userType,proto3" json:"user_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateUserRequest) Reset() {
+	*x = UpdateUserRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateUserRequest) ProtoMessage() {}
+
+func (x *UpdateUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateUserRequest.ProtoReflect.Descriptor instead.
+func (*UpdateUserRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateUserRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateUserRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *UpdateUserRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *UpdateUserRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UpdateUserRequest) GetUserType() string {
+	if x != nil {
+		return x.UserType
+	}
+	return ""
+}
+
+type DeleteUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserRequest) Reset() {
+	*x = DeleteUserRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserRequest) ProtoMessage() {}
+
+func (x *DeleteUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteUserRequest.ProtoReflect.Descriptor instead.
+func (*DeleteUserRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteUserRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type CreateGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateGroupRequest) Reset() {
+	*x = CreateGroupRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateGroupRequest) ProtoMessage() {}
+
+func (x *CreateGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGroupRequest.ProtoReflect.Descriptor instead.
+func (*CreateGroupRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateGroupRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type UpdateGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateGroupRequest) Reset() {
+	*x = UpdateGroupRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateGroupRequest) ProtoMessage() {}
+
+func (x *UpdateGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateGroupRequest.ProtoReflect.Descriptor instead.
+func (*UpdateGroupRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpdateGroupRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateGroupRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type DeleteGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteGroupRequest) Reset() {
+	*x = DeleteGroupRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteGroupRequest) ProtoMessage() {}
+
+func (x *DeleteGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteGroupRequest.ProtoReflect.Descriptor instead.
+func (*DeleteGroupRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteGroupRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Groups        []*Group               `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsResponse) Reset() {
+	*x = ListGroupsResponse{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsResponse) ProtoMessage() {}
+
+func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListGroupsResponse) GetGroups() []*Group {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+type MembershipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint64                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       uint64                 `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembershipRequest) Reset() {
+	*x = MembershipRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembershipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembershipRequest) ProtoMessage() {}
+
+func (x *MembershipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembershipRequest.ProtoReflect.Descriptor instead.
+func (*MembershipRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MembershipRequest) GetUserId() uint64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *MembershipRequest) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+type SetUserGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint64                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupIds      []uint64               `protobuf:"varint,2,rep,packed,name=group_ids,json=groupIds,proto3" json:"group_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetUserGroupsRequest) Reset() {
+	*x = SetUserGroupsRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetUserGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetUserGroupsRequest) ProtoMessage() {}
+
+func (x *SetUserGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetUserGroupsRequest.ProtoReflect.Descriptor instead.
+func (*SetUserGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SetUserGroupsRequest) GetUserId() uint64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *SetUserGroupsRequest) GetGroupIds() []uint64 {
+	if x != nil {
+		return x.GroupIds
+	}
+	return nil
+}
+
+type CreateAccessRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateAccessRequest) Reset() {
+	*x = CreateAccessRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateAccessRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateAccessRequest) ProtoMessage() {}
+
+func (x *CreateAccessRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateAccessRequest.ProtoReflect.Descriptor instead.
+func (*CreateAccessRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CreateAccessRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateAccessRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type DeleteAccessRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAccessRequest) Reset() {
+	*x = DeleteAccessRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAccessRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAccessRequest) ProtoMessage() {}
+
+func (x *DeleteAccessRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAccessRequest.ProtoReflect.Descriptor instead.
+func (*DeleteAccessRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeleteAccessRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListAccessesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accesses      []*Access              `protobuf:"bytes,1,rep,name=accesses,proto3" json:"accesses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAccessesResponse) Reset() {
+	*x = ListAccessesResponse{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAccessesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAccessesResponse) ProtoMessage() {}
+
+func (x *ListAccessesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAccessesResponse.ProtoReflect.Descriptor instead.
+func (*ListAccessesResponse) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListAccessesResponse) GetAccesses() []*Access {
+	if x != nil {
+		return x.Accesses
+	}
+	return nil
+}
+
+type UserAccessRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint64                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Access        string                 `protobuf:"bytes,2,opt,name=access,proto3" json:"access,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserAccessRequest) Reset() {
+	*x = UserAccessRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserAccessRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserAccessRequest) ProtoMessage() {}
+
+func (x *UserAccessRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserAccessRequest.ProtoReflect.Descriptor instead.
+func (*UserAccessRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *UserAccessRequest) GetUserId() uint64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *UserAccessRequest) GetAccess() string {
+	if x != nil {
+		return x.Access
+	}
+	return ""
+}
+
+type GroupAccessRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       uint64                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Access        string                 `protobuf:"bytes,2,opt,name=access,proto3" json:"access,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupAccessRequest) Reset() {
+	*x = GroupAccessRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupAccessRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupAccessRequest) ProtoMessage() {}
+
+func (x *GroupAccessRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupAccessRequest.ProtoReflect.Descriptor instead.
+func (*GroupAccessRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GroupAccessRequest) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *GroupAccessRequest) GetAccess() string {
+	if x != nil {
+		return x.Access
+	}
+	return ""
+}
+
+type UserPermissions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Direct        []string               `protobuf:"bytes,1,rep,name=direct,proto3" json:"direct,omitempty"`
+	Effective     []string               `protobuf:"bytes,2,rep,name=effective,proto3" json:"effective,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserPermissions) Reset() {
+	*x = UserPermissions{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserPermissions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserPermissions) ProtoMessage() {}
+
+func (x *UserPermissions) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserPermissions.ProtoReflect.Descriptor instead.
+func (*UserPermissions) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UserPermissions) GetDirect() []string {
+	if x != nil {
+		return x.Direct
+	}
+	return nil
+}
+
+func (x *UserPermissions) GetEffective() []string {
+	if x != nil {
+		return x.Effective
+	}
+	return nil
+}
+
+type CheckUserAccessRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint64                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Access        string                 `protobuf:"bytes,2,opt,name=access,proto3" json:"access,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckUserAccessRequest) Reset() {
+	*x = CheckUserAccessRequest{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckUserAccessRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckUserAccessRequest) ProtoMessage() {}
+
+func (x *CheckUserAccessRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckUserAccessRequest.ProtoReflect.Descriptor instead.
+func (*CheckUserAccessRequest) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CheckUserAccessRequest) GetUserId() uint64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CheckUserAccessRequest) GetAccess() string {
+	if x != nil {
+		return x.Access
+	}
+	return ""
+}
+
+type CheckUserAccessResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckUserAccessResponse) Reset() {
+	*x = CheckUserAccessResponse{}
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckUserAccessResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckUserAccessResponse) ProtoMessage() {}
+
+func (x *CheckUserAccessResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_accessgo_v1_accessgo_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckUserAccessResponse.ProtoReflect.Descriptor instead.
+func (*CheckUserAccessResponse) Descriptor() ([]byte, []int) {
+	return file_accessgo_v1_accessgo_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CheckUserAccessResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+var File_accessgo_v1_accessgo_proto protoreflect.FileDescriptor
+
+const file_accessgo_v1_accessgo_proto_rawDesc = "" +
+	"\n" +
+	"\x1aaccessgo/v1/accessgo.proto\x12\vaccessgo.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x94\x02\n" +
+	"\x04User\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1b\n" +
+	"\tuser_type\x18\x04 \x01(\tR\buserType\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12'\n" +
+	"\x0femail_validated\x18\x06 \x01(\bR\x0eemailValidated\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"+\n" +
+	"\x05Group\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"N\n" +
+	"\x06Access\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\"]\n" +
+	"\fLoginRequest\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x1b\n" +
+	"\tlong_term\x18\x03 \x01(\bR\blongTerm\"\x90\x01\n" +
+	"\rLoginResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x129\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12%\n" +
+	"\x04user\x18\x03 \x01(\v2\x11.accessgo.v1.UserR\x04user\"v\n" +
+	"\x11CreateUserRequest\x12\x14\n" +
+	"\x05email\x18\x01 \x01(\tR\x05email\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1b\n" +
+	"\tuser_type\x18\x04 \x01(\tR\buserType\" \n" +
+	"\x0eGetUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"\x86\x01\n" +
+	"\x11UpdateUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x1a\n" +
+	"\bpassword\x18\x03 \x01(\tR\bpassword\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x1b\n" +
+	"\tuser_type\x18\x05 \x01(\tR\buserType\"#\n" +
+	"\x11DeleteUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"(\n" +
+	"\x12CreateGroupRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"8\n" +
+	"\x12UpdateGroupRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"$\n" +
+	"\x12DeleteGroupRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"@\n" +
+	"\x12ListGroupsResponse\x12*\n" +
+	"\x06groups\x18\x01 \x03(\v2\x12.accessgo.v1.GroupR\x06groups\"G\n" +
+	"\x11MembershipRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x04R\x06userId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\x04R\agroupId\"L\n" +
+	"\x14SetUserGroupsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x04R\x06userId\x12\x1b\n" +
+	"\tgroup_ids\x18\x02 \x03(\x04R\bgroupIds\"K\n" +
+	"\x13CreateAccessRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\"%\n" +
+	"\x13DeleteAccessRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"G\n" +
+	"\x14ListAccessesResponse\x12/\n" +
+	"\baccesses\x18\x01 \x03(\v2\x13.accessgo.v1.AccessR\baccesses\"D\n" +
+	"\x11UserAccessRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x04R\x06userId\x12\x16\n" +
+	"\x06access\x18\x02 \x01(\tR\x06access\"G\n" +
+	"\x12GroupAccessRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\x04R\agroupId\x12\x16\n" +
+	"\x06access\x18\x02 \x01(\tR\x06access\"G\n" +
+	"\x0fUserPermissions\x12\x16\n" +
+	"\x06direct\x18\x01 \x03(\tR\x06direct\x12\x1c\n" +
+	"\teffective\x18\x02 \x03(\tR\teffective\"I\n" +
+	"\x16CheckUserAccessRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x04R\x06userId\x12\x16\n" +
+	"\x06access\x18\x02 \x01(\tR\x06access\"3\n" +
+	"\x17CheckUserAccessResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed2\x8a\x0e\n" +
+	"\bAccessGo\x12>\n" +
+	"\x05Login\x12\x19.accessgo.v1.LoginRequest\x1a\x1a.accessgo.v1.LoginResponse\x128\n" +
+	"\x06Logout\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\x12;\n" +
+	"\x0eGetCurrentUser\x12\x16.google.protobuf.Empty\x1a\x11.accessgo.v1.User\x12?\n" +
+	"\n" +
+	"CreateUser\x12\x1e.accessgo.v1.CreateUserRequest\x1a\x11.accessgo.v1.User\x129\n" +
+	"\aGetUser\x12\x1b.accessgo.v1.GetUserRequest\x1a\x11.accessgo.v1.User\x12?\n" +
+	"\n" +
+	"UpdateUser\x12\x1e.accessgo.v1.UpdateUserRequest\x1a\x11.accessgo.v1.User\x12D\n" +
+	"\n" +
+	"DeleteUser\x12\x1e.accessgo.v1.DeleteUserRequest\x1a\x16.google.protobuf.Empty\x128\n" +
+	"\tListUsers\x12\x16.google.protobuf.Empty\x1a\x11.accessgo.v1.User0\x01\x12B\n" +
+	"\vCreateGroup\x12\x1f.accessgo.v1.CreateGroupRequest\x1a\x12.accessgo.v1.Group\x12B\n" +
+	"\vUpdateGroup\x12\x1f.accessgo.v1.UpdateGroupRequest\x1a\x12.accessgo.v1.Group\x12F\n" +
+	"\vDeleteGroup\x12\x1f.accessgo.v1.DeleteGroupRequest\x1a\x16.google.protobuf.Empty\x12E\n" +
+	"\n" +
+	"ListGroups\x12\x16.google.protobuf.Empty\x1a\x1f.accessgo.v1.ListGroupsResponse\x12K\n" +
+	"\x11AssignUserToGroup\x12\x1e.accessgo.v1.MembershipRequest\x1a\x16.google.protobuf.Empty\x12N\n" +
+	"\x14ExcludeUserFromGroup\x12\x1e.accessgo.v1.MembershipRequest\x1a\x16.google.protobuf.Empty\x12J\n" +
+	"\rSetUserGroups\x12!.accessgo.v1.SetUserGroupsRequest\x1a\x16.google.protobuf.Empty\x12M\n" +
+	"\rGetUserGroups\x12\x1b.accessgo.v1.GetUserRequest\x1a\x1f.accessgo.v1.ListGroupsResponse\x12E\n" +
+	"\fCreateAccess\x12 .accessgo.v1.CreateAccessRequest\x1a\x13.accessgo.v1.Access\x12H\n" +
+	"\fDeleteAccess\x12 .accessgo.v1.DeleteAccessRequest\x1a\x16.google.protobuf.Empty\x12I\n" +
+	"\fListAccesses\x12\x16.google.protobuf.Empty\x1a!.accessgo.v1.ListAccessesResponse\x12I\n" +
+	"\x0fGrantUserAccess\x12\x1e.accessgo.v1.UserAccessRequest\x1a\x16.google.protobuf.Empty\x12J\n" +
+	"\x10RevokeUserAccess\x12\x1e.accessgo.v1.UserAccessRequest\x1a\x16.google.protobuf.Empty\x12K\n" +
+	"\x10GrantGroupAccess\x12\x1f.accessgo.v1.GroupAccessRequest\x1a\x16.google.protobuf.Empty\x12L\n" +
+	"\x11RevokeGroupAccess\x12\x1f.accessgo.v1.GroupAccessRequest\x1a\x16.google.protobuf.Empty\x12O\n" +
+	"\x12GetUserPermissions\x12\x1b.accessgo.v1.GetUserRequest\x1a\x1c.accessgo.v1.UserPermissions\x12\\\n" +
+	"\x0fCheckUserAccess\x12#.accessgo.v1.CheckUserAccessRequest\x1a$.accessgo.v1.CheckUserAccessResponseB:Z8github.com/axgrid/accessgo/grpcapi/accessgopb;accessgopbb\x06proto3"
+
+var (
+	file_accessgo_v1_accessgo_proto_rawDescOnce sync.Once
+	file_accessgo_v1_accessgo_proto_rawDescData []byte
+)
+
+func file_accessgo_v1_accessgo_proto_rawDescGZIP() []byte {
+	file_accessgo_v1_accessgo_proto_rawDescOnce.Do(func() {
+		file_accessgo_v1_accessgo_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_accessgo_v1_accessgo_proto_rawDesc), len(file_accessgo_v1_accessgo_proto_rawDesc)))
+	})
+	return file_accessgo_v1_accessgo_proto_rawDescData
+}
+
+var file_accessgo_v1_accessgo_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_accessgo_v1_accessgo_proto_goTypes = []any{
+	(*User)(nil),                    // 0: accessgo.v1.User
+	(*Group)(nil),                   // 1: accessgo.v1.Group
+	(*Access)(nil),                  // 2: accessgo.v1.Access
+	(*LoginRequest)(nil),            // 3: accessgo.v1.LoginRequest
+	(*LoginResponse)(nil),           // 4: accessgo.v1.LoginResponse
+	(*CreateUserRequest)(nil),       // 5: accessgo.v1.CreateUserRequest
+	(*GetUserRequest)(nil),          // 6: accessgo.v1.GetUserRequest
+	(*UpdateUserRequest)(nil),       // 7: accessgo.v1.UpdateUserRequest
+	(*DeleteUserRequest)(nil),       // 8: accessgo.v1.DeleteUserRequest
+	(*CreateGroupRequest)(nil),      // 9: accessgo.v1.CreateGroupRequest
+	(*UpdateGroupRequest)(nil),      // 10: accessgo.v1.UpdateGroupRequest
+	(*DeleteGroupRequest)(nil),      // 11: accessgo.v1.DeleteGroupRequest
+	(*ListGroupsResponse)(nil),      // 12: accessgo.v1.ListGroupsResponse
+	(*MembershipRequest)(nil),       // 13: accessgo.v1.MembershipRequest
+	(*SetUserGroupsRequest)(nil),    // 14: accessgo.v1.SetUserGroupsRequest
+	(*CreateAccessRequest)(nil),     // 15: accessgo.v1.CreateAccessRequest
+	(*DeleteAccessRequest)(nil),     // 16: accessgo.v1.DeleteAccessRequest
+	(*ListAccessesResponse)(nil),    // 17: accessgo.v1.ListAccessesResponse
+	(*UserAccessRequest)(nil),       // 18: accessgo.v1.UserAccessRequest
+	(*GroupAccessRequest)(nil),      // 19: accessgo.v1.GroupAccessRequest
+	(*UserPermissions)(nil),         // 20: accessgo.v1.UserPermissions
+	(*CheckUserAccessRequest)(nil),  // 21: accessgo.v1.CheckUserAccessRequest
+	(*CheckUserAccessResponse)(nil), // 22: accessgo.v1.CheckUserAccessResponse
+	(*timestamppb.Timestamp)(nil),   // 23: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),           // 24: google.protobuf.Empty
+}
+var file_accessgo_v1_accessgo_proto_depIdxs = []int32{
+	23, // 0: accessgo.v1.User.created_at:type_name -> google.protobuf.Timestamp
+	23, // 1: accessgo.v1.User.updated_at:type_name -> google.protobuf.Timestamp
+	23, // 2: accessgo.v1.LoginResponse.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 3: accessgo.v1.LoginResponse.user:type_name -> accessgo.v1.User
+	1,  // 4: accessgo.v1.ListGroupsResponse.groups:type_name -> accessgo.v1.Group
+	2,  // 5: accessgo.v1.ListAccessesResponse.accesses:type_name -> accessgo.v1.Access
+	3,  // 6: accessgo.v1.AccessGo.Login:input_type -> accessgo.v1.LoginRequest
+	24, // 7: accessgo.v1.AccessGo.Logout:input_type -> google.protobuf.Empty
+	24, // 8: accessgo.v1.AccessGo.GetCurrentUser:input_type -> google.protobuf.Empty
+	5,  // 9: accessgo.v1.AccessGo.CreateUser:input_type -> accessgo.v1.CreateUserRequest
+	6,  // 10: accessgo.v1.AccessGo.GetUser:input_type -> accessgo.v1.GetUserRequest
+	7,  // 11: accessgo.v1.AccessGo.UpdateUser:input_type -> accessgo.v1.UpdateUserRequest
+	8,  // 12: accessgo.v1.AccessGo.DeleteUser:input_type -> accessgo.v1.DeleteUserRequest
+	24, // 13: accessgo.v1.AccessGo.ListUsers:input_type -> google.protobuf.Empty
+	9,  // 14: accessgo.v1.AccessGo.CreateGroup:input_type -> accessgo.v1.CreateGroupRequest
+	10, // 15: accessgo.v1.AccessGo.UpdateGroup:input_type -> accessgo.v1.UpdateGroupRequest
+	11, // 16: accessgo.v1.AccessGo.DeleteGroup:input_type -> accessgo.v1.DeleteGroupRequest
+	24, // 17: accessgo.v1.AccessGo.ListGroups:input_type -> google.protobuf.Empty
+	13, // 18: accessgo.v1.AccessGo.AssignUserToGroup:input_type -> accessgo.v1.MembershipRequest
+	13, // 19: accessgo.v1.AccessGo.ExcludeUserFromGroup:input_type -> accessgo.v1.MembershipRequest
+	14, // 20: accessgo.v1.AccessGo.SetUserGroups:input_type -> accessgo.v1.SetUserGroupsRequest
+	6,  // 21: accessgo.v1.AccessGo.GetUserGroups:input_type -> accessgo.v1.GetUserRequest
+	15, // 22: accessgo.v1.AccessGo.CreateAccess:input_type -> accessgo.v1.CreateAccessRequest
+	16, // 23: accessgo.v1.AccessGo.DeleteAccess:input_type -> accessgo.v1.DeleteAccessRequest
+	24, // 24: accessgo.v1.AccessGo.ListAccesses:input_type -> google.protobuf.Empty
+	18, // 25: accessgo.v1.AccessGo.GrantUserAccess:input_type -> accessgo.v1.UserAccessRequest
+	18, // 26: accessgo.v1.AccessGo.RevokeUserAccess:input_type -> accessgo.v1.UserAccessRequest
+	19, // 27: accessgo.v1.AccessGo.GrantGroupAccess:input_type -> accessgo.v1.GroupAccessRequest
+	19, // 28: accessgo.v1.AccessGo.RevokeGroupAccess:input_type -> accessgo.v1.GroupAccessRequest
+	6,  // 29: accessgo.v1.AccessGo.GetUserPermissions:input_type -> accessgo.v1.GetUserRequest
+	21, // 30: accessgo.v1.AccessGo.CheckUserAccess:input_type -> accessgo.v1.CheckUserAccessRequest
+	4,  // 31: accessgo.v1.AccessGo.Login:output_type -> accessgo.v1.LoginResponse
+	24, // 32: accessgo.v1.AccessGo.Logout:output_type -> google.protobuf.Empty
+	0,  // 33: accessgo.v1.AccessGo.GetCurrentUser:output_type -> accessgo.v1.User
+	0,  // 34: accessgo.v1.AccessGo.CreateUser:output_type -> accessgo.v1.User
+	0,  // 35: accessgo.v1.AccessGo.GetUser:output_type -> accessgo.v1.User
+	0,  // 36: accessgo.v1.AccessGo.UpdateUser:output_type -> accessgo.v1.User
+	24, // 37: accessgo.v1.AccessGo.DeleteUser:output_type -> google.protobuf.Empty
+	0,  // 38: accessgo.v1.AccessGo.ListUsers:output_type -> accessgo.v1.User
+	1,  // 39: accessgo.v1.AccessGo.CreateGroup:output_type -> accessgo.v1.Group
+	1,  // 40: accessgo.v1.AccessGo.UpdateGroup:output_type -> accessgo.v1.Group
+	24, // 41: accessgo.v1.AccessGo.DeleteGroup:output_type -> google.protobuf.Empty
+	12, // 42: accessgo.v1.AccessGo.ListGroups:output_type -> accessgo.v1.ListGroupsResponse
+	24, // 43: accessgo.v1.AccessGo.AssignUserToGroup:output_type -> google.protobuf.Empty
+	24, // 44: accessgo.v1.AccessGo.ExcludeUserFromGroup:output_type -> google.protobuf.Empty
+	24, // 45: accessgo.v1.AccessGo.SetUserGroups:output_type -> google.protobuf.Empty
+	12, // 46: accessgo.v1.AccessGo.GetUserGroups:output_type -> accessgo.v1.ListGroupsResponse
+	2,  // 47: accessgo.v1.AccessGo.CreateAccess:output_type -> accessgo.v1.Access
+	24, // 48: accessgo.v1.AccessGo.DeleteAccess:output_type -> google.protobuf.Empty
+	17, // 49: accessgo.v1.AccessGo.ListAccesses:output_type -> accessgo.v1.ListAccessesResponse
+	24, // 50: accessgo.v1.AccessGo.GrantUserAccess:output_type -> google.protobuf.Empty
+	24, // 51: accessgo.v1.AccessGo.RevokeUserAccess:output_type -> google.protobuf.Empty
+	24, // 52: accessgo.v1.AccessGo.GrantGroupAccess:output_type -> google.protobuf.Empty
+	24, // 53: accessgo.v1.AccessGo.RevokeGroupAccess:output_type -> google.protobuf.Empty
+	20, // 54: accessgo.v1.AccessGo.GetUserPermissions:output_type -> accessgo.v1.UserPermissions
+	22, // 55: accessgo.v1.AccessGo.CheckUserAccess:output_type -> accessgo.v1.CheckUserAccessResponse
+	31, // [31:56] is the sub-list for method output_type
+	6,  // [6:31] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_accessgo_v1_accessgo_proto_init() }
+func file_accessgo_v1_accessgo_proto_init() {
+	if File_accessgo_v1_accessgo_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_accessgo_v1_accessgo_proto_rawDesc), len(file_accessgo_v1_accessgo_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_accessgo_v1_accessgo_proto_goTypes,
+		DependencyIndexes: file_accessgo_v1_accessgo_proto_depIdxs,
+		MessageInfos:      file_accessgo_v1_accessgo_proto_msgTypes,
+	}.Build()
+	File_accessgo_v1_accessgo_proto = out.File
+	file_accessgo_v1_accessgo_proto_goTypes = nil
+	file_accessgo_v1_accessgo_proto_depIdxs = nil
+}
@@ -0,0 +1,184 @@
+package grpcapi
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/axgrid/accessgo"
+	"github.com/axgrid/accessgo/grpcapi/accessgopb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMethodAccess - права, необходимые для методов accessgo.v1.AccessGo.
+// Пустое право означает, что достаточно аутентификации
+var DefaultMethodAccess = map[string]string{
+	accessgopb.AccessGo_Logout_FullMethodName:               "",
+	accessgopb.AccessGo_GetCurrentUser_FullMethodName:       "",
+	accessgopb.AccessGo_CreateUser_FullMethodName:           "user:create",
+	accessgopb.AccessGo_GetUser_FullMethodName:              "user:read",
+	accessgopb.AccessGo_UpdateUser_FullMethodName:           "user:update",
+	accessgopb.AccessGo_DeleteUser_FullMethodName:           "user:delete",
+	accessgopb.AccessGo_ListUsers_FullMethodName:            "user:read",
+	accessgopb.AccessGo_CreateGroup_FullMethodName:          "group:create",
+	accessgopb.AccessGo_UpdateGroup_FullMethodName:          "group:update",
+	accessgopb.AccessGo_DeleteGroup_FullMethodName:          "group:delete",
+	accessgopb.AccessGo_ListGroups_FullMethodName:           "group:read",
+	accessgopb.AccessGo_AssignUserToGroup_FullMethodName:    "group:update",
+	accessgopb.AccessGo_ExcludeUserFromGroup_FullMethodName: "group:update",
+	accessgopb.AccessGo_SetUserGroups_FullMethodName:        "group:update",
+	accessgopb.AccessGo_GetUserGroups_FullMethodName:        "user:read",
+	accessgopb.AccessGo_CreateAccess_FullMethodName:         "access:create",
+	accessgopb.AccessGo_DeleteAccess_FullMethodName:         "access:delete",
+	accessgopb.AccessGo_ListAccesses_FullMethodName:         "access:read",
+	accessgopb.AccessGo_GrantUserAccess_FullMethodName:      "user_access:set",
+	accessgopb.AccessGo_RevokeUserAccess_FullMethodName:     "user_access:set",
+	accessgopb.AccessGo_GrantGroupAccess_FullMethodName:     "group_access:set",
+	accessgopb.AccessGo_RevokeGroupAccess_FullMethodName:    "group_access:set",
+	accessgopb.AccessGo_GetUserPermissions_FullMethodName:   "user:read",
+	accessgopb.AccessGo_CheckUserAccess_FullMethodName:      "user:read",
+}
+
+// AuthInterceptor аутентифицирует вызовы по сессии или API ключу из метаданных
+// "authorization: Bearer <токен>" и проверяет право, заданное для метода.
+// Методы, отсутствующие в карте прав и в списке публичных, запрещены
+type AuthInterceptor struct {
+	svc           *accessgo.AccessGoService
+	sessions      *accessgo.SessionService
+	methodAccess  map[string]string
+	publicMethods []string
+}
+
+type userContextKey struct{}
+
+// scopesContextKey - ключ контекста с областями API ключа, которым аутентифицирован вызов
+type scopesContextKey struct{}
+
+// requestIDMetadataKey - ключ метаданных с идентификатором запроса для журнала аудита
+const requestIDMetadataKey = "x-request-id"
+
+// NewAuthInterceptor создает перехватчик. methodAccess сопоставляет полное имя
+// метода ("/пакет.Сервис/Метод") с правом доступа; publicMethods не требуют аутентификации
+func NewAuthInterceptor(svc *accessgo.AccessGoService, sessions *accessgo.SessionService, methodAccess map[string]string, publicMethods ...string) *AuthInterceptor {
+	return &AuthInterceptor{
+		svc:           svc,
+		sessions:      sessions,
+		methodAccess:  methodAccess,
+		publicMethods: publicMethods,
+	}
+}
+
+// Unary возвращает перехватчик унарных вызовов
+func (a *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream возвращает перехватчик потоковых вызовов
+func (a *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// UserFromContext возвращает пользователя, аутентифицированного AuthInterceptor
+func UserFromContext(ctx context.Context) (*accessgo.User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*accessgo.User)
+	return user, ok
+}
+
+func (a *AuthInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 && values[0] != "" {
+			ctx = accessgo.ContextWithRequestID(ctx, values[0])
+		}
+	}
+	if slices.Contains(a.publicMethods, method) {
+		return ctx, nil
+	}
+	accessName, ok := a.methodAccess[method]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "для метода не заданы права доступа")
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if t, ok := strings.CutPrefix(value, "Bearer "); ok {
+				token = t
+			}
+		}
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "требуется аутентификация")
+	}
+
+	var (
+		user   *accessgo.User
+		scopes []string
+		apiKey = strings.HasPrefix(token, "ag_")
+	)
+	if apiKey {
+		var err error
+		if user, scopes, err = a.svc.AuthenticateAPIKey(token); err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	} else {
+		session, err := a.sessions.GetSession(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if user, err = a.svc.GetUserByID(uint(session.UserID)); err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if user.UserType == string(accessgo.UserTypeBlocked) {
+			return nil, status.Error(codes.PermissionDenied, "пользователь заблокирован")
+		}
+		ctx = accessgo.ContextWithSession(ctx, session)
+	}
+
+	if accessName != "" {
+		var (
+			allowed bool
+			err     error
+		)
+		if apiKey {
+			allowed, err = a.svc.CheckScopedAccess(user.ID, scopes, accessName)
+		} else {
+			allowed, err = a.svc.CheckUserAccess(user.ID, accessName)
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if !allowed {
+			return nil, status.Error(codes.PermissionDenied, "требуется право доступа "+accessName)
+		}
+	}
+	ctx = accessgo.ContextWithActor(ctx, user.ID)
+	if apiKey {
+		ctx = context.WithValue(ctx, scopesContextKey{}, scopes)
+	}
+	return context.WithValue(ctx, userContextKey{}, user), nil
+}
+
+// contextStream подменяет контекст потока контекстом с пользователем
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
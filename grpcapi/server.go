@@ -0,0 +1,388 @@
+// Package grpcapi предоставляет gRPC сервер AccessGo (accessgo.v1.AccessGo)
+// и перехватчики, которые аутентифицируют вызовы и проверяют права доступа.
+//
+// Код в accessgopb генерируется из proto/accessgo/v1/accessgo.proto:
+//
+//	buf generate
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/axgrid/accessgo"
+	"github.com/axgrid/accessgo/grpcapi/accessgopb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// Server реализует accessgopb.AccessGoServer поверх AccessGoService
+type Server struct {
+	accessgopb.UnimplementedAccessGoServer
+
+	svc      *accessgo.AccessGoService
+	sessions *accessgo.SessionService
+}
+
+// NewServer создает новый экземпляр Server
+func NewServer(svc *accessgo.AccessGoService, sessions *accessgo.SessionService) *Server {
+	return &Server{svc: svc, sessions: sessions}
+}
+
+// Register регистрирует сервер и перехватчики с правами DefaultMethodAccess
+// в новом grpc.Server
+func Register(svc *accessgo.AccessGoService, sessions *accessgo.SessionService, opts ...grpc.ServerOption) *grpc.Server {
+	auth := NewAuthInterceptor(svc, sessions, DefaultMethodAccess, accessgopb.AccessGo_Login_FullMethodName)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(auth.Unary()),
+		grpc.ChainStreamInterceptor(auth.Stream()),
+	)
+	server := grpc.NewServer(opts...)
+	accessgopb.RegisterAccessGoServer(server, NewServer(svc, sessions))
+	return server
+}
+
+// service возвращает сервис, записывающий изменения в аудит от имени вызывающего
+func (s *Server) service(ctx context.Context) *accessgo.AccessGoService {
+	return s.svc.WithContext(ctx)
+}
+
+func (s *Server) Login(ctx context.Context, req *accessgopb.LoginRequest) (*accessgopb.LoginResponse, error) {
+	user, err := s.service(ctx).AuthenticateUser(req.GetEmail(), req.GetPassword())
+	if err != nil {
+		// Причину не раскрываем, чтобы нельзя было перебирать email
+		return nil, status.Error(codes.Unauthenticated, "неверный email или пароль")
+	}
+	sessionID, err := s.sessions.CreateSession(int(user.ID), req.GetLongTerm())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	session, err := s.sessions.GetSession(sessionID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &accessgopb.LoginResponse{
+		SessionId: sessionID,
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+		User:      userToProto(user),
+	}, nil
+}
+
+func (s *Server) Logout(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	session, ok := accessgo.SessionFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "вызов выполнен не в рамках сессии")
+	}
+	s.sessions.DeleteSession(session.ID)
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GetCurrentUser(ctx context.Context, _ *emptypb.Empty) (*accessgopb.User, error) {
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "требуется аутентификация")
+	}
+	return userToProto(user), nil
+}
+
+func (s *Server) CreateUser(ctx context.Context, req *accessgopb.CreateUserRequest) (*accessgopb.User, error) {
+	userType := req.GetUserType()
+	if userType == "" {
+		userType = string(accessgo.UserTypeUser)
+	}
+	if err := s.checkUserType(ctx, accessgo.UserType(userType), nil); err != nil {
+		return nil, err
+	}
+	user, err := s.service(ctx).CreateUser(req.GetEmail(), req.GetPassword(), req.GetName(), accessgo.UserType(userType))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return userToProto(user), nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *accessgopb.GetUserRequest) (*accessgopb.User, error) {
+	user, err := s.service(ctx).GetUserByID(uint(req.GetId()))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return userToProto(user), nil
+}
+
+func (s *Server) UpdateUser(ctx context.Context, req *accessgopb.UpdateUserRequest) (*accessgopb.User, error) {
+	current, err := s.service(ctx).GetUserByID(uint(req.GetId()))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	userType := req.GetUserType()
+	if userType == "" {
+		userType = current.UserType
+	}
+	if err := s.checkUserType(ctx, accessgo.UserType(userType), current); err != nil {
+		return nil, err
+	}
+	user, err := s.service(ctx).UpdateUser(current.ID, req.GetEmail(), req.GetPassword(), req.GetName(), accessgo.UserType(userType))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return userToProto(user), nil
+}
+
+func (s *Server) DeleteUser(ctx context.Context, req *accessgopb.DeleteUserRequest) (*emptypb.Empty, error) {
+	current, err := s.service(ctx).GetUserByID(uint(req.GetId()))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	if isAdmin(current) {
+		if err := s.requireUserAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.service(ctx).DeleteUser(current.ID); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// checkUserType проверяет тип пользователя из запроса. Назначить тип admin, а также
+// изменить администратора current можно только с правом user:admin.
+// current равен nil при создании пользователя
+func (s *Server) checkUserType(ctx context.Context, userType accessgo.UserType, current *accessgo.User) error {
+	switch userType {
+	case accessgo.UserTypeAdmin, accessgo.UserTypeEmployee, accessgo.UserTypeUser, accessgo.UserTypeService, accessgo.UserTypeBlocked:
+	default:
+		return status.Error(codes.InvalidArgument, "неверный тип пользователя "+string(userType))
+	}
+	if userType != accessgo.UserTypeAdmin && !isAdmin(current) {
+		return nil
+	}
+	return s.requireUserAdmin(ctx)
+}
+
+// requireUserAdmin проверяет право user:admin вызывающего с учетом областей API ключа
+func (s *Server) requireUserAdmin(ctx context.Context) error {
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "требуется аутентификация")
+	}
+	var (
+		allowed bool
+		err     error
+	)
+	if scopes, ok := ctx.Value(scopesContextKey{}).([]string); ok {
+		allowed, err = s.svc.CheckScopedAccessCtx(ctx, user.ID, scopes, "user:admin")
+	} else {
+		allowed, err = s.svc.CheckUserAccessCtx(ctx, user.ID, "user:admin")
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if !allowed {
+		return status.Error(codes.PermissionDenied, "требуется право доступа user:admin")
+	}
+	return nil
+}
+
+// isAdmin сообщает, является ли user администратором, в том числе заблокированным
+func isAdmin(user *accessgo.User) bool {
+	if user == nil {
+		return false
+	}
+	if user.UserType == string(accessgo.UserTypeBlocked) {
+		return user.BlockedUserType == string(accessgo.UserTypeAdmin)
+	}
+	return user.UserType == string(accessgo.UserTypeAdmin)
+}
+
+func (s *Server) ListUsers(_ *emptypb.Empty, stream grpc.ServerStreamingServer[accessgopb.User]) error {
+	users, err := s.service(stream.Context()).GetAllUsers()
+	if err != nil {
+		return serviceError(err)
+	}
+	for i := range users {
+		if err := stream.Send(userToProto(&users[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) CreateGroup(ctx context.Context, req *accessgopb.CreateGroupRequest) (*accessgopb.Group, error) {
+	group, err := s.service(ctx).CreateGroup(req.GetName())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return groupToProto(group), nil
+}
+
+func (s *Server) UpdateGroup(ctx context.Context, req *accessgopb.UpdateGroupRequest) (*accessgopb.Group, error) {
+	group, err := s.service(ctx).UpdateGroup(uint(req.GetId()), req.GetName())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return groupToProto(group), nil
+}
+
+func (s *Server) DeleteGroup(ctx context.Context, req *accessgopb.DeleteGroupRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).DeleteGroup(uint(req.GetId())); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ListGroups(ctx context.Context, _ *emptypb.Empty) (*accessgopb.ListGroupsResponse, error) {
+	groups, err := s.service(ctx).GetAllGroups()
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return groupsToProto(groups), nil
+}
+
+func (s *Server) AssignUserToGroup(ctx context.Context, req *accessgopb.MembershipRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).AssignUserToGroup(uint(req.GetUserId()), uint(req.GetGroupId())); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ExcludeUserFromGroup(ctx context.Context, req *accessgopb.MembershipRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).ExcludeUserFromGroup(uint(req.GetUserId()), uint(req.GetGroupId())); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) SetUserGroups(ctx context.Context, req *accessgopb.SetUserGroupsRequest) (*emptypb.Empty, error) {
+	groupIDs := make([]uint, 0, len(req.GetGroupIds()))
+	for _, id := range req.GetGroupIds() {
+		groupIDs = append(groupIDs, uint(id))
+	}
+	if err := s.service(ctx).SetUserGroups(uint(req.GetUserId()), groupIDs...); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GetUserGroups(ctx context.Context, req *accessgopb.GetUserRequest) (*accessgopb.ListGroupsResponse, error) {
+	groups, err := s.service(ctx).GetUserGroups(uint(req.GetId()))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return groupsToProto(groups), nil
+}
+
+func (s *Server) CreateAccess(ctx context.Context, req *accessgopb.CreateAccessRequest) (*accessgopb.Access, error) {
+	access, err := s.service(ctx).CreateAccess(req.GetName(), req.GetDescription())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return accessToProto(access), nil
+}
+
+func (s *Server) DeleteAccess(ctx context.Context, req *accessgopb.DeleteAccessRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).DeleteAccess(uint(req.GetId())); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ListAccesses(ctx context.Context, _ *emptypb.Empty) (*accessgopb.ListAccessesResponse, error) {
+	accesses, err := s.service(ctx).ListAccesses()
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	response := &accessgopb.ListAccessesResponse{}
+	for i := range accesses {
+		response.Accesses = append(response.Accesses, accessToProto(&accesses[i]))
+	}
+	return response, nil
+}
+
+func (s *Server) GrantUserAccess(ctx context.Context, req *accessgopb.UserAccessRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).AddUserAccessLevel(uint(req.GetUserId()), req.GetAccess()); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) RevokeUserAccess(ctx context.Context, req *accessgopb.UserAccessRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).RemoveUserAccessLevel(uint(req.GetUserId()), req.GetAccess()); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GrantGroupAccess(ctx context.Context, req *accessgopb.GroupAccessRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).AddGroupAccessLevel(uint(req.GetGroupId()), req.GetAccess()); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) RevokeGroupAccess(ctx context.Context, req *accessgopb.GroupAccessRequest) (*emptypb.Empty, error) {
+	if err := s.service(ctx).RemoveGroupAccessLevel(uint(req.GetGroupId()), req.GetAccess()); err != nil {
+		return nil, serviceError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GetUserPermissions(ctx context.Context, req *accessgopb.GetUserRequest) (*accessgopb.UserPermissions, error) {
+	direct, err := s.service(ctx).GetUserAccessLevels(uint(req.GetId()))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	effective, err := s.service(ctx).GetUserSummaryAccessLevels(uint(req.GetId()))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &accessgopb.UserPermissions{Direct: direct, Effective: effective}, nil
+}
+
+func (s *Server) CheckUserAccess(ctx context.Context, req *accessgopb.CheckUserAccessRequest) (*accessgopb.CheckUserAccessResponse, error) {
+	allowed, err := s.service(ctx).CheckUserAccess(uint(req.GetUserId()), req.GetAccess())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &accessgopb.CheckUserAccessResponse{Allowed: allowed}, nil
+}
+
+// serviceError преобразует ошибку сервиса в статус gRPC: NotFound для
+// отсутствующих записей (accessgo.ErrNotFound), иначе InvalidArgument
+func serviceError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, accessgo.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+func userToProto(user *accessgo.User) *accessgopb.User {
+	return &accessgopb.User{
+		Id:             uint64(user.ID),
+		Email:          user.Email,
+		Name:           user.Name,
+		UserType:       user.UserType,
+		Source:         user.Source,
+		EmailValidated: user.EmailValidate,
+		CreatedAt:      timestamppb.New(user.CreatedAt),
+		UpdatedAt:      timestamppb.New(user.UpdatedAt),
+	}
+}
+
+func groupToProto(group *accessgo.Group) *accessgopb.Group {
+	return &accessgopb.Group{Id: uint64(group.ID), Name: group.Name}
+}
+
+func groupsToProto(groups []accessgo.Group) *accessgopb.ListGroupsResponse {
+	response := &accessgopb.ListGroupsResponse{}
+	for i := range groups {
+		response.Groups = append(response.Groups, groupToProto(&groups[i]))
+	}
+	return response
+}
+
+func accessToProto(access *accessgo.Access) *accessgopb.Access {
+	return &accessgopb.Access{Id: uint64(access.ID), Name: access.Name, Description: access.Description}
+}
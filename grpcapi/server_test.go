@@ -0,0 +1,197 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/axgrid/accessgo"
+	"github.com/axgrid/accessgo/grpcapi/accessgopb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupGRPC(t *testing.T) (*accessgo.AccessGoService, accessgopb.AccessGoClient) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	service, err := accessgo.NewAccessGoService(db)
+	require.NoError(t, err)
+	sessions := accessgo.NewSessionService(context.Background())
+	t.Cleanup(sessions.Stop)
+
+	listener := bufconn.Listen(1 << 20)
+	server := Register(service, sessions)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return service, accessgopb.NewAccessGoClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestGRPCLoginAndPermissions(t *testing.T) {
+	service, client := setupGRPC(t)
+	require.NoError(t, service.CreateDefaultAdminUser("admin@example.com", "secret", "Admin"))
+	admin, err := service.GetUserByEmail("admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(admin.EmailValidationToken))
+
+	_, err = client.GetCurrentUser(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	login, err := client.Login(context.Background(), &accessgopb.LoginRequest{Email: "admin@example.com", Password: "secret"})
+	require.NoError(t, err)
+	ctx := withToken(login.GetSessionId())
+
+	current, err := client.GetCurrentUser(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(admin.ID), current.GetId())
+
+	bob, err := client.CreateUser(ctx, &accessgopb.CreateUserRequest{Email: "bob@example.com", Password: "password", Name: "Bob"})
+	require.NoError(t, err)
+	_, err = client.GrantUserAccess(ctx, &accessgopb.UserAccessRequest{UserId: bob.GetId(), Access: "user:read"})
+	require.NoError(t, err)
+
+	check, err := client.CheckUserAccess(ctx, &accessgopb.CheckUserAccessRequest{UserId: bob.GetId(), Access: "user:read"})
+	require.NoError(t, err)
+	assert.True(t, check.GetAllowed())
+
+	_, err = client.GetUser(ctx, &accessgopb.GetUserRequest{Id: 999})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Потоковый вызов проходит через потоковый перехватчик
+	stream, err := client.ListUsers(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	var emails []string
+	for {
+		user, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		emails = append(emails, user.GetEmail())
+	}
+	assert.ElementsMatch(t, []string{"admin@example.com", "bob@example.com"}, emails)
+
+	_, err = client.Logout(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	_, err = client.GetCurrentUser(ctx, &emptypb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCUserTypeRequiresUserAdmin(t *testing.T) {
+	service, client := setupGRPC(t)
+	require.NoError(t, service.CreateDefaultAdminUser("admin@example.com", "secret", "Admin"))
+	admin, err := service.GetUserByEmail("admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(admin.EmailValidationToken))
+	manager, err := service.CreateUser("manager@example.com", "password", "Manager", accessgo.UserTypeEmployee)
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(manager.EmailValidationToken))
+	for _, access := range []string{"user:create", "user:read", "user:update", "user:delete"} {
+		require.NoError(t, service.AddUserAccessLevel(manager.ID, access))
+	}
+	login := func(email, password string) context.Context {
+		resp, err := client.Login(context.Background(), &accessgopb.LoginRequest{Email: email, Password: password})
+		require.NoError(t, err)
+		return withToken(resp.GetSessionId())
+	}
+	adminCtx := login("admin@example.com", "secret")
+	ctx := login("manager@example.com", "password")
+
+	_, err = client.CreateUser(ctx, &accessgopb.CreateUserRequest{Email: "dan@example.com", Password: "password", Name: "Dan", UserType: "root"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.CreateUser(ctx, &accessgopb.CreateUserRequest{Email: "dan@example.com", Password: "password", Name: "Dan", UserType: "admin"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	carol, err := client.CreateUser(ctx, &accessgopb.CreateUserRequest{Email: "carol@example.com", Password: "password", Name: "Carol", UserType: "employee"})
+	require.NoError(t, err)
+
+	// Без user_type тип не меняется
+	updated, err := client.UpdateUser(ctx, &accessgopb.UpdateUserRequest{Id: carol.GetId(), Email: "carol@example.com", Name: "Carol S."})
+	require.NoError(t, err)
+	assert.Equal(t, string(accessgo.UserTypeEmployee), updated.GetUserType())
+
+	_, err = client.UpdateUser(ctx, &accessgopb.UpdateUserRequest{Id: carol.GetId(), Email: "carol@example.com", Name: "Carol", UserType: "root"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.UpdateUser(ctx, &accessgopb.UpdateUserRequest{Id: carol.GetId(), Email: "carol@example.com", Name: "Carol", UserType: "admin"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	updated, err = client.UpdateUser(adminCtx, &accessgopb.UpdateUserRequest{Id: carol.GetId(), Email: "carol@example.com", Name: "Carol", UserType: "admin"})
+	require.NoError(t, err)
+	assert.Equal(t, string(accessgo.UserTypeAdmin), updated.GetUserType())
+
+	// Администратора без права user:admin нельзя изменить, понизить или удалить
+	_, err = client.UpdateUser(ctx, &accessgopb.UpdateUserRequest{Id: carol.GetId(), Email: "carol@example.com", Password: "hijacked", Name: "Carol"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.UpdateUser(ctx, &accessgopb.UpdateUserRequest{Id: carol.GetId(), Email: "carol@example.com", Name: "Carol", UserType: "user"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.DeleteUser(ctx, &accessgopb.DeleteUserRequest{Id: carol.GetId()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Области API ключа ограничивают и право user:admin
+	token, _, err := service.CreateAPIKey(admin.ID, "ci", nil, "user:update")
+	require.NoError(t, err)
+	_, err = client.UpdateUser(withToken(token), &accessgopb.UpdateUserRequest{Id: carol.GetId(), Email: "carol@example.com", Name: "Carol"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Группы пользователя меняются только с правом group:update
+	group, err := service.CreateGroup("ops")
+	require.NoError(t, err)
+	_, err = client.SetUserGroups(ctx, &accessgopb.SetUserGroupsRequest{UserId: carol.GetId(), GroupIds: []uint64{uint64(group.ID)}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.SetUserGroups(adminCtx, &accessgopb.SetUserGroupsRequest{UserId: carol.GetId(), GroupIds: []uint64{uint64(group.ID)}})
+	require.NoError(t, err)
+
+	_, err = client.DeleteUser(adminCtx, &accessgopb.DeleteUserRequest{Id: carol.GetId()})
+	require.NoError(t, err)
+}
+
+func TestGRPCAPIKeyScopes(t *testing.T) {
+	service, client := setupGRPC(t)
+	account, err := service.CreateServiceAccount("robot@service.local", "Robot")
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(account.ID, "user:read"))
+	token, _, err := service.CreateAPIKey(account.ID, "ci", nil)
+	require.NoError(t, err)
+	ctx := withToken(token)
+
+	stream, err := client.ListUsers(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	_, err = client.DeleteUser(ctx, &accessgopb.DeleteUserRequest{Id: uint64(account.ID)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream, err = client.ListUsers(withToken("ag_bad_token"), &emptypb.Empty{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthInterceptorDeniesUnlistedMethods(t *testing.T) {
+	auth := NewAuthInterceptor(nil, nil, map[string]string{})
+	_, err := auth.Unary()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/other.Service/Method"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/axgrid/accessgo"
+)
+
+type accessDTO struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type accessRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func newAccessDTO(access *accessgo.Access) accessDTO {
+	return accessDTO{ID: access.ID, Name: access.Name, Description: access.Description}
+}
+
+func (h *Handler) listAccesses(w http.ResponseWriter, r *http.Request, p *principal) {
+	accesses, err := h.service(r).ListAccesses()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	result := make([]accessDTO, 0, len(accesses))
+	for i := range accesses {
+		result = append(result, newAccessDTO(&accesses[i]))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) createAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	var req accessRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	access, err := h.service(r).CreateAccess(req.Name, req.Description)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newAccessDTO(access))
+}
+
+func (h *Handler) updateAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req accessRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	access, err := h.service(r).UpdateAccess(id, req.Name, req.Description)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAccessDTO(access))
+}
+
+func (h *Handler) deleteAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service(r).DeleteAccess(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	LongTerm bool   `json:"long_term"`
+}
+
+type sessionResponse struct {
+	SessionID   string     `json:"session_id,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	User        userDTO    `json:"user"`
+	Permissions []string   `json:"permissions"`
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	user, err := h.service(r).AuthenticateUser(req.Email, req.Password)
+	if err != nil {
+		// Причину не раскрываем, чтобы нельзя было перебирать email
+		writeError(w, http.StatusUnauthorized, errors.New("неверный email или пароль"))
+		return
+	}
+
+	sessionID, err := h.sessions.CreateSession(int(user.ID), req.LongTerm)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	session, err := h.sessions.GetSession(sessionID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	permissions, err := h.service(r).GetUserSummaryAccessLevels(user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sessionResponse{
+		SessionID:   sessionID,
+		ExpiresAt:   &session.ExpiresAt,
+		User:        newUserDTO(user),
+		Permissions: permissions,
+	})
+}
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request, p *principal) {
+	if p.sessionID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("запрос выполнен не в рамках сессии"))
+		return
+	}
+	h.sessions.DeleteSession(p.sessionID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) session(w http.ResponseWriter, r *http.Request, p *principal) {
+	permissions := p.scopes
+	if !p.apiKey {
+		var err error
+		if permissions, err = h.service(r).GetUserSummaryAccessLevels(p.user.ID); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	response := sessionResponse{User: newUserDTO(p.user), Permissions: permissions}
+	if p.sessionID != "" {
+		session, err := h.sessions.GetSession(p.sessionID)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		response.SessionID = session.ID
+		response.ExpiresAt = &session.ExpiresAt
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,170 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/axgrid/accessgo"
+)
+
+type groupDTO struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type groupRequest struct {
+	Name string `json:"name"`
+}
+
+func newGroupDTOs(groups []accessgo.Group) []groupDTO {
+	result := make([]groupDTO, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, groupDTO{ID: group.ID, Name: group.Name})
+	}
+	return result
+}
+
+func (h *Handler) listGroups(w http.ResponseWriter, r *http.Request, p *principal) {
+	groups, err := h.service(r).GetAllGroups()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newGroupDTOs(groups))
+}
+
+func (h *Handler) createGroup(w http.ResponseWriter, r *http.Request, p *principal) {
+	var req groupRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	group, err := h.service(r).CreateGroup(req.Name)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, groupDTO{ID: group.ID, Name: group.Name})
+}
+
+func (h *Handler) getGroup(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	group, err := h.service(r).GetGroupByID(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, groupDTO{ID: group.ID, Name: group.Name})
+}
+
+func (h *Handler) updateGroup(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req groupRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	group, err := h.service(r).UpdateGroup(id, req.Name)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, groupDTO{ID: group.ID, Name: group.Name})
+}
+
+func (h *Handler) deleteGroup(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service(r).DeleteGroup(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) getGroupMembers(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	users, err := h.service(r).GetGroupUsers(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserDTOs(users))
+}
+
+func (h *Handler) addGroupMember(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID, ok := pathID(w, r, "userID")
+	if !ok {
+		return
+	}
+	if err := h.service(r).AssignUserToGroup(userID, id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) removeGroupMember(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	userID, ok := pathID(w, r, "userID")
+	if !ok {
+		return
+	}
+	if err := h.service(r).ExcludeUserFromGroup(userID, id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) getGroupAccesses(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	accesses, err := h.service(r).GetGroupAccessLevels(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, accesses)
+}
+
+func (h *Handler) grantGroupAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service(r).AddGroupAccessLevel(id, r.PathValue("access")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) revokeGroupAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service(r).RemoveGroupAccessLevel(id, r.PathValue("access")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,196 @@
+// Package httpapi предоставляет JSON REST API поверх AccessGoService.
+// Каждый endpoint защищен стандартными правами доступа, созданными
+// SetupDefaultPermissions (user:read, group_access:set и т.д.).
+package httpapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/axgrid/accessgo"
+	"gorm.io/gorm"
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// Handler обслуживает REST API. Клиент передает в заголовке
+// Authorization: Bearer идентификатор сессии, полученный через /login, или API ключ
+type Handler struct {
+	svc      *accessgo.AccessGoService
+	sessions *accessgo.SessionService
+	mux      *http.ServeMux
+	handler  http.Handler
+}
+
+// principal - аутентифицированный клиент запроса
+type principal struct {
+	user      *accessgo.User
+	sessionID string
+	// scopes заданы только для API ключей и ограничивают права пользователя
+	scopes []string
+	apiKey bool
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler создает обработчик REST API, который можно смонтировать в любом роутере:
+//
+//	mux.Handle("/api/", http.StripPrefix("/api", httpapi.NewHandler(service, sessions)))
+func NewHandler(svc *accessgo.AccessGoService, sessions *accessgo.SessionService) *Handler {
+	h := &Handler{svc: svc, sessions: sessions, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /openapi.json", h.openAPI)
+	h.mux.HandleFunc("POST /login", h.login)
+	h.mux.HandleFunc("POST /logout", h.guard("", h.logout))
+	h.mux.HandleFunc("GET /session", h.guard("", h.session))
+
+	h.mux.HandleFunc("GET /users", h.guard("user:read", h.listUsers))
+	h.mux.HandleFunc("POST /users", h.guard("user:create", h.createUser))
+	h.mux.HandleFunc("GET /users/{id}", h.guard("user:read", h.getUser))
+	h.mux.HandleFunc("PUT /users/{id}", h.guard("user:update", h.updateUser))
+	h.mux.HandleFunc("DELETE /users/{id}", h.guard("user:delete", h.deleteUser))
+	h.mux.HandleFunc("GET /users/{id}/groups", h.guard("user:read", h.getUserGroups))
+	h.mux.HandleFunc("PUT /users/{id}/groups", h.guard("group:update", h.setUserGroups))
+	h.mux.HandleFunc("GET /users/{id}/accesses", h.guard("user:read", h.getUserAccesses))
+	h.mux.HandleFunc("PUT /users/{id}/accesses/{access}", h.guard("user_access:set", h.grantUserAccess))
+	h.mux.HandleFunc("DELETE /users/{id}/accesses/{access}", h.guard("user_access:set", h.revokeUserAccess))
+	h.mux.HandleFunc("GET /users/{id}/check/{access}", h.guard("user:read", h.checkUserAccess))
+
+	h.mux.HandleFunc("GET /groups", h.guard("group:read", h.listGroups))
+	h.mux.HandleFunc("POST /groups", h.guard("group:create", h.createGroup))
+	h.mux.HandleFunc("GET /groups/{id}", h.guard("group:read", h.getGroup))
+	h.mux.HandleFunc("PUT /groups/{id}", h.guard("group:update", h.updateGroup))
+	h.mux.HandleFunc("DELETE /groups/{id}", h.guard("group:delete", h.deleteGroup))
+	h.mux.HandleFunc("GET /groups/{id}/members", h.guard("group:read", h.getGroupMembers))
+	h.mux.HandleFunc("PUT /groups/{id}/members/{userID}", h.guard("group:update", h.addGroupMember))
+	h.mux.HandleFunc("DELETE /groups/{id}/members/{userID}", h.guard("group:update", h.removeGroupMember))
+	h.mux.HandleFunc("GET /groups/{id}/accesses", h.guard("group:read", h.getGroupAccesses))
+	h.mux.HandleFunc("PUT /groups/{id}/accesses/{access}", h.guard("group_access:set", h.grantGroupAccess))
+	h.mux.HandleFunc("DELETE /groups/{id}/accesses/{access}", h.guard("group_access:set", h.revokeGroupAccess))
+
+	h.mux.HandleFunc("GET /accesses", h.guard("access:read", h.listAccesses))
+	h.mux.HandleFunc("POST /accesses", h.guard("access:create", h.createAccess))
+	h.mux.HandleFunc("PUT /accesses/{id}", h.guard("access:update", h.updateAccess))
+	h.mux.HandleFunc("DELETE /accesses/{id}", h.guard("access:delete", h.deleteAccess))
+	h.handler = accessgo.RequestID(h.mux)
+	return h
+}
+
+// ServeHTTP реализует http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// guard аутентифицирует запрос и проверяет право accessName.
+// Пустой accessName означает, что достаточно аутентификации
+func (h *Handler) guard(accessName string, next func(http.ResponseWriter, *http.Request, *principal)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := h.authenticate(r)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		if accessName != "" {
+			allowed, err := h.allowed(p, accessName)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if !allowed {
+				writeError(w, http.StatusForbidden, errors.New("требуется право доступа "+accessName))
+				return
+			}
+		}
+		next(w, r.WithContext(accessgo.ContextWithActor(r.Context(), p.user.ID)), p)
+	}
+}
+
+// allowed проверяет право accessName клиента запроса с учетом scope API ключа
+func (h *Handler) allowed(p *principal, accessName string) (bool, error) {
+	if p.apiKey {
+		return h.svc.CheckScopedAccess(p.user.ID, p.scopes, accessName)
+	}
+	return h.svc.CheckUserAccess(p.user.ID, accessName)
+}
+
+// service возвращает сервис, записывающий изменения в аудит от имени клиента запроса
+func (h *Handler) service(r *http.Request) *accessgo.AccessGoService {
+	return h.svc.WithContext(r.Context())
+}
+
+func (h *Handler) authenticate(r *http.Request) (*principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, errors.New("требуется аутентификация")
+	}
+
+	if strings.HasPrefix(token, "ag_") {
+		user, scopes, err := h.svc.AuthenticateAPIKey(token)
+		if err != nil {
+			return nil, err
+		}
+		return &principal{user: user, scopes: scopes, apiKey: true}, nil
+	}
+
+	session, err := h.sessions.GetSession(token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.svc.GetUserByID(uint(session.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if user.UserType == string(accessgo.UserTypeBlocked) {
+		return nil, errors.New("пользователь заблокирован")
+	}
+	return &principal{user: user, sessionID: session.ID}, nil
+}
+
+func (h *Handler) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, name string) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("неверный идентификатор "+name))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeServiceError отвечает ошибкой сервиса: 404 для отсутствующих записей
+// (accessgo.ErrNotFound), иначе 400
+func writeServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, accessgo.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
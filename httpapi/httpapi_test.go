@@ -0,0 +1,228 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/axgrid/accessgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAPI(t *testing.T) (*accessgo.AccessGoService, *Handler) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	service, err := accessgo.NewAccessGoService(db)
+	require.NoError(t, err)
+
+	sessions := accessgo.NewSessionService(context.Background())
+	t.Cleanup(sessions.Stop)
+	return service, NewHandler(service, sessions)
+}
+
+func doRequest(t *testing.T, h http.Handler, method, path, token string, body interface{}) (int, []byte) {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+func login(t *testing.T, h http.Handler, email, password string) string {
+	status, body := doRequest(t, h, http.MethodPost, "/login", "", loginRequest{Email: email, Password: password})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var session sessionResponse
+	require.NoError(t, json.Unmarshal(body, &session))
+	return session.SessionID
+}
+
+func TestAPISessionLifecycle(t *testing.T) {
+	service, h := setupAPI(t)
+	require.NoError(t, service.CreateDefaultAdminUser("admin@example.com", "secret", "Admin"))
+	admin, err := service.GetUserByEmail("admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(admin.EmailValidationToken))
+
+	status, _ := doRequest(t, h, http.MethodPost, "/login", "", loginRequest{Email: "admin@example.com", Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	token := login(t, h, "admin@example.com", "secret")
+
+	status, body := doRequest(t, h, http.MethodGet, "/session", token, nil)
+	require.Equal(t, http.StatusOK, status)
+	var session sessionResponse
+	require.NoError(t, json.Unmarshal(body, &session))
+	assert.Equal(t, admin.ID, session.User.ID)
+	assert.Contains(t, session.Permissions, "user_access:set")
+
+	status, _ = doRequest(t, h, http.MethodPost, "/logout", token, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = doRequest(t, h, http.MethodGet, "/session", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAPIManageUsersGroupsAndGrants(t *testing.T) {
+	service, h := setupAPI(t)
+	require.NoError(t, service.CreateDefaultAdminUser("admin@example.com", "secret", "Admin"))
+	admin, err := service.GetUserByEmail("admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(admin.EmailValidationToken))
+	token := login(t, h, "admin@example.com", "secret")
+
+	status, body := doRequest(t, h, http.MethodPost, "/users", token, userRequest{Email: "bob@example.com", Password: "password", Name: "Bob"})
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var bob userDTO
+	require.NoError(t, json.Unmarshal(body, &bob))
+	assert.Equal(t, string(accessgo.UserTypeUser), bob.UserType)
+	assert.NotContains(t, string(body), "password")
+
+	status, body = doRequest(t, h, http.MethodPost, "/groups", token, groupRequest{Name: "readers"})
+	require.Equal(t, http.StatusCreated, status)
+	var group groupDTO
+	require.NoError(t, json.Unmarshal(body, &group))
+
+	groupPath := "/groups/" + strconv.FormatUint(uint64(group.ID), 10)
+	userPath := "/users/" + strconv.FormatUint(uint64(bob.ID), 10)
+	status, _ = doRequest(t, h, http.MethodPut, groupPath+"/accesses/user:read", token, nil)
+	require.Equal(t, http.StatusNoContent, status)
+	status, _ = doRequest(t, h, http.MethodPut, groupPath+"/members/"+strconv.FormatUint(uint64(bob.ID), 10), token, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	// Изменения записаны в аудит от имени администратора
+	events, err := service.QueryAuditLog(accessgo.AuditQuery{ActorID: &admin.ID, TargetType: accessgo.AuditTargetGroup})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.NotEmpty(t, events[0].RequestID)
+
+	status, body = doRequest(t, h, http.MethodGet, userPath+"/check/user:read", token, nil)
+	require.Equal(t, http.StatusOK, status)
+	var check checkResponse
+	require.NoError(t, json.Unmarshal(body, &check))
+	assert.True(t, check.Allowed)
+
+	status, body = doRequest(t, h, http.MethodGet, userPath+"/accesses", token, nil)
+	require.Equal(t, http.StatusOK, status)
+	var accesses userAccessesResponse
+	require.NoError(t, json.Unmarshal(body, &accesses))
+	assert.Empty(t, accesses.Direct)
+	assert.Equal(t, []string{"user:read"}, accesses.Effective)
+
+	status, _ = doRequest(t, h, http.MethodGet, "/users/999", token, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Бобу разрешено только чтение пользователей
+	bobUser, err := service.GetUserByID(bob.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(bobUser.EmailValidationToken))
+	bobToken := login(t, h, "bob@example.com", "password")
+
+	status, _ = doRequest(t, h, http.MethodGet, "/users", bobToken, nil)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = doRequest(t, h, http.MethodDelete, userPath, bobToken, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doRequest(t, h, http.MethodPut, userPath+"/accesses/user:delete", bobToken, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestAPIUserTypeAndGroupPermissions(t *testing.T) {
+	service, h := setupAPI(t)
+	require.NoError(t, service.CreateDefaultAdminUser("admin@example.com", "secret", "Admin"))
+	admin, err := service.GetUserByEmail("admin@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(admin.EmailValidationToken))
+	adminToken := login(t, h, "admin@example.com", "secret")
+
+	manager, err := service.CreateUser("manager@example.com", "password", "Manager", accessgo.UserTypeEmployee)
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(manager.EmailValidationToken))
+	for _, access := range []string{"user:create", "user:read", "user:update"} {
+		require.NoError(t, service.AddUserAccessLevel(manager.ID, access))
+	}
+	token := login(t, h, "manager@example.com", "password")
+
+	status, body := doRequest(t, h, http.MethodPost, "/users", token, userRequest{Email: "carol@example.com", Password: "password", Name: "Carol", UserType: "employee"})
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var carol userDTO
+	require.NoError(t, json.Unmarshal(body, &carol))
+	userPath := "/users/" + strconv.FormatUint(uint64(carol.ID), 10)
+
+	// Без user_type тип не меняется
+	status, body = doRequest(t, h, http.MethodPut, userPath, token, userRequest{Email: "carol@example.com", Name: "Carol S."})
+	require.Equal(t, http.StatusOK, status, string(body))
+	require.NoError(t, json.Unmarshal(body, &carol))
+	assert.Equal(t, string(accessgo.UserTypeEmployee), carol.UserType)
+
+	status, _ = doRequest(t, h, http.MethodPut, userPath, token, userRequest{Email: "carol@example.com", Name: "Carol", UserType: "root"})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Тип admin назначается только с правом user:admin
+	status, _ = doRequest(t, h, http.MethodPut, userPath, token, userRequest{Email: "carol@example.com", Name: "Carol", UserType: "admin"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doRequest(t, h, http.MethodPost, "/users", token, userRequest{Email: "dan@example.com", Password: "password", Name: "Dan", UserType: "admin"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, body = doRequest(t, h, http.MethodPut, userPath, adminToken, userRequest{Email: "carol@example.com", Name: "Carol", UserType: "admin"})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// Группы пользователя меняются только с правом group:update
+	group, err := service.CreateGroup("ops")
+	require.NoError(t, err)
+	status, _ = doRequest(t, h, http.MethodPut, userPath+"/groups", token, userGroupsRequest{GroupIDs: []uint{group.ID}})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doRequest(t, h, http.MethodPut, userPath+"/groups", adminToken, userGroupsRequest{GroupIDs: []uint{group.ID}})
+	assert.Equal(t, http.StatusOK, status)
+
+	// Отсутствующие записи - 404
+	status, _ = doRequest(t, h, http.MethodPut, userPath+"/groups", adminToken, userGroupsRequest{GroupIDs: []uint{999}})
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = doRequest(t, h, http.MethodPut, "/users/999", adminToken, userRequest{Email: "x@example.com", Name: "X"})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Администратора без права user:admin нельзя изменить, понизить или удалить
+	require.NoError(t, service.AddUserAccessLevel(manager.ID, "user:delete"))
+	status, _ = doRequest(t, h, http.MethodPut, userPath, token, userRequest{Email: "carol@example.com", Password: "hijacked", Name: "Carol"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doRequest(t, h, http.MethodPut, userPath, token, userRequest{Email: "carol@example.com", Name: "Carol", UserType: "user"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doRequest(t, h, http.MethodDelete, userPath, token, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doRequest(t, h, http.MethodDelete, userPath, adminToken, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestAPIKeyScopesAndOpenAPI(t *testing.T) {
+	service, h := setupAPI(t)
+	account, err := service.CreateServiceAccount("robot@service.local", "Robot")
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(account.ID, "group:read"))
+	require.NoError(t, service.AddUserAccessLevel(account.ID, "group:create"))
+	token, _, err := service.CreateAPIKey(account.ID, "ci", nil, "group:read")
+	require.NoError(t, err)
+
+	status, _ := doRequest(t, h, http.MethodGet, "/groups", token, nil)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = doRequest(t, h, http.MethodPost, "/groups", token, groupRequest{Name: "ops"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doRequest(t, h, http.MethodGet, "/groups", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, body := doRequest(t, h, http.MethodGet, "/openapi.json", "", nil)
+	require.Equal(t, http.StatusOK, status)
+	var doc struct {
+		Paths map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(body, &doc))
+	assert.Contains(t, doc.Paths, "/users/{id}/accesses/{access}")
+}
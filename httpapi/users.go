@@ -0,0 +1,278 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/axgrid/accessgo"
+)
+
+type userDTO struct {
+	ID             uint      `json:"id"`
+	Email          string    `json:"email"`
+	Name           string    `json:"name"`
+	UserType       string    `json:"user_type"`
+	Source         string    `json:"source"`
+	EmailValidated bool      `json:"email_validated"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type userRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	UserType string `json:"user_type"`
+}
+
+type userGroupsRequest struct {
+	GroupIDs []uint `json:"group_ids"`
+}
+
+type userAccessesResponse struct {
+	Direct    []string `json:"direct"`
+	Effective []string `json:"effective"`
+}
+
+type checkResponse struct {
+	Access  string `json:"access"`
+	Allowed bool   `json:"allowed"`
+}
+
+func newUserDTO(user *accessgo.User) userDTO {
+	return userDTO{
+		ID:             user.ID,
+		Email:          user.Email,
+		Name:           user.Name,
+		UserType:       user.UserType,
+		Source:         user.Source,
+		EmailValidated: user.EmailValidate,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+	}
+}
+
+func newUserDTOs(users []accessgo.User) []userDTO {
+	result := make([]userDTO, 0, len(users))
+	for i := range users {
+		result = append(result, newUserDTO(&users[i]))
+	}
+	return result
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request, p *principal) {
+	users, err := h.service(r).GetAllUsers()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserDTOs(users))
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request, p *principal) {
+	var req userRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.UserType == "" {
+		req.UserType = string(accessgo.UserTypeUser)
+	}
+	if !h.checkUserType(w, p, accessgo.UserType(req.UserType), nil) {
+		return
+	}
+	user, err := h.service(r).CreateUser(req.Email, req.Password, req.Name, accessgo.UserType(req.UserType))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newUserDTO(user))
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	user, err := h.service(r).GetUserByID(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserDTO(user))
+}
+
+// updateUser заменяет email, имя и тип пользователя; пустые пароль и тип не меняются
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req userRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	current, err := h.service(r).GetUserByID(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if req.UserType == "" {
+		req.UserType = current.UserType
+	}
+	if !h.checkUserType(w, p, accessgo.UserType(req.UserType), current) {
+		return
+	}
+	user, err := h.service(r).UpdateUser(id, req.Email, req.Password, req.Name, accessgo.UserType(req.UserType))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newUserDTO(user))
+}
+
+// checkUserType проверяет тип пользователя из запроса. Назначить тип admin, а также
+// изменить администратора current можно только с правом user:admin.
+// current равен nil при создании пользователя
+func (h *Handler) checkUserType(w http.ResponseWriter, p *principal, userType accessgo.UserType, current *accessgo.User) bool {
+	switch userType {
+	case accessgo.UserTypeAdmin, accessgo.UserTypeEmployee, accessgo.UserTypeUser, accessgo.UserTypeService, accessgo.UserTypeBlocked:
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("неверный тип пользователя "+string(userType)))
+		return false
+	}
+	if userType != accessgo.UserTypeAdmin && !isAdmin(current) {
+		return true
+	}
+	return h.requireUserAdmin(w, p)
+}
+
+// requireUserAdmin проверяет право user:admin, необходимое для изменения администраторов
+func (h *Handler) requireUserAdmin(w http.ResponseWriter, p *principal) bool {
+	allowed, err := h.allowed(p, "user:admin")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if !allowed {
+		writeError(w, http.StatusForbidden, errors.New("требуется право доступа user:admin"))
+		return false
+	}
+	return true
+}
+
+// isAdmin сообщает, является ли user администратором, в том числе заблокированным
+func isAdmin(user *accessgo.User) bool {
+	if user == nil {
+		return false
+	}
+	if user.UserType == string(accessgo.UserTypeBlocked) {
+		return user.BlockedUserType == string(accessgo.UserTypeAdmin)
+	}
+	return user.UserType == string(accessgo.UserTypeAdmin)
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	current, err := h.service(r).GetUserByID(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if isAdmin(current) && !h.requireUserAdmin(w, p) {
+		return
+	}
+	if err := h.service(r).DeleteUser(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) getUserGroups(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	groups, err := h.service(r).GetUserGroups(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newGroupDTOs(groups))
+}
+
+func (h *Handler) setUserGroups(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req userGroupsRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if err := h.service(r).SetUserGroups(id, req.GroupIDs...); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	h.getUserGroups(w, r, p)
+}
+
+func (h *Handler) getUserAccesses(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	direct, err := h.service(r).GetUserAccessLevels(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	effective, err := h.service(r).GetUserSummaryAccessLevels(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, userAccessesResponse{Direct: direct, Effective: effective})
+}
+
+func (h *Handler) grantUserAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service(r).AddUserAccessLevel(id, r.PathValue("access")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) revokeUserAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service(r).RemoveUserAccessLevel(id, r.PathValue("access")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) checkUserAccess(w http.ResponseWriter, r *http.Request, p *principal) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	accessName := r.PathValue("access")
+	allowed, err := h.service(r).CheckUserAccess(id, accessName)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, checkResponse{Access: accessName, Allowed: allowed})
+}
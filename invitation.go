@@ -0,0 +1,268 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// InvitationTTL - срок действия токена приглашения
+const InvitationTTL = 7 * 24 * time.Hour
+
+// InviteUser создает пользователя без пароля, добавляет его в группы groupIDs и
+// выдает приглашение со сроком действия InvitationTTL. Пока приглашение не принято
+// (AcceptInvitation), пользователь не может войти. Возвращает токен приглашения
+// (показывается один раз, в базе хранится только хеш) и приглашение
+func (s *AccessGoService) InviteUser(email, name string, userType UserType, groupIDs []uint) (string, *Invitation, error) {
+	return s.InviteUserCtx(s.ctx, email, name, userType, groupIDs)
+}
+
+// InviteUserCtx - InviteUser с контекстом ctx
+func (s *AccessGoService) InviteUserCtx(ctx context.Context, email, name string, userType UserType, groupIDs []uint) (string, *Invitation, error) {
+	s = s.WithContext(ctx)
+	if len(groupIDs) > 0 {
+		var count int64
+		if err := s.db.Model(&Group{}).Where("id IN ?", groupIDs).Count(&count).Error; err != nil {
+			return "", nil, err
+		}
+		if int(count) != len(groupIDs) {
+			return "", nil, notFound("одна или несколько групп не найдены")
+		}
+	}
+
+	var token string
+	var invitation *Invitation
+	err := s.transaction(func(tx *AccessGoService) error {
+		var err error
+		token, invitation, err = tx.invitePendingUser(email, name, userType, groupIDs)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return token, invitation, nil
+}
+
+// AcceptInvitation принимает приглашение: задает пароль, подтверждает email и,
+// если name не пустое, меняет имя пользователя. Токен после этого недействителен
+func (s *AccessGoService) AcceptInvitation(token, password, name string) (*User, error) {
+	return s.AcceptInvitationCtx(s.ctx, token, password, name)
+}
+
+// AcceptInvitationCtx - AcceptInvitation с контекстом ctx
+func (s *AccessGoService) AcceptInvitationCtx(ctx context.Context, token, password, name string) (*User, error) {
+	s = s.WithContext(ctx)
+	if token == "" {
+		return nil, errors.New("необходимо указать токен приглашения")
+	}
+	if password == "" {
+		return nil, errors.New("необходимо указать пароль")
+	}
+	var invitation Invitation
+	if err := s.db.Preload("User").Where("token_hash = ?", hashToken(token)).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("приглашение не найдено")
+		}
+		return nil, err
+	}
+	if invitation.User.ID == 0 {
+		return nil, errors.New("приглашение не найдено")
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		return nil, errors.New("срок действия приглашения истек")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// Приглашение удаляется до изменения пользователя: из одновременных попыток
+	// принять его по одному токену выполнится только одна
+	var user User
+	err = s.transaction(func(tx *AccessGoService) error {
+		result := tx.db.Where("token_hash = ?", invitation.TokenHash).Delete(&Invitation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errors.New("приглашение не найдено")
+		}
+		if err := tx.db.First(&user, invitation.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("приглашение не найдено")
+			}
+			return err
+		}
+		previous := newEventUser(&user)
+		before := userSnapshot(&user)
+		user.Password = string(hashedPassword)
+		user.EmailValidate = true
+		user.EmailValidationToken = ""
+		if name != "" {
+			user.Name = name
+		}
+		event := UserUpdated{Before: previous, After: newEventUser(&user)}
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Save(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.db.Where("user_id = ?", user.ID).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		return tx.audit(AuditInvitationAccept, AuditTargetUser, user.ID, before, userSnapshot(&user))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ResendInvitation выдает приглашенному пользователю новый токен с новым сроком
+// действия. Прежний токен становится недействительным
+func (s *AccessGoService) ResendInvitation(userID uint) (string, *Invitation, error) {
+	return s.ResendInvitationCtx(s.ctx, userID)
+}
+
+// ResendInvitationCtx - ResendInvitation с контекстом ctx
+func (s *AccessGoService) ResendInvitationCtx(ctx context.Context, userID uint) (string, *Invitation, error) {
+	s = s.WithContext(ctx)
+	var token string
+	var invitation *Invitation
+	err := s.transaction(func(tx *AccessGoService) error {
+		user, err := tx.takeInvitations(userID)
+		if err != nil {
+			return err
+		}
+		token, invitation, err = tx.issueInvitation(user)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return token, invitation, nil
+}
+
+// RevokeInvitation отзывает приглашение и удаляет пользователя, который его
+// еще не принял, вместе с его членством в группах и правами. Email после этого
+// можно пригласить снова
+func (s *AccessGoService) RevokeInvitation(userID uint) error {
+	return s.RevokeInvitationCtx(s.ctx, userID)
+}
+
+// RevokeInvitationCtx - RevokeInvitation с контекстом ctx
+func (s *AccessGoService) RevokeInvitationCtx(ctx context.Context, userID uint) error {
+	s = s.WithContext(ctx)
+	return s.transaction(func(tx *AccessGoService) error {
+		user, err := tx.takeInvitations(userID)
+		if err != nil {
+			return err
+		}
+		event := UserDeleted{User: newEventUser(user)}
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Unscoped().Where("user_id = ?", user.ID).Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.db.Unscoped().Where("user_id = ?", user.ID).Delete(&AccessLevel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.db.Model(user).Association("Groups").Clear(); err != nil {
+			return err
+		}
+		if err := tx.db.Unscoped().Delete(user).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{user.ID}})
+		return tx.audit(AuditInvitationRevoke, AuditTargetUser, user.ID, userSnapshot(user), nil)
+	})
+}
+
+// ListInvitations возвращает действующие и просроченные приглашения, которые еще не приняты
+func (s *AccessGoService) ListInvitations() ([]Invitation, error) {
+	return s.ListInvitationsCtx(s.ctx)
+}
+
+// ListInvitationsCtx - ListInvitations с контекстом ctx
+func (s *AccessGoService) ListInvitationsCtx(ctx context.Context) ([]Invitation, error) {
+	s = s.WithContext(ctx)
+	var invitations []Invitation
+	if err := s.db.Preload("User").Order("id").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// invitePendingUser создает пользователя без пароля и выдает ему приглашение.
+// Вызывается внутри транзакции
+func (s *AccessGoService) invitePendingUser(email, name string, userType UserType, groupIDs []uint) (string, *Invitation, error) {
+	var user User
+	if err := s.createImportedUser(&user, ExportUser{Email: email, Name: name, UserType: userType}); err != nil {
+		return "", nil, err
+	}
+	if len(groupIDs) > 0 {
+		if err := s.SetUserGroups(user.ID, groupIDs...); err != nil {
+			return "", nil, err
+		}
+	}
+	return s.issueInvitation(&user)
+}
+
+func (s *AccessGoService) issueInvitation(user *User) (string, *Invitation, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	invitation := &Invitation{
+		UserID:    user.ID,
+		TokenHash: hashToken(secret),
+		ExpiresAt: time.Now().Add(InvitationTTL),
+	}
+	if err := s.db.Create(invitation).Error; err != nil {
+		return "", nil, err
+	}
+	invitation.User = *user
+	if err := s.audit(AuditInvitationCreate, AuditTargetUser, user.ID, nil, invitationSnapshot(invitation)); err != nil {
+		return "", nil, err
+	}
+	return secret, invitation, nil
+}
+
+// takeInvitations удаляет непринятые приглашения пользователя и возвращает его.
+// Вызывается в транзакции: из одновременных повторных отправок и отзывов одного
+// приглашения выполнится только одна, а принятое приглашение уже не найдется
+func (s *AccessGoService) takeInvitations(userID uint) (*User, error) {
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	result := s.db.Where("user_id = ?", user.ID).Delete(&Invitation{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("у пользователя нет приглашения")
+	}
+	return &user, nil
+}
+
+func invitationSnapshot(invitation *Invitation) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":    invitation.UserID,
+		"expires_at": invitation.ExpiresAt,
+	}
+}
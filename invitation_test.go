@@ -0,0 +1,124 @@
+package accessgo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationFlow(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	sales, err := service.CreateGroup("sales")
+	require.NoError(t, err)
+
+	_, _, err = service.InviteUser("bob@example.com", "Bob", UserTypeEmployee, []uint{sales.ID, 999})
+	assert.EqualError(t, err, "одна или несколько групп не найдены")
+
+	token, invitation, err := service.InviteUser("bob@example.com", "Bob", UserTypeEmployee, []uint{sales.ID})
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+	assert.Equal(t, "bob@example.com", invitation.User.Email)
+	assert.WithinDuration(t, time.Now().Add(InvitationTTL), invitation.ExpiresAt, time.Minute)
+	assert.NotEqual(t, token, invitation.TokenHash)
+	members, err := service.GetGroupUsers(sales.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+	_, err = service.AuthenticateUser("bob@example.com", "")
+	assert.Error(t, err, "приглашенный пользователь не может войти")
+
+	invitations, err := service.ListInvitations()
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, invitation.UserID, invitations[0].User.ID)
+
+	// Повторная отправка делает прежний токен недействительным
+	resent, _, err := service.ResendInvitation(invitation.UserID)
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(token, "secret", "")
+	assert.EqualError(t, err, "приглашение не найдено")
+	_, err = service.AcceptInvitation(resent, "", "")
+	assert.EqualError(t, err, "необходимо указать пароль")
+
+	user, err := service.AcceptInvitation(resent, "secret", "Robert")
+	require.NoError(t, err)
+	assert.Equal(t, "Robert", user.Name)
+	assert.True(t, user.EmailValidate)
+	user, err = service.AuthenticateUser("bob@example.com", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, invitation.UserID, user.ID)
+	_, err = service.AcceptInvitation(resent, "other", "")
+	assert.EqualError(t, err, "приглашение не найдено")
+	_, _, err = service.ResendInvitation(user.ID)
+	assert.EqualError(t, err, "у пользователя нет приглашения")
+	assert.EqualError(t, service.RevokeInvitation(user.ID), "у пользователя нет приглашения")
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetUser, TargetID: strconv.FormatUint(uint64(user.ID), 10)})
+	require.NoError(t, err)
+	var actions []AuditAction
+	for _, event := range events {
+		actions = append(actions, event.Action)
+	}
+	assert.Contains(t, actions, AuditInvitationCreate)
+	assert.Contains(t, actions, AuditInvitationAccept)
+}
+
+func TestInvitationExpiryAndRevoke(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	sales, err := service.CreateGroup("sales")
+	require.NoError(t, err)
+	token, invitation, err := service.InviteUser("ann@example.com", "Ann", UserTypeUser, []uint{sales.ID})
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(invitation.UserID, "user:read"))
+
+	require.NoError(t, service.db.Model(invitation).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = service.AcceptInvitation(token, "secret", "")
+	assert.EqualError(t, err, "срок действия приглашения истек")
+
+	// Отзыв удаляет не принявшего приглашение пользователя, и email можно пригласить снова
+	require.NoError(t, service.RevokeInvitation(invitation.UserID))
+	_, err = service.GetUserByEmail("ann@example.com")
+	assert.Error(t, err)
+	members, err := service.GetGroupUsers(sales.ID)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+	invitations, err := service.ListInvitations()
+	require.NoError(t, err)
+	assert.Empty(t, invitations)
+	_, err = service.AcceptInvitation(token, "secret", "")
+	assert.EqualError(t, err, "приглашение не найдено")
+
+	_, _, err = service.InviteUser("ann@example.com", "Ann", UserTypeUser, nil)
+	require.NoError(t, err)
+	assert.EqualError(t, service.RevokeInvitation(999), "пользователь не найден")
+}
+
+func TestInvitationSingleUse(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	token, invitation, err := service.InviteUser("bob@example.com", "Bob", UserTypeUser, nil)
+	require.NoError(t, err)
+
+	// Повторное принятие того же токена и повторная отправка внутри транзакции
+	// принятия видят, что приглашение уже использовано
+	var replayErrs []error
+	OnBefore(service, func(ctx context.Context, event UserUpdated) error {
+		_, err := service.AcceptInvitationCtx(ctx, token, "other", "")
+		replayErrs = append(replayErrs, err)
+		_, _, err = service.ResendInvitationCtx(ctx, event.After.ID)
+		replayErrs = append(replayErrs, err)
+		return nil
+	})
+	user, err := service.AcceptInvitation(token, "secret", "")
+	require.NoError(t, err)
+	require.Len(t, replayErrs, 2)
+	assert.EqualError(t, replayErrs[0], "приглашение не найдено")
+	assert.EqualError(t, replayErrs[1], "у пользователя нет приглашения")
+	_, err = service.AuthenticateUser("bob@example.com", "secret")
+	require.NoError(t, err)
+	assert.EqualError(t, service.RevokeInvitation(invitation.UserID), "у пользователя нет приглашения")
+	_, err = service.GetUserByID(user.ID)
+	require.NoError(t, err)
+}
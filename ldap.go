@@ -0,0 +1,417 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LDAPConn - операции LDAP, используемые LDAPService. Реализуется *ldap.Conn
+type LDAPConn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPConfig описывает подключение к LDAP / Active Directory
+type LDAPConfig struct {
+	URL             string // например "ldaps://dc.example.com:636"
+	BindDN          string // сервисная учетная запись для поиска пользователей
+	BindPassword    string
+	BaseDN          string
+	UserFilter      string // по умолчанию "(objectClass=person)"
+	EmailAttribute  string // по умолчанию "mail"
+	NameAttribute   string // по умолчанию "displayName"
+	GroupAttribute  string // по умолчанию "memberOf"
+	DefaultUserType UserType
+	// GroupMapping сопоставляет DN группы LDAP с именем группы AccessGo.
+	// Если не задано, используется CN группы LDAP. Синхронизация управляет
+	// членством в группах из GroupMapping и в созданных ею группах; локальная
+	// группа с тем же именем, что у CN, не затрагивается (LDAPSyncResult.SkippedGroups)
+	GroupMapping map[string]string
+	// BlockMissingUsers блокирует LDAP пользователей, которых больше нет в каталоге
+	BlockMissingUsers bool
+	// LinkExistingUsers привязывает к записи LDAP существующего локального пользователя
+	// с тем же email. Без этого флага такие записи пропускаются (LDAPSyncResult.Skipped),
+	// чтобы учетная запись каталога не получила чужой локальный аккаунт
+	LinkExistingUsers bool
+	SyncInterval      time.Duration // по умолчанию 15 минут
+	// Dial открывает соединение с сервером, по умолчанию ldap.DialURL(URL)
+	Dial func() (LDAPConn, error)
+	// OnSyncError вызывается при ошибке периодической синхронизации
+	OnSyncError func(error)
+}
+
+// LDAPSyncResult содержит итоги синхронизации
+type LDAPSyncResult struct {
+	Created int
+	Updated int
+	Blocked int
+	// Skipped - записи, email которых занят локальным пользователем (см. LinkExistingUsers),
+	// и записи, привязанный пользователь которых удален в AccessGo
+	Skipped int
+	// SkippedGroups - группы LDAP, имя которых занято локальной группой
+	SkippedGroups []string
+}
+
+// LDAPService аутентифицирует LDAP пользователей и синхронизирует их группы
+type LDAPService struct {
+	svc    *AccessGoService
+	cfg    LDAPConfig
+	cancel context.CancelFunc
+}
+
+type ldapUser struct {
+	DN     string
+	Email  string
+	Name   string
+	Groups []string
+}
+
+// NewLDAPService создает новый экземпляр LDAPService и регистрирует его
+// для аутентификации пользователей с источником UserSourceLDAP
+func NewLDAPService(svc *AccessGoService, cfg LDAPConfig) (*LDAPService, error) {
+	if err := svc.db.AutoMigrate(&ExternalIdentity{}); err != nil {
+		return nil, err
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(objectClass=person)"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "displayName"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.DefaultUserType == "" {
+		cfg.DefaultUserType = UserTypeEmployee
+	}
+	if cfg.SyncInterval == 0 {
+		cfg.SyncInterval = 15 * time.Minute
+	}
+	if cfg.GroupMapping != nil {
+		// DN в LDAP сравниваются без учета регистра
+		mapping := make(map[string]string, len(cfg.GroupMapping))
+		for dn, name := range cfg.GroupMapping {
+			mapping[strings.ToLower(dn)] = name
+		}
+		cfg.GroupMapping = mapping
+	}
+	if cfg.Dial == nil {
+		cfg.Dial = func() (LDAPConn, error) {
+			return ldap.DialURL(cfg.URL)
+		}
+	}
+
+	l := &LDAPService{svc: svc, cfg: cfg}
+	svc.RegisterPasswordAuthenticator(UserSourceLDAP, l)
+	return l, nil
+}
+
+// AuthenticatePassword проверяет пароль, выполняя bind от имени пользователя
+func (l *LDAPService) AuthenticatePassword(user *User, password string) error {
+	if password == "" {
+		// Пустой пароль в LDAP означает анонимный bind, который всегда успешен
+		return errors.New("пароль не указан")
+	}
+
+	var identity ExternalIdentity
+	if err := l.svc.db.Where("provider = ? AND user_id = ?", string(UserSourceLDAP), user.ID).First(&identity).Error; err != nil {
+		return errors.New("пользователь не найден в LDAP")
+	}
+
+	conn, err := l.cfg.Dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Bind(identity.Subject, password)
+}
+
+// Sync загружает пользователей из LDAP, создает и обновляет их в AccessGo
+// и устанавливает группы согласно членству в группах LDAP
+func (l *LDAPService) Sync() (LDAPSyncResult, error) {
+	var result LDAPSyncResult
+
+	users, err := l.searchUsers()
+	if err != nil {
+		return result, err
+	}
+
+	// Управляемые группы - из GroupMapping и созданные синхронизацией ранее, даже
+	// если в них больше никто не состоит: так членство в них отзывается
+	var ldapGroups []string
+	if err := l.svc.db.Model(&Group{}).Where("source = ?", string(UserSourceLDAP)).Pluck("name", &ldapGroups).Error; err != nil {
+		return result, err
+	}
+	managedGroups := make(map[string]bool, len(ldapGroups)+len(l.cfg.GroupMapping))
+	for _, name := range ldapGroups {
+		managedGroups[name] = true
+	}
+	for _, name := range l.cfg.GroupMapping {
+		managedGroups[name] = true
+	}
+
+	seen := make(map[uint]bool)
+	for _, u := range users {
+		if u.Email == "" {
+			continue
+		}
+		user, created, err := l.upsertUser(u)
+		if errors.Is(err, errLDAPUserConflict) || errors.Is(err, ErrUserNotFound) {
+			result.Skipped++
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		seen[user.ID] = true
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+		if err := l.syncGroups(user.ID, l.mapGroups(u.Groups), managedGroups, &result); err != nil {
+			return result, err
+		}
+	}
+
+	if l.cfg.BlockMissingUsers {
+		var ldapUsers []User
+		if err := l.svc.db.Where("source = ?", string(UserSourceLDAP)).Find(&ldapUsers).Error; err != nil {
+			return result, err
+		}
+		for _, user := range ldapUsers {
+			if seen[user.ID] || user.UserType == string(UserTypeBlocked) {
+				continue
+			}
+			if _, err := l.svc.UpdateUser(user.ID, user.Email, "", user.Name, UserTypeBlocked); err != nil {
+				return result, err
+			}
+			result.Blocked++
+		}
+	}
+
+	return result, nil
+}
+
+// StartSync запускает периодическую синхронизацию до отмены контекста или вызова Stop
+func (l *LDAPService) StartSync(ctx context.Context) {
+	ctx, l.cancel = context.WithCancel(ctx)
+	go l.syncRoutine(ctx)
+}
+
+// Stop останавливает периодическую синхронизацию
+func (l *LDAPService) Stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+}
+
+func (l *LDAPService) syncRoutine(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := l.Sync(); err != nil && l.cfg.OnSyncError != nil {
+				l.cfg.OnSyncError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (l *LDAPService) searchUsers() ([]ldapUser, error) {
+	conn, err := l.cfg.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(l.cfg.BindDN, l.cfg.BindPassword); err != nil {
+		return nil, err
+	}
+
+	request := ldap.NewSearchRequest(
+		l.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		l.cfg.UserFilter,
+		[]string{l.cfg.EmailAttribute, l.cfg.NameAttribute, l.cfg.GroupAttribute},
+		nil,
+	)
+	response, err := conn.Search(request)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]ldapUser, 0, len(response.Entries))
+	for _, entry := range response.Entries {
+		users = append(users, ldapUser{
+			DN:     entry.DN,
+			Email:  strings.ToLower(entry.GetAttributeValue(l.cfg.EmailAttribute)),
+			Name:   entry.GetAttributeValue(l.cfg.NameAttribute),
+			Groups: entry.GetAttributeValues(l.cfg.GroupAttribute),
+		})
+	}
+	return users, nil
+}
+
+// errLDAPUserConflict - email записи LDAP занят локальным пользователем
+var errLDAPUserConflict = errors.New("email занят локальным пользователем")
+
+// upsertUser создает или обновляет пользователя записи LDAP в одной транзакции
+func (l *LDAPService) upsertUser(u ldapUser) (*User, bool, error) {
+	name := u.Name
+	if name == "" {
+		name = u.Email
+	}
+
+	var user *User
+	created := false
+	err := l.svc.transaction(func(tx *AccessGoService) error {
+		var identity ExternalIdentity
+		err := tx.db.Where("provider = ? AND subject = ?", string(UserSourceLDAP), u.DN).First(&identity).Error
+		if err == nil {
+			if user, err = tx.GetUserByID(identity.UserID); err != nil {
+				return err
+			}
+			if user.Email != u.Email || user.Name != name {
+				user, err = tx.UpdateUser(user.ID, u.Email, "", name, UserType(user.UserType))
+			}
+			return err
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		user, err = tx.GetUserByEmail(u.Email)
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			if user, err = tx.CreateUser(u.Email, uuid.NewString(), name, l.cfg.DefaultUserType); err != nil {
+				return err
+			}
+			created = true
+		case err != nil:
+			return err
+		case !l.cfg.LinkExistingUsers:
+			return errLDAPUserConflict
+		}
+
+		user, err = tx.updateUser(user.ID, func(user *User) {
+			user.Name = name
+			user.Source = string(UserSourceLDAP)
+			user.EmailValidate = true
+			user.EmailValidationToken = ""
+		})
+		if err != nil {
+			return err
+		}
+		return tx.db.Create(&ExternalIdentity{
+			UserID:   user.ID,
+			Provider: string(UserSourceLDAP),
+			Subject:  u.DN,
+			Email:    u.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return user, created, nil
+}
+
+// syncGroups заменяет управляемые из LDAP группы пользователя, сохраняя остальные.
+// Недостающие группы создаются с источником UserSourceLDAP и становятся управляемыми
+func (l *LDAPService) syncGroups(userID uint, groupNames []string, managedGroups map[string]bool, result *LDAPSyncResult) error {
+	current, err := l.svc.GetUserGroups(userID)
+	if err != nil {
+		return err
+	}
+
+	groupIDs := make([]uint, 0, len(current)+len(groupNames))
+	for _, group := range current {
+		if !managedGroups[group.Name] {
+			groupIDs = append(groupIDs, group.ID)
+		}
+	}
+	added := make(map[string]bool, len(groupNames))
+	for _, name := range groupNames {
+		if added[name] {
+			continue
+		}
+		added[name] = true
+		var group Group
+		err := l.svc.db.Where("name = ?", name).First(&group).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if group, err = l.createGroup(name); err != nil {
+				return err
+			}
+			managedGroups[name] = true
+		case err != nil:
+			return err
+		case !managedGroups[name]:
+			// Локальная группа с тем же именем не переходит под управление LDAP
+			if !slices.Contains(result.SkippedGroups, name) {
+				result.SkippedGroups = append(result.SkippedGroups, name)
+			}
+			continue
+		}
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	return l.svc.SetUserGroups(userID, groupIDs...)
+}
+
+// createGroup создает группу с источником UserSourceLDAP. Удаленная группа
+// с тем же именем восстанавливается
+func (l *LDAPService) createGroup(name string) (Group, error) {
+	var group Group
+	err := l.svc.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Unscoped().Where("name = ?", name).Limit(1).Find(&group).Error; err != nil {
+			return err
+		}
+		if group.ID != 0 {
+			if err := tx.restoreGroup(&group); err != nil {
+				return err
+			}
+		} else {
+			created, err := tx.CreateGroup(name)
+			if err != nil {
+				return err
+			}
+			group = *created
+		}
+		return tx.db.Model(&group).Update("source", string(UserSourceLDAP)).Error
+	})
+	return group, err
+}
+
+func (l *LDAPService) mapGroups(groupDNs []string) []string {
+	names := make([]string, 0, len(groupDNs))
+	for _, dn := range groupDNs {
+		if l.cfg.GroupMapping != nil {
+			if name, ok := l.cfg.GroupMapping[strings.ToLower(dn)]; ok {
+				names = append(names, name)
+			}
+			continue
+		}
+		parsed, err := ldap.ParseDN(dn)
+		if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		names = append(names, parsed.RDNs[0].Attributes[0].Value)
+	}
+	return names
+}
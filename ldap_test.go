@@ -0,0 +1,236 @@
+package accessgo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLDAPDirectory - каталог LDAP в памяти для тестов
+type fakeLDAPDirectory struct {
+	passwords map[string]string
+	entries   []*ldap.Entry
+}
+
+type fakeLDAPConn struct {
+	dir   *fakeLDAPDirectory
+	bound bool
+}
+
+func (c *fakeLDAPConn) Bind(username, password string) error {
+	if expected, ok := c.dir.passwords[username]; !ok || expected != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, nil)
+	}
+	c.bound = true
+	return nil
+}
+
+func (c *fakeLDAPConn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if !c.bound {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, nil)
+	}
+	return &ldap.SearchResult{Entries: c.dir.entries}, nil
+}
+
+func (c *fakeLDAPConn) Close() error {
+	return nil
+}
+
+func (d *fakeLDAPDirectory) addUser(dn, password, mail, name string, groups ...string) {
+	d.passwords[dn] = password
+	d.entries = append(d.entries, ldap.NewEntry(dn, map[string][]string{
+		"mail":        {mail},
+		"displayName": {name},
+		"memberOf":    groups,
+	}))
+}
+
+func setupLDAPService(t *testing.T, cfg LDAPConfig) (*AccessGoService, *LDAPService, *fakeLDAPDirectory) {
+	service := newTestService(t, setupTestDB(t))
+	dir := &fakeLDAPDirectory{passwords: map[string]string{"cn=svc,dc=corp": "svc-secret"}}
+
+	cfg.BindDN = "cn=svc,dc=corp"
+	cfg.BindPassword = "svc-secret"
+	cfg.BaseDN = "dc=corp"
+	cfg.Dial = func() (LDAPConn, error) {
+		return &fakeLDAPConn{dir: dir}, nil
+	}
+
+	ldapService, err := NewLDAPService(service, cfg)
+	require.NoError(t, err)
+	return service, ldapService, dir
+}
+
+func TestLDAPSyncAndAuthenticate(t *testing.T) {
+	service, ldapService, dir := setupLDAPService(t, LDAPConfig{})
+	dir.addUser("cn=alice,ou=staff,dc=corp", "alice-pass", "Alice@corp.example", "Alice",
+		"cn=Developers,ou=groups,dc=corp", "cn=Admins,ou=groups,dc=corp")
+
+	result, err := ldapService.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+
+	user, err := service.GetUserByEmail("alice@corp.example")
+	require.NoError(t, err)
+	assert.Equal(t, string(UserSourceLDAP), user.Source)
+	assert.Equal(t, string(UserTypeEmployee), user.UserType)
+
+	groups, err := service.GetUserGroups(user.ID)
+	require.NoError(t, err)
+	names := []string{}
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	assert.ElementsMatch(t, []string{"Developers", "Admins"}, names)
+
+	// Пароль проверяется bind в LDAP, локальный хеш не используется
+	_, err = service.AuthenticateUser("alice@corp.example", "alice-pass")
+	assert.NoError(t, err)
+	_, err = service.AuthenticateUser("alice@corp.example", "wrong")
+	assert.Error(t, err)
+	_, err = service.AuthenticateUser("alice@corp.example", "")
+	assert.Error(t, err)
+
+	// Локальные пользователи по-прежнему проверяются по хешу
+	local, err := service.CreateUser("local@example.com", "password", "Local", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(local.EmailValidationToken))
+	_, err = service.AuthenticateUser("local@example.com", "password")
+	assert.NoError(t, err)
+}
+
+func TestLDAPSyncUpdatesGroupsAndBlocksMissingUsers(t *testing.T) {
+	service, ldapService, dir := setupLDAPService(t, LDAPConfig{
+		GroupMapping: map[string]string{
+			"CN=Developers,OU=Groups,DC=corp": "developers",
+			"cn=ops,ou=groups,dc=corp":        "operations",
+		},
+		BlockMissingUsers: true,
+	})
+	dir.addUser("cn=bob,dc=corp", "bob-pass", "bob@corp.example", "Bob",
+		"cn=developers,ou=groups,dc=corp", "cn=unmapped,ou=groups,dc=corp")
+	dir.addUser("cn=carol,dc=corp", "carol-pass", "carol@corp.example", "Carol", "cn=ops,ou=groups,dc=corp")
+
+	_, err := ldapService.Sync()
+	require.NoError(t, err)
+
+	bob, err := service.GetUserByEmail("bob@corp.example")
+	require.NoError(t, err)
+
+	// Локальная группа, назначенная вручную, не управляется LDAP
+	manual, err := service.CreateGroup("manual")
+	require.NoError(t, err)
+	require.NoError(t, service.AssignUserToGroup(bob.ID, manual.ID))
+
+	// Боба переводят в ops и переименовывают, Кэрол удаляют из каталога
+	dir.entries = nil
+	dir.addUser("cn=bob,dc=corp", "bob-pass", "bob@corp.example", "Robert", "cn=ops,ou=groups,dc=corp")
+
+	result, err := ldapService.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, LDAPSyncResult{Updated: 1, Blocked: 1}, result)
+
+	bob, err = service.GetUserByID(bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Robert", bob.Name)
+
+	groups, err := service.GetUserGroups(bob.ID)
+	require.NoError(t, err)
+	names := []string{}
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	assert.ElementsMatch(t, []string{"manual", "operations"}, names)
+
+	carol, err := service.GetUserByEmail("carol@corp.example")
+	require.NoError(t, err)
+	assert.Equal(t, string(UserTypeBlocked), carol.UserType)
+	_, err = service.AuthenticateUser("carol@corp.example", "carol-pass")
+	assert.Error(t, err)
+}
+
+func TestLDAPSyncLinksExistingUsersOnlyWhenAllowed(t *testing.T) {
+	service, ldapService, dir := setupLDAPService(t, LDAPConfig{})
+	local, err := service.CreateUser("dave@corp.example", "password", "Dave", UserTypeUser)
+	require.NoError(t, err)
+	dir.addUser("cn=dave,dc=corp", "dave-pass", "dave@corp.example", "David")
+
+	// Без LinkExistingUsers локальный пользователь не привязывается к LDAP
+	result, err := ldapService.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, LDAPSyncResult{Skipped: 1}, result)
+	user, err := service.GetUserByID(local.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(UserSourceLocal), user.Source)
+	_, err = service.AuthenticateUser("dave@corp.example", "dave-pass")
+	assert.Error(t, err)
+
+	var updated []UserUpdated
+	service.AddBeforeHook(EventUserUpdated, func(ctx context.Context, event Event) error {
+		updated = append(updated, event.(UserUpdated))
+		return nil
+	})
+	ldapService.cfg.LinkExistingUsers = true
+	result, err = ldapService.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, LDAPSyncResult{Updated: 1}, result)
+	user, err = service.GetUserByID(local.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(UserSourceLDAP), user.Source)
+	assert.Equal(t, "David", user.Name)
+
+	// Привязка проходит через UpdateUser: с событием и записью аудита
+	require.Len(t, updated, 1)
+	assert.Equal(t, string(UserSourceLocal), updated[0].Before.Source)
+	assert.Equal(t, string(UserSourceLDAP), updated[0].After.Source)
+	events, err := service.QueryAuditLog(AuditQuery{Action: AuditUserUpdate, TargetID: strconv.FormatUint(uint64(local.ID), 10)})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestLDAPSyncManagedGroupsAndDeletedUsers(t *testing.T) {
+	service, ldapService, dir := setupLDAPService(t, LDAPConfig{})
+	local, err := service.CreateGroup("Admins")
+	require.NoError(t, err)
+	dir.addUser("cn=alice,dc=corp", "alice-pass", "alice@corp.example", "Alice",
+		"cn=Devs,ou=groups,dc=corp", "cn=Admins,ou=groups,dc=corp")
+	dir.addUser("cn=bob,dc=corp", "bob-pass", "bob@corp.example", "Bob")
+
+	// CN, совпадающий с локальной группой, не дает членства в ней
+	result, err := ldapService.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Admins"}, result.SkippedGroups)
+	members, err := service.GetGroupUsers(local.ID)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+	alice, err := service.GetUserByEmail("alice@corp.example")
+	require.NoError(t, err)
+	groups, err := service.GetUserGroups(alice.ID)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "Devs", groups[0].Name)
+	assert.Equal(t, string(UserSourceLDAP), groups[0].Source)
+
+	// Последний участник покидает группу LDAP - членство отзывается
+	dir.entries = nil
+	dir.addUser("cn=alice,dc=corp", "alice-pass", "alice@corp.example", "Alice")
+	dir.addUser("cn=bob,dc=corp", "bob-pass", "bob@corp.example", "Bob")
+	_, err = ldapService.Sync()
+	require.NoError(t, err)
+	groups, err = service.GetUserGroups(alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	// Удаленный в AccessGo пользователь пропускается, а не прерывает синхронизацию
+	bob, err := service.GetUserByEmail("bob@corp.example")
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteUser(bob.ID))
+	dir.addUser("cn=carol,dc=corp", "carol-pass", "carol@corp.example", "Carol")
+	result, err = ldapService.Sync()
+	require.NoError(t, err)
+	assert.Equal(t, LDAPSyncResult{Created: 1, Updated: 1, Skipped: 1}, result)
+}
@@ -0,0 +1,381 @@
+package accessgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest описывает модель прав: права доступа, роли (именованные наборы прав)
+// и группы с их правами. Манифест хранится в YAML или JSON рядом с кодом и
+// применяется ApplyManifest
+type Manifest struct {
+	Accesses []ManifestAccess `json:"accesses" yaml:"accesses"`
+	Roles    []ManifestRole   `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Groups   []ManifestGroup  `json:"groups,omitempty" yaml:"groups,omitempty"`
+	// Prune удаляет права доступа и группы, которых нет в манифесте. Без Prune
+	// манифест только создает и обновляет. Права групп из манифеста приводятся
+	// к описанным в любом случае
+	Prune bool `json:"prune,omitempty" yaml:"prune,omitempty"`
+}
+
+// ManifestAccess - право доступа в манифесте
+type ManifestAccess struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// ManifestRole - именованный набор прав, который можно выдать группам
+type ManifestRole struct {
+	Name     string   `json:"name" yaml:"name"`
+	Accesses []string `json:"accesses" yaml:"accesses"`
+}
+
+// ManifestGroup - группа и ее права: перечисленные в Accesses, права ролей Roles
+// и права с условиями Conditions (имя права - условие)
+type ManifestGroup struct {
+	Name       string            `json:"name" yaml:"name"`
+	Roles      []string          `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Accesses   []string          `json:"accesses,omitempty" yaml:"accesses,omitempty"`
+	Conditions map[string]string `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+// ManifestAction - действие плана применения манифеста
+type ManifestAction string
+
+const (
+	ManifestCreate ManifestAction = "create"
+	ManifestUpdate ManifestAction = "update"
+	ManifestDelete ManifestAction = "delete"
+	// ManifestRestore восстанавливает удаленные право доступа или группу с прежним ID
+	ManifestRestore ManifestAction = "restore"
+)
+
+// ManifestChange - одно изменение плана. Kind - "access", "group" или "grant";
+// для "grant" Name - группа, Access - право
+type ManifestChange struct {
+	Action      ManifestAction `json:"action"`
+	Kind        string         `json:"kind"`
+	Name        string         `json:"name"`
+	Access      string         `json:"access,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Condition   string         `json:"condition,omitempty"`
+}
+
+func (c ManifestChange) String() string {
+	switch c.Kind {
+	case "grant":
+		s := fmt.Sprintf("%s grant %s -> %s", c.Action, c.Access, c.Name)
+		if c.Condition != "" {
+			s += " при условии " + c.Condition
+		}
+		return s
+	case "access":
+		if c.Action != ManifestDelete {
+			return fmt.Sprintf("%s access %s (%s)", c.Action, c.Name, c.Description)
+		}
+	}
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Name)
+}
+
+// ManifestPlan - изменения, необходимые, чтобы база соответствовала манифесту,
+// в порядке применения. Пустой план означает, что база уже соответствует
+type ManifestPlan struct {
+	Changes []ManifestChange `json:"changes"`
+}
+
+// DefaultManifest возвращает манифест стандартных прав доступа AccessGo
+func DefaultManifest() *Manifest {
+	return &Manifest{Accesses: []ManifestAccess{
+		{"user:create", "Создание пользователя"},
+		{"user:read", "Чтение информации о пользователе"},
+		{"user:update", "Обновление информации о пользователе"},
+		{"user:delete", "Удаление пользователя"},
+		{"group:create", "Создание группы"},
+		{"group:read", "Чтение информации о группе"},
+		{"group:update", "Обновление информации о группе"},
+		{"group:delete", "Удаление группы"},
+		{"access:create", "Создание права доступа"},
+		{"access:read", "Чтение информации о праве доступа"},
+		{"access:update", "Обновление информации о праве доступа"},
+		{"access:delete", "Удаление права доступа"},
+		{"user_access:set", "Установка прав доступа пользователю"},
+		{"group_access:set", "Установка прав доступа группе"},
+		{"user:admin", "Назначение пользователю типа admin"},
+	}}
+}
+
+// ParseManifest разбирает манифест в формате YAML или JSON. Неизвестные поля - ошибка
+func ParseManifest(data []byte) (*Manifest, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var manifest Manifest
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("неверный манифест: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ApplyManifest сравнивает манифест с базой и возвращает план изменений. Если dryRun
+// не задан, план применяется в одной транзакции; повторное применение того же
+// манифеста ничего не меняет
+func (s *AccessGoService) ApplyManifest(manifest *Manifest, dryRun bool) (*ManifestPlan, error) {
+	return s.ApplyManifestCtx(s.ctx, manifest, dryRun)
+}
+
+// ApplyManifestCtx - ApplyManifest с контекстом ctx
+func (s *AccessGoService) ApplyManifestCtx(ctx context.Context, manifest *Manifest, dryRun bool) (*ManifestPlan, error) {
+	s = s.WithContext(ctx)
+	if dryRun {
+		return s.planManifest(manifest)
+	}
+
+	var plan *ManifestPlan
+	err := s.transaction(func(tx *AccessGoService) error {
+		var err error
+		if plan, err = tx.planManifest(manifest); err != nil {
+			return err
+		}
+		return tx.applyManifestPlan(plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// grants проверяет манифест и возвращает права его групп с условиями (пустое - без условия)
+func (m *Manifest) grants() (map[string]map[string]string, error) {
+	accesses := make(map[string]bool, len(m.Accesses))
+	for _, access := range m.Accesses {
+		if access.Name == "" {
+			return nil, errors.New("неверный манифест: право без имени")
+		}
+		if accesses[access.Name] {
+			return nil, fmt.Errorf("неверный манифест: право %q описано дважды", access.Name)
+		}
+		accesses[access.Name] = true
+	}
+	roles := make(map[string][]string, len(m.Roles))
+	for _, role := range m.Roles {
+		if _, ok := roles[role.Name]; ok || role.Name == "" {
+			return nil, fmt.Errorf("неверный манифест: роль %q описана дважды или без имени", role.Name)
+		}
+		roles[role.Name] = role.Accesses
+	}
+
+	grants := make(map[string]map[string]string, len(m.Groups))
+	for _, group := range m.Groups {
+		if _, ok := grants[group.Name]; ok || group.Name == "" {
+			return nil, fmt.Errorf("неверный манифест: группа %q описана дважды или без имени", group.Name)
+		}
+		groupGrants := make(map[string]string)
+		names := append([]string(nil), group.Accesses...)
+		for _, roleName := range group.Roles {
+			role, ok := roles[roleName]
+			if !ok {
+				return nil, fmt.Errorf("неверный манифест: группа %q ссылается на неизвестную роль %q", group.Name, roleName)
+			}
+			names = append(names, role...)
+		}
+		for _, name := range names {
+			groupGrants[name] = ""
+		}
+		for name, condition := range group.Conditions {
+			if _, ok := groupGrants[name]; ok {
+				return nil, fmt.Errorf("неверный манифест: право %q выдано группе %q и с условием, и без", name, group.Name)
+			}
+			if err := ValidateCondition(condition); err != nil {
+				return nil, fmt.Errorf("группа %q, право %q: %w", group.Name, name, err)
+			}
+			groupGrants[name] = condition
+		}
+		for name := range groupGrants {
+			if !accesses[name] {
+				return nil, fmt.Errorf("неверный манифест: право %q группы %q не описано в accesses", name, group.Name)
+			}
+		}
+		grants[group.Name] = groupGrants
+	}
+	return grants, nil
+}
+
+func (s *AccessGoService) planManifest(manifest *Manifest) (*ManifestPlan, error) {
+	grants, err := manifest.grants()
+	if err != nil {
+		return nil, err
+	}
+
+	var accesses []Access
+	if err := s.db.Find(&accesses).Error; err != nil {
+		return nil, err
+	}
+	var groups []Group
+	if err := s.db.Preload("Accesses.Access").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	existingAccesses := make(map[string]Access, len(accesses))
+	for _, access := range accesses {
+		existingAccesses[access.Name] = access
+	}
+	existingGroups := make(map[string]Group, len(groups))
+	for _, group := range groups {
+		existingGroups[group.Name] = group
+	}
+	// Имена удаленных записей остаются занятыми, поэтому такие записи восстанавливаются
+	var deletedAccesses, deletedGroups []string
+	if err := s.db.Unscoped().Model(&Access{}).Where("deleted_at IS NOT NULL").Pluck("name", &deletedAccesses).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Unscoped().Model(&Group{}).Where("deleted_at IS NOT NULL").Pluck("name", &deletedGroups).Error; err != nil {
+		return nil, err
+	}
+
+	plan := &ManifestPlan{Changes: []ManifestChange{}}
+	declaredAccesses := make(map[string]bool, len(manifest.Accesses))
+	for _, access := range manifest.Accesses {
+		declaredAccesses[access.Name] = true
+		existing, ok := existingAccesses[access.Name]
+		switch {
+		case !ok && slices.Contains(deletedAccesses, access.Name):
+			plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestRestore, Kind: "access", Name: access.Name, Description: access.Description})
+		case !ok:
+			plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestCreate, Kind: "access", Name: access.Name, Description: access.Description})
+		case existing.Description != access.Description:
+			plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestUpdate, Kind: "access", Name: access.Name, Description: access.Description})
+		}
+	}
+
+	for _, group := range manifest.Groups {
+		switch _, ok := existingGroups[group.Name]; {
+		case !ok && slices.Contains(deletedGroups, group.Name):
+			plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestRestore, Kind: "group", Name: group.Name})
+		case !ok:
+			plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestCreate, Kind: "group", Name: group.Name})
+		}
+	}
+
+	for _, group := range manifest.Groups {
+		current := make(map[string]string)
+		for _, level := range existingGroups[group.Name].Accesses {
+			// Выдачи удаленных прав не действуют
+			if level.Access.ID != 0 {
+				current[level.Access.Name] = level.Condition
+			}
+		}
+		wanted := grants[group.Name]
+		for _, name := range sortedKeys(current) {
+			if _, ok := wanted[name]; !ok {
+				plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestDelete, Kind: "grant", Name: group.Name, Access: name})
+			}
+		}
+		for _, name := range sortedKeys(wanted) {
+			condition, ok := current[name]
+			switch {
+			case !ok:
+				plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestCreate, Kind: "grant", Name: group.Name, Access: name, Condition: wanted[name]})
+			case condition != wanted[name]:
+				plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestUpdate, Kind: "grant", Name: group.Name, Access: name, Condition: wanted[name]})
+			}
+		}
+	}
+
+	if manifest.Prune {
+		for _, group := range groups {
+			if _, ok := grants[group.Name]; !ok {
+				plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestDelete, Kind: "group", Name: group.Name})
+			}
+		}
+		for _, access := range accesses {
+			if !declaredAccesses[access.Name] {
+				plan.Changes = append(plan.Changes, ManifestChange{Action: ManifestDelete, Kind: "access", Name: access.Name})
+			}
+		}
+	}
+	return plan, nil
+}
+
+func (s *AccessGoService) applyManifestPlan(plan *ManifestPlan) error {
+	groupIDs := make(map[string]uint)
+	groupID := func(name string) (uint, error) {
+		if id, ok := groupIDs[name]; ok {
+			return id, nil
+		}
+		var group Group
+		if err := s.db.Where("name = ?", name).First(&group).Error; err != nil {
+			return 0, err
+		}
+		groupIDs[name] = group.ID
+		return group.ID, nil
+	}
+
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Kind + " " + string(change.Action) {
+		case "access create":
+			_, err = s.CreateAccess(change.Name, change.Description)
+		case "access update":
+			var access *Access
+			if access, err = s.GetAccessByName(change.Name); err == nil {
+				_, err = s.UpdateAccess(access.ID, access.Name, change.Description)
+			}
+		case "access restore":
+			var access Access
+			if err = s.db.Unscoped().Where("name = ?", change.Name).First(&access).Error; err == nil {
+				err = s.restoreAccess(&access, change.Description)
+			}
+		case "access delete":
+			var access *Access
+			if access, err = s.GetAccessByName(change.Name); err == nil {
+				err = s.DeleteAccess(access.ID)
+			}
+		case "group create":
+			var group *Group
+			if group, err = s.CreateGroup(change.Name); err == nil {
+				groupIDs[group.Name] = group.ID
+			}
+		case "group restore":
+			var group Group
+			if err = s.db.Unscoped().Where("name = ?", change.Name).First(&group).Error; err == nil {
+				err = s.restoreGroup(&group)
+				groupIDs[group.Name] = group.ID
+			}
+		case "group delete":
+			var id uint
+			if id, err = groupID(change.Name); err == nil {
+				err = s.DeleteGroup(id)
+			}
+		case "grant create", "grant update", "grant delete":
+			var id uint
+			if id, err = groupID(change.Name); err != nil {
+				break
+			}
+			if change.Action != ManifestCreate {
+				if err = s.RemoveGroupAccessLevel(id, change.Access); err != nil {
+					break
+				}
+			}
+			if change.Action != ManifestDelete {
+				err = s.AddGroupAccessLevelWithCondition(id, change.Access, change.Condition)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", change, err)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
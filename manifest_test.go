@@ -0,0 +1,180 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `
+accesses:
+  - name: report:read
+    description: Чтение отчетов
+  - name: report:export
+    description: Выгрузка отчетов
+  - name: user:read
+    description: Чтение информации о пользователе
+roles:
+  - name: analyst
+    accesses: [report:read, report:export]
+groups:
+  - name: analysts
+    roles: [analyst]
+  - name: support
+    accesses: [user:read]
+    conditions:
+      report:read: cidr(request.ip, "10.0.0.0/8")
+`
+
+func TestApplyManifest(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	manifest, err := ParseManifest([]byte(testManifest))
+	require.NoError(t, err)
+
+	plan, err := service.ApplyManifest(manifest, true)
+	require.NoError(t, err)
+	var changes []string
+	for _, change := range plan.Changes {
+		changes = append(changes, change.String())
+	}
+	assert.Equal(t, []string{
+		"create access report:read (Чтение отчетов)",
+		"create access report:export (Выгрузка отчетов)",
+		"create group analysts",
+		"create group support",
+		"create grant report:export -> analysts",
+		"create grant report:read -> analysts",
+		`create grant report:read -> support при условии cidr(request.ip, "10.0.0.0/8")`,
+		"create grant user:read -> support",
+	}, changes)
+	_, err = service.GetAccessByName("report:read")
+	assert.Error(t, err, "dry run не меняет базу")
+
+	_, err = service.ApplyManifest(manifest, false)
+	require.NoError(t, err)
+	plan, err = service.ApplyManifest(manifest, false)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+
+	analysts, err := service.CreateUser("ann@example.com", "password", "Ann", UserTypeUser)
+	require.NoError(t, err)
+	groups, err := service.GetAllGroups()
+	require.NoError(t, err)
+	for _, group := range groups {
+		if group.Name == "analysts" {
+			require.NoError(t, service.AssignUserToGroup(analysts.ID, group.ID))
+		}
+	}
+	allowed, err := service.CheckUserAccess(analysts.ID, "report:export")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Изменение манифеста: описание, условие, отзыв права у группы и удаление лишнего
+	manifest.Accesses[0].Description = "Просмотр отчетов"
+	manifest.Roles[0].Accesses = []string{"report:read"}
+	manifest.Groups[1].Conditions["report:read"] = `cidr(request.ip, "192.168.0.0/16")`
+	manifest.Prune = true
+	plan, err = service.ApplyManifest(manifest, false)
+	require.NoError(t, err)
+	changes = changes[:0]
+	for _, change := range plan.Changes {
+		changes = append(changes, change.String())
+	}
+	assert.Contains(t, changes, "update access report:read (Просмотр отчетов)")
+	assert.Contains(t, changes, "delete grant report:export -> analysts")
+	assert.Contains(t, changes, `update grant report:read -> support при условии cidr(request.ip, "192.168.0.0/16")`)
+	assert.Contains(t, changes, "delete access user:create")
+	allowed, err = service.CheckUserAccess(analysts.ID, "report:export")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	accesses, err := service.ListAccesses()
+	require.NoError(t, err)
+	assert.Len(t, accesses, 3)
+
+	plan, err = service.ApplyManifest(manifest, false)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+}
+
+func TestApplyManifestRestoresPruned(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	first := &Manifest{
+		Accesses: []ManifestAccess{{Name: "x:y", Description: "Право x:y"}},
+		Groups:   []ManifestGroup{{Name: "xy", Accesses: []string{"x:y"}}},
+	}
+	_, err := service.ApplyManifest(first, false)
+	require.NoError(t, err)
+	access, err := service.GetAccessByName("x:y")
+	require.NoError(t, err)
+	groups, err := service.GetAllGroups()
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	ann, err := service.CreateUser("ann@example.com", "password", "Ann", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AssignUserToGroup(ann.ID, groups[0].ID))
+
+	_, err = service.ApplyManifest(&Manifest{Accesses: []ManifestAccess{{Name: "user:read"}}, Prune: true}, false)
+	require.NoError(t, err)
+	_, err = service.GetAccessByName("x:y")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Удаленные право и группа восстанавливаются с прежними ID, без прежних участников
+	plan, err := service.ApplyManifest(first, false)
+	require.NoError(t, err)
+	var changes []string
+	for _, change := range plan.Changes {
+		changes = append(changes, change.String())
+	}
+	assert.Equal(t, []string{"restore access x:y (Право x:y)", "restore group xy", "create grant x:y -> xy"}, changes)
+	restored, err := service.GetAccessByName("x:y")
+	require.NoError(t, err)
+	assert.Equal(t, access.ID, restored.ID)
+	accesses, err := service.GetGroupAccessLevels(groups[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x:y"}, accesses)
+	members, err := service.GetGroupUsers(groups[0].ID)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	plan, err = service.ApplyManifest(first, false)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes)
+}
+
+func TestManifestValidation(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	_, err := ParseManifest([]byte("accesses:\n  - name: a\n    descripton: typo\n"))
+	assert.ErrorContains(t, err, "неверный манифест")
+
+	_, err = ParseManifest([]byte(`{"accesses": [{"name": "report:read"}], "groups": [{"name": "g", "accesses": ["report:read"]}]}`))
+	require.NoError(t, err, "JSON - тоже манифест")
+
+	for manifest, message := range map[*Manifest]string{
+		{Accesses: []ManifestAccess{{Name: "a"}, {Name: "a"}}}:                             `неверный манифест: право "a" описано дважды`,
+		{Groups: []ManifestGroup{{Name: "g", Roles: []string{"admin"}}}}:                   `неверный манифест: группа "g" ссылается на неизвестную роль "admin"`,
+		{Groups: []ManifestGroup{{Name: "g", Accesses: []string{"report:read"}}}}:          `неверный манифест: право "report:read" группы "g" не описано в accesses`,
+		{Groups: []ManifestGroup{{Name: "g", Conditions: map[string]string{"a": "x =="}}}}: `группа "g", право "a": неверное условие: неожиданный "конец выражения" в позиции 4`,
+	} {
+		_, err := service.ApplyManifest(manifest, true)
+		assert.EqualError(t, err, message)
+	}
+
+	// Ошибка применения откатывает все изменения
+	groups, err := service.GetAllGroups()
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+	service.AddBeforeHook(EventAccessGranted, func(ctx context.Context, event Event) error {
+		return errors.New("запрещено")
+	})
+	_, err = service.ApplyManifest(&Manifest{
+		Accesses: []ManifestAccess{{Name: "report:read"}},
+		Groups:   []ManifestGroup{{Name: "g", Accesses: []string{"report:read"}}},
+	}, false)
+	assert.EqualError(t, err, "create grant report:read -> g: запрещено")
+	groups, err = service.GetAllGroups()
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
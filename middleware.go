@@ -0,0 +1,229 @@
+package accessgo
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SessionCookieName - имя cookie с идентификатором сессии
+	SessionCookieName = "accessgo_session"
+	// CSRFCookieName - имя cookie с CSRF токеном, доступным JavaScript
+	CSRFCookieName = "accessgo_csrf"
+	// CSRFHeaderName - заголовок, в котором клиент возвращает CSRF токен
+	CSRFHeaderName = "X-CSRF-Token"
+	// CSRFFormField - поле формы с CSRF токеном для обычных HTML форм
+	CSRFFormField = "csrf_token"
+	// RequestIDHeaderName - заголовок с идентификатором запроса
+	RequestIDHeaderName = "X-Request-ID"
+)
+
+// SessionMiddlewareConfig настраивает RequireSessionWithConfig
+type SessionMiddlewareConfig struct {
+	// Sliding продлевает сессию при каждом запросе через ExtendSession
+	Sliding bool
+	// DisableCSRF отключает проверку CSRF токена для сессий из cookie
+	DisableCSRF bool
+}
+
+type sessionContextKey struct{}
+
+// RequireSession пропускает только запросы с действующей сессией из cookie
+// SessionCookieName или заголовка Authorization: Bearer. Сессия доступна
+// обработчику через SessionFromContext. Изменяющие запросы с сессией из cookie
+// должны передавать CSRF токен
+func RequireSession(ss *SessionService) func(http.Handler) http.Handler {
+	return RequireSessionWithConfig(ss, SessionMiddlewareConfig{})
+}
+
+// RequireSessionWithConfig - RequireSession с дополнительными настройками
+func RequireSessionWithConfig(ss *SessionService, cfg SessionMiddlewareConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sessionID, fromCookie := sessionIDFromRequest(r)
+			if sessionID == "" {
+				writeHTTPError(w, http.StatusUnauthorized, "требуется аутентификация")
+				return
+			}
+			session, err := ss.GetSession(sessionID)
+			if err != nil {
+				writeHTTPError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			if fromCookie && !cfg.DisableCSRF && !isSafeMethod(r.Method) && !validCSRFToken(r, sessionID) {
+				writeHTTPError(w, http.StatusForbidden, "неверный CSRF токен")
+				return
+			}
+
+			if cfg.Sliding {
+				if err := ss.ExtendSession(sessionID); err != nil {
+					writeHTTPError(w, http.StatusUnauthorized, err.Error())
+					return
+				}
+				if session, err = ss.GetSession(sessionID); err != nil {
+					writeHTTPError(w, http.StatusUnauthorized, err.Error())
+					return
+				}
+				if fromCookie {
+					SetSessionCookie(w, session)
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithSession(r.Context(), session)))
+		})
+	}
+}
+
+// RequirePermission пропускает только запросы пользователя с правом accessName.
+// Должен использоваться после RequireSession
+func RequirePermission(svc *AccessGoService, accessName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok {
+				writeHTTPError(w, http.StatusUnauthorized, "требуется аутентификация")
+				return
+			}
+			user, err := svc.GetUserByIDCtx(r.Context(), userID)
+			if errors.Is(err, ErrNotFound) {
+				writeHTTPError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			// Ошибки хранилища не раскрываются клиенту и не выдаются за отказ в доступе
+			if err != nil {
+				writeHTTPError(w, http.StatusInternalServerError, "внутренняя ошибка сервера")
+				return
+			}
+			if user.UserType == string(UserTypeBlocked) {
+				writeHTTPError(w, http.StatusForbidden, "пользователь заблокирован")
+				return
+			}
+			allowed, err := svc.CheckUserAccessCtx(r.Context(), userID, accessName)
+			if err != nil {
+				writeHTTPError(w, http.StatusInternalServerError, "внутренняя ошибка сервера")
+				return
+			}
+			if !allowed {
+				writeHTTPError(w, http.StatusForbidden, "требуется право доступа "+accessName)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestID добавляет в контекст идентификатор запроса из заголовка RequestIDHeaderName
+// или новый, если заголовок не задан, и возвращает его клиенту.
+// Идентификатор попадает в записи журнала аудита
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeaderName)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeaderName, requestID)
+		next.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), requestID)))
+	})
+}
+
+// ContextWithSession возвращает контекст с сессией
+func ContextWithSession(ctx context.Context, session Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext возвращает сессию, добавленную RequireSession
+func SessionFromContext(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(Session)
+	return session, ok
+}
+
+// UserIDFromContext возвращает ID пользователя сессии, добавленной RequireSession
+func UserIDFromContext(ctx context.Context) (uint, bool) {
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return uint(session.UserID), true
+}
+
+// SetSessionCookie устанавливает cookie сессии (HttpOnly, Secure, SameSite=Lax)
+// и cookie с CSRF токеном, который клиент передает в заголовке CSRFHeaderName
+func SetSessionCookie(w http.ResponseWriter, session Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    session.ID,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    CSRFToken(session.ID),
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie удаляет cookie сессии и CSRF токена
+func ClearSessionCookie(w http.ResponseWriter) {
+	for _, name := range []string{SessionCookieName, CSRFCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: name == SessionCookieName,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// CSRFToken возвращает CSRF токен сессии. Токен выводится из идентификатора
+// сессии, поэтому не требует хранения и не может быть подставлен другим сайтом
+func CSRFToken(sessionID string) string {
+	return hashToken("csrf:" + sessionID)
+}
+
+func sessionIDFromRequest(r *http.Request) (string, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token, false
+	}
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		return cookie.Value, true
+	}
+	return "", false
+}
+
+func validCSRFToken(r *http.Request, sessionID string) bool {
+	token := r.Header.Get(CSRFHeaderName)
+	if token == "" {
+		token = r.PostFormValue(CSRFFormField)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(CSRFToken(sessionID))) == 1
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
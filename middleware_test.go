@@ -0,0 +1,127 @@
+package accessgo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireSessionAndPermission(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	ss := NewSessionService(context.Background())
+	defer ss.Stop()
+
+	reader, err := service.CreateUser("reader@example.com", "password", "Reader", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(reader.ID, "user:read"))
+	sessionID, err := ss.CreateSession(int(reader.ID), false)
+	require.NoError(t, err)
+
+	var seenUserID uint
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenUserID, _ = UserIDFromContext(r.Context())
+	})
+	session := RequireSession(ss)
+	readUsers := session(RequirePermission(service, "user:read")(ok))
+	deleteUsers := session(RequirePermission(service, "user:delete")(ok))
+
+	serve := func(h http.Handler, req *http.Request) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	assert.Equal(t, http.StatusUnauthorized, serve(readUsers, req))
+
+	req = httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	assert.Equal(t, http.StatusOK, serve(readUsers, req))
+	assert.Equal(t, reader.ID, seenUserID)
+
+	req = httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	assert.Equal(t, http.StatusForbidden, serve(deleteUsers, req))
+
+	// Заблокированный пользователь теряет доступ в рамках существующей сессии
+	_, err = service.UpdateUser(reader.ID, reader.Email, "", reader.Name, UserTypeBlocked)
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	assert.Equal(t, http.StatusForbidden, serve(readUsers, req))
+}
+
+func TestRequirePermissionHidesStorageErrors(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+	ss := NewSessionService(context.Background())
+	defer ss.Stop()
+
+	reader, err := service.CreateUser("reader@example.com", "password", "Reader", UserTypeUser)
+	require.NoError(t, err)
+	sessionID, err := ss.CreateSession(int(reader.ID), false)
+	require.NoError(t, err)
+	handler := RequireSession(ss)(RequirePermission(service, "user:read")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	// Сбой проверки права - ошибка сервера, а не отказ в доступе с текстом ошибки БД
+	require.NoError(t, db.Migrator().DropTable(&AccessLevel{}))
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "access_levels")
+}
+
+func TestSessionCookieCSRFAndSliding(t *testing.T) {
+	ss := NewSessionService(context.Background())
+	defer ss.Stop()
+
+	sessionID, err := ss.CreateSession(1, false)
+	require.NoError(t, err)
+	session, err := ss.GetSession(sessionID)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	SetSessionCookie(rec, session)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 2)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	assert.False(t, cookies[1].HttpOnly)
+
+	handler := RequireSessionWithConfig(ss, SessionMiddlewareConfig{Sliding: true})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func(method, csrf string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		if csrf != "" {
+			req.Header.Set(CSRFHeaderName, csrf)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Чтение не требует CSRF токена и продлевает cookie
+	rec = request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Result().Cookies())
+
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, CSRFToken("other")).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, cookies[1].Value).Code)
+
+	rec = httptest.NewRecorder()
+	ClearSessionCookie(rec)
+	for _, c := range rec.Result().Cookies() {
+		assert.Equal(t, -1, c.MaxAge)
+	}
+}
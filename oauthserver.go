@@ -0,0 +1,642 @@
+package accessgo
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuthServerConfig описывает параметры сервера авторизации OAuth2
+type OAuthServerConfig struct {
+	AccessTokenTTL  time.Duration // время жизни access токена, по умолчанию 1 час
+	RefreshTokenTTL time.Duration // время жизни refresh токена, по умолчанию 30 дней
+	CodeTTL         time.Duration // время жизни кода авторизации, по умолчанию 1 минута
+	// CurrentUser определяет вошедшего пользователя по запросу к /authorize
+	CurrentUser func(r *http.Request) (uint, error)
+	// LoginURL - страница входа, на которую перенаправляется неаутентифицированный пользователь.
+	// Адрес исходного запроса передается в параметре return_to
+	LoginURL string
+	// ConsentURL - страница согласия, на которую перенаправляется пользователь, еще не
+	// разрешивший клиенту запрошенные scope. Передаются параметры return_to, client_id
+	// и scope; после согласия страница вызывает GrantConsent и возвращает пользователя
+	// на return_to. Без ConsentURL недоверенные клиенты получают access_denied.
+	// Для доверенных клиентов (SetClientTrusted) согласие не запрашивается
+	ConsentURL string
+}
+
+// OAuthServer реализует сервер авторизации OAuth2 поверх AccessGoService.
+// Scope токенов соответствуют именам прав доступа (Access)
+type OAuthServer struct {
+	svc *AccessGoService
+	cfg OAuthServerConfig
+}
+
+// OAuthTokenResponse представляет ответ token endpoint
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	status      int
+}
+
+// NewOAuthServer создает новый экземпляр OAuthServer
+func NewOAuthServer(svc *AccessGoService, cfg OAuthServerConfig) (*OAuthServer, error) {
+	if err := svc.db.AutoMigrate(&OAuthClient{}, &OAuthAuthorizationCode{}, &OAuthToken{}, &OAuthConsent{}); err != nil {
+		return nil, err
+	}
+	if cfg.AccessTokenTTL == 0 {
+		cfg.AccessTokenTTL = time.Hour
+	}
+	if cfg.RefreshTokenTTL == 0 {
+		cfg.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if cfg.CodeTTL == 0 {
+		cfg.CodeTTL = time.Minute
+	}
+	return &OAuthServer{svc: svc, cfg: cfg}, nil
+}
+
+// RegisterClient регистрирует клиентское приложение.
+// Для конфиденциальных клиентов возвращается секрет (показывается один раз).
+// serviceUserID задает пользователя, от имени которого работает grant client_credentials
+func (o *OAuthServer) RegisterClient(name string, redirectURIs []string, confidential bool, serviceUserID *uint, scopes ...string) (*OAuthClient, string, error) {
+	for _, redirectURI := range redirectURIs {
+		if strings.TrimSpace(redirectURI) == "" {
+			return nil, "", errors.New("redirect URI не может быть пустым")
+		}
+	}
+	var accesses []Access
+	if len(scopes) > 0 {
+		if err := o.svc.db.Where("name IN ?", scopes).Find(&accesses).Error; err != nil {
+			return nil, "", err
+		}
+		if len(accesses) != len(scopes) {
+			return nil, "", errors.New("одно или несколько прав доступа не найдены")
+		}
+	}
+	if serviceUserID != nil {
+		if !confidential {
+			return nil, "", errors.New("сервисный пользователь доступен только конфиденциальным клиентам")
+		}
+		if _, err := o.svc.GetUserByID(*serviceUserID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	clientID, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &OAuthClient{
+		ClientID:      clientID,
+		Name:          name,
+		RedirectURIs:  strings.Join(redirectURIs, "\n"),
+		Confidential:  confidential,
+		ServiceUserID: serviceUserID,
+		Scopes:        accesses,
+	}
+
+	var secret string
+	if confidential {
+		if secret, err = randomToken(32); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := o.svc.db.Create(client).Error; err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// SetClientTrusted помечает клиента доверенным (собственным приложением) или снимает
+// отметку. Доверенный клиент получает код авторизации без согласия пользователя
+func (o *OAuthServer) SetClientTrusted(clientID string, trusted bool) error {
+	result := o.svc.db.Model(&OAuthClient{}).Where("client_id = ?", clientID).Update("trusted", trusted)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("клиент не найден")
+	}
+	return nil
+}
+
+// GrantConsent сохраняет согласие пользователя на выдачу клиенту токенов с scope.
+// Scope добавляются к ранее разрешенным
+func (o *OAuthServer) GrantConsent(userID uint, clientID string, scopes []string) error {
+	if _, err := o.findClient(clientID); err != nil {
+		return err
+	}
+	if _, err := o.svc.GetUserByID(userID); err != nil {
+		return err
+	}
+	return o.svc.db.Transaction(func(tx *gorm.DB) error {
+		var consent OAuthConsent
+		err := tx.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		granted := strings.Fields(consent.Scopes)
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				granted = append(granted, scope)
+			}
+		}
+		consent.UserID = userID
+		consent.ClientID = clientID
+		consent.Scopes = strings.Join(granted, " ")
+		return tx.Save(&consent).Error
+	})
+}
+
+// RevokeConsent отзывает согласие пользователя и все токены, выданные клиенту от его имени
+func (o *OAuthServer) RevokeConsent(userID uint, clientID string) error {
+	return o.svc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&OAuthConsent{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&OAuthToken{}).Error
+	})
+}
+
+// ListClients возвращает список зарегистрированных клиентов
+func (o *OAuthServer) ListClients() ([]OAuthClient, error) {
+	var clients []OAuthClient
+	if err := o.svc.db.Preload("Scopes").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// DeleteClient удаляет клиента и отзывает все выданные ему токены
+func (o *OAuthServer) DeleteClient(clientID string) error {
+	return o.svc.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("client_id = ?", clientID).Delete(&OAuthClient{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("клиент не найден")
+		}
+		if err := tx.Where("client_id = ?", clientID).Delete(&OAuthAuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("client_id = ?", clientID).Delete(&OAuthConsent{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("client_id = ?", clientID).Delete(&OAuthToken{}).Error
+	})
+}
+
+// ValidateAccessToken проверяет access токен и возвращает пользователя и scope токена.
+// Scope пересекаются с текущими правами пользователя
+func (o *OAuthServer) ValidateAccessToken(accessToken string) (*User, []string, error) {
+	var token OAuthToken
+	if err := o.svc.db.Where("access_token_hash = ?", hashToken(accessToken)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("токен не найден")
+		}
+		return nil, nil, err
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, nil, errors.New("срок действия токена истек")
+	}
+
+	user, err := o.svc.GetUserByID(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.UserType == string(UserTypeBlocked) {
+		return nil, nil, errors.New("пользователь заблокирован")
+	}
+
+	userAccesses, err := o.svc.GetUserSummaryAccessLevels(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	scopes := make([]string, 0)
+	for _, scope := range strings.Fields(token.Scopes) {
+		if slices.Contains(userAccesses, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return user, scopes, nil
+}
+
+// Handler возвращает обработчик со всеми endpoint: /authorize, /token, /revoke
+func (o *OAuthServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/authorize", o.AuthorizeHandler())
+	mux.Handle("/token", o.TokenHandler())
+	mux.Handle("/revoke", o.RevokeHandler())
+	return mux
+}
+
+// AuthorizeHandler возвращает обработчик authorization endpoint (authorization code + PKCE)
+func (o *OAuthServer) AuthorizeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		client, err := o.findClient(query.Get("client_id"))
+		if err != nil {
+			http.Error(w, "invalid client_id", http.StatusBadRequest)
+			return
+		}
+		redirectURI := query.Get("redirect_uri")
+		if redirectURI == "" || !slices.Contains(strings.Split(client.RedirectURIs, "\n"), redirectURI) {
+			http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+			return
+		}
+
+		state := query.Get("state")
+		if query.Get("response_type") != "code" {
+			redirectOAuthError(w, r, redirectURI, state, &oauthError{Code: "unsupported_response_type"})
+			return
+		}
+
+		challenge := query.Get("code_challenge")
+		method := query.Get("code_challenge_method")
+		if challenge == "" && !client.Confidential {
+			redirectOAuthError(w, r, redirectURI, state, &oauthError{Code: "invalid_request", Description: "code_challenge required"})
+			return
+		}
+		if challenge != "" && method != "S256" {
+			redirectOAuthError(w, r, redirectURI, state, &oauthError{Code: "invalid_request", Description: "only S256 code_challenge_method is supported"})
+			return
+		}
+
+		var userID uint
+		if o.cfg.CurrentUser != nil {
+			userID, err = o.cfg.CurrentUser(r)
+		}
+		if o.cfg.CurrentUser == nil || err != nil || userID == 0 {
+			if o.cfg.LoginURL == "" {
+				http.Error(w, "login required", http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, o.cfg.LoginURL+"?return_to="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+			return
+		}
+
+		scopes, oerr := o.grantedScopes(client, userID, query.Get("scope"))
+		if oerr != nil {
+			redirectOAuthError(w, r, redirectURI, state, oerr)
+			return
+		}
+		if !client.Trusted {
+			consented, err := o.hasConsent(userID, client.ClientID, scopes)
+			if err != nil {
+				redirectOAuthError(w, r, redirectURI, state, &oauthError{Code: "server_error"})
+				return
+			}
+			if !consented {
+				if o.cfg.ConsentURL == "" {
+					redirectOAuthError(w, r, redirectURI, state, &oauthError{Code: "access_denied", Description: "consent required"})
+					return
+				}
+				params := url.Values{
+					"return_to": {r.URL.RequestURI()},
+					"client_id": {client.ClientID},
+					"scope":     {strings.Join(scopes, " ")},
+				}
+				http.Redirect(w, r, appendQuery(o.cfg.ConsentURL, params), http.StatusFound)
+				return
+			}
+		}
+
+		code, err := randomToken(32)
+		if err != nil {
+			redirectOAuthError(w, r, redirectURI, state, &oauthError{Code: "server_error"})
+			return
+		}
+		err = o.svc.db.Create(&OAuthAuthorizationCode{
+			CodeHash:            hashToken(code),
+			ClientID:            client.ClientID,
+			UserID:              userID,
+			RedirectURI:         redirectURI,
+			Scopes:              strings.Join(scopes, " "),
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: method,
+			ExpiresAt:           time.Now().Add(o.cfg.CodeTTL),
+		}).Error
+		if err != nil {
+			redirectOAuthError(w, r, redirectURI, state, &oauthError{Code: "server_error"})
+			return
+		}
+
+		params := url.Values{"code": {code}}
+		if state != "" {
+			params.Set("state", state)
+		}
+		http.Redirect(w, r, appendQuery(redirectURI, params), http.StatusFound)
+	})
+}
+
+// TokenHandler возвращает обработчик token endpoint
+// (grant authorization_code, client_credentials и refresh_token)
+func (o *OAuthServer) TokenHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeOAuthError(w, &oauthError{Code: "invalid_request", Description: "POST required", status: http.StatusMethodNotAllowed})
+			return
+		}
+
+		client, oerr := o.authenticateClient(r)
+		if oerr != nil {
+			writeOAuthError(w, oerr)
+			return
+		}
+
+		var response *OAuthTokenResponse
+		switch r.PostFormValue("grant_type") {
+		case "authorization_code":
+			response, oerr = o.exchangeCode(client, r.PostFormValue("code"), r.PostFormValue("redirect_uri"), r.PostFormValue("code_verifier"))
+		case "client_credentials":
+			response, oerr = o.clientCredentials(client, r.PostFormValue("scope"))
+		case "refresh_token":
+			response, oerr = o.refresh(client, r.PostFormValue("refresh_token"), r.PostFormValue("scope"))
+		default:
+			oerr = &oauthError{Code: "unsupported_grant_type"}
+		}
+		if oerr != nil {
+			writeOAuthError(w, oerr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
+
+// RevokeHandler возвращает обработчик отзыва токенов (RFC 7009)
+func (o *OAuthServer) RevokeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeOAuthError(w, &oauthError{Code: "invalid_request", Description: "POST required", status: http.StatusMethodNotAllowed})
+			return
+		}
+		client, oerr := o.authenticateClient(r)
+		if oerr != nil {
+			writeOAuthError(w, oerr)
+			return
+		}
+		hash := hashToken(r.PostFormValue("token"))
+		err := o.svc.db.Where("client_id = ? AND (access_token_hash = ? OR refresh_token_hash = ?)", client.ClientID, hash, hash).
+			Delete(&OAuthToken{}).Error
+		if err != nil {
+			writeOAuthError(w, &oauthError{Code: "server_error", status: http.StatusInternalServerError})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (o *OAuthServer) exchangeCode(client *OAuthClient, code, redirectURI, verifier string) (*OAuthTokenResponse, *oauthError) {
+	var authCode OAuthAuthorizationCode
+	codeHash := hashToken(code)
+	err := o.svc.db.Unscoped().Where("code_hash = ?", codeHash).First(&authCode).Error
+	if err != nil {
+		return nil, &oauthError{Code: "invalid_grant", Description: "unknown code"}
+	}
+	// Код одноразовый: удаляем его до проверок. Повторное предъявление кода
+	// означает, что он мог быть перехвачен, поэтому выданные по нему токены
+	// отзываются (RFC 6749, раздел 4.1.2)
+	used := authCode.DeletedAt.Valid
+	result := o.svc.db.Delete(&authCode)
+	if used || (result.Error == nil && result.RowsAffected == 0) {
+		if err := o.svc.db.Where("code_hash = ?", codeHash).Delete(&OAuthToken{}).Error; err != nil {
+			return nil, &oauthError{Code: "server_error", status: http.StatusInternalServerError}
+		}
+		return nil, &oauthError{Code: "invalid_grant", Description: "code already used"}
+	}
+	if result.Error != nil {
+		return nil, &oauthError{Code: "server_error", status: http.StatusInternalServerError}
+	}
+
+	if authCode.ClientID != client.ClientID || authCode.RedirectURI != redirectURI {
+		return nil, &oauthError{Code: "invalid_grant", Description: "code was issued to another client or redirect_uri"}
+	}
+	if time.Now().After(authCode.ExpiresAt) {
+		return nil, &oauthError{Code: "invalid_grant", Description: "code expired"}
+	}
+	if authCode.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(verifier))
+		expected := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(authCode.CodeChallenge)) != 1 {
+			return nil, &oauthError{Code: "invalid_grant", Description: "invalid code_verifier"}
+		}
+	}
+
+	return o.issueToken(client.ClientID, authCode.UserID, strings.Fields(authCode.Scopes), codeHash, true)
+}
+
+func (o *OAuthServer) clientCredentials(client *OAuthClient, scope string) (*OAuthTokenResponse, *oauthError) {
+	if !client.Confidential || client.ServiceUserID == nil {
+		return nil, &oauthError{Code: "unauthorized_client", Description: "client_credentials grant is not allowed for this client"}
+	}
+	scopes, oerr := o.grantedScopes(client, *client.ServiceUserID, scope)
+	if oerr != nil {
+		return nil, oerr
+	}
+	return o.issueToken(client.ClientID, *client.ServiceUserID, scopes, "", false)
+}
+
+func (o *OAuthServer) refresh(client *OAuthClient, refreshToken, scope string) (*OAuthTokenResponse, *oauthError) {
+	var token OAuthToken
+	err := o.svc.db.Where("refresh_token_hash = ? AND client_id = ?", hashToken(refreshToken), client.ClientID).First(&token).Error
+	if err != nil {
+		return nil, &oauthError{Code: "invalid_grant", Description: "unknown refresh_token"}
+	}
+	if token.RefreshExpiresAt == nil || time.Now().After(*token.RefreshExpiresAt) {
+		return nil, &oauthError{Code: "invalid_grant", Description: "refresh_token expired"}
+	}
+
+	scopes := strings.Fields(token.Scopes)
+	if scope != "" {
+		requested := strings.Fields(scope)
+		for _, s := range requested {
+			if !slices.Contains(scopes, s) {
+				return nil, &oauthError{Code: "invalid_scope", Description: "scope exceeds the original grant"}
+			}
+		}
+		scopes = requested
+	}
+
+	// Refresh токен одноразовый: при обновлении выдается новая пара
+	result := o.svc.db.Delete(&token)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, &oauthError{Code: "invalid_grant", Description: "unknown refresh_token"}
+	}
+	return o.issueToken(client.ClientID, token.UserID, scopes, token.CodeHash, true)
+}
+
+// issueToken выдает токен. codeHash - хеш кода авторизации, по которому выдан
+// токен: при повторном предъявлении кода такие токены отзываются
+func (o *OAuthServer) issueToken(clientID string, userID uint, scopes []string, codeHash string, withRefresh bool) (*OAuthTokenResponse, *oauthError) {
+	accessToken, err := randomToken(32)
+	if err != nil {
+		return nil, &oauthError{Code: "server_error", status: http.StatusInternalServerError}
+	}
+
+	token := OAuthToken{
+		AccessTokenHash: hashToken(accessToken),
+		ClientID:        clientID,
+		UserID:          userID,
+		Scopes:          strings.Join(scopes, " "),
+		CodeHash:        codeHash,
+		ExpiresAt:       time.Now().Add(o.cfg.AccessTokenTTL),
+	}
+	response := &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(o.cfg.AccessTokenTTL.Seconds()),
+		Scope:       token.Scopes,
+	}
+
+	if withRefresh {
+		refreshToken, err := randomToken(32)
+		if err != nil {
+			return nil, &oauthError{Code: "server_error", status: http.StatusInternalServerError}
+		}
+		refreshExpiresAt := time.Now().Add(o.cfg.RefreshTokenTTL)
+		token.RefreshTokenHash = hashToken(refreshToken)
+		token.RefreshExpiresAt = &refreshExpiresAt
+		response.RefreshToken = refreshToken
+	}
+
+	if err := o.svc.db.Create(&token).Error; err != nil {
+		return nil, &oauthError{Code: "server_error", status: http.StatusInternalServerError}
+	}
+	return response, nil
+}
+
+// grantedScopes вычисляет scope токена: запрошенные (или все разрешенные клиенту)
+// scope, ограниченные правами пользователя
+func (o *OAuthServer) grantedScopes(client *OAuthClient, userID uint, scope string) ([]string, *oauthError) {
+	allowed := make([]string, 0, len(client.Scopes))
+	for _, access := range client.Scopes {
+		allowed = append(allowed, access.Name)
+	}
+
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		requested = allowed
+	}
+	for _, s := range requested {
+		if !slices.Contains(allowed, s) {
+			return nil, &oauthError{Code: "invalid_scope", Description: "scope " + s + " is not allowed for this client"}
+		}
+	}
+
+	userAccesses, err := o.svc.GetUserSummaryAccessLevels(userID)
+	if err != nil {
+		return nil, &oauthError{Code: "access_denied", Description: "user not found"}
+	}
+	granted := make([]string, 0, len(requested))
+	for _, s := range requested {
+		if slices.Contains(userAccesses, s) {
+			granted = append(granted, s)
+		}
+	}
+	return granted, nil
+}
+
+// hasConsent проверяет, что пользователь разрешил клиенту все scope
+func (o *OAuthServer) hasConsent(userID uint, clientID string, scopes []string) (bool, error) {
+	var consent OAuthConsent
+	err := o.svc.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	granted := strings.Fields(consent.Scopes)
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (o *OAuthServer) authenticateClient(r *http.Request) (*OAuthClient, *oauthError) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	invalid := &oauthError{Code: "invalid_client", status: http.StatusUnauthorized}
+	client, err := o.findClient(clientID)
+	if err != nil {
+		return nil, invalid
+	}
+	if client.Confidential && subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(hashToken(secret))) != 1 {
+		return nil, invalid
+	}
+	return client, nil
+}
+
+func (o *OAuthServer) findClient(clientID string) (*OAuthClient, error) {
+	if clientID == "" {
+		return nil, errors.New("клиент не найден")
+	}
+	var client OAuthClient
+	if err := o.svc.db.Preload("Scopes").Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, errors.New("клиент не найден")
+	}
+	return &client, nil
+}
+
+func writeOAuthError(w http.ResponseWriter, e *oauthError) {
+	status := e.status
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+func redirectOAuthError(w http.ResponseWriter, r *http.Request, redirectURI, state string, e *oauthError) {
+	params := url.Values{"error": {e.Code}}
+	if e.Description != "" {
+		params.Set("error_description", e.Description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	http.Redirect(w, r, appendQuery(redirectURI, params), http.StatusFound)
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	if strings.Contains(rawURL, "?") {
+		return rawURL + "&" + params.Encode()
+	}
+	return rawURL + "?" + params.Encode()
+}
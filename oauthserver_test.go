@@ -0,0 +1,301 @@
+package accessgo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+const testRedirectURI = "https://partner.example.com/callback"
+
+func setupOAuthServer(t *testing.T) (*AccessGoService, *OAuthServer, *httptest.Server) {
+	service := newTestService(t, setupTestDB(t))
+	server, err := NewOAuthServer(service, OAuthServerConfig{
+		// В тестах пользователь передается заголовком вместо сессии
+		CurrentUser: func(r *http.Request) (uint, error) {
+			id, err := strconv.ParseUint(r.Header.Get("X-Test-User"), 10, 64)
+			if err != nil {
+				return 0, errors.New("не аутентифицирован")
+			}
+			return uint(id), nil
+		},
+		LoginURL:   "/login",
+		ConsentURL: "/consent",
+	})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return service, server, ts
+}
+
+func authorizeTestRequest(t *testing.T, ts *httptest.Server, userID uint, params url.Values) *url.URL {
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/authorize?"+params.Encode(), nil)
+	require.NoError(t, err)
+	if userID != 0 {
+		req.Header.Set("X-Test-User", strconv.FormatUint(uint64(userID), 10))
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location
+}
+
+func postTokenRequest(t *testing.T, ts *httptest.Server, form url.Values) (int, map[string]interface{}) {
+	resp, err := http.PostForm(ts.URL+"/token", form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestOAuthAuthorizationCodeWithPKCE(t *testing.T) {
+	service, server, ts := setupOAuthServer(t)
+
+	user, err := service.CreateUser("owner@example.com", "password", "Owner", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "user:read"))
+
+	client, secret, err := server.RegisterClient("Partner", []string{testRedirectURI}, false, nil, "user:read", "user:delete")
+	require.NoError(t, err)
+	assert.Empty(t, secret)
+
+	verifier := oauth2.GenerateVerifier()
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {"user:read user:delete"},
+		"state":                 {"xyz"},
+		"code_challenge":        {oauth2.S256ChallengeFromVerifier(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	// Неаутентифицированный пользователь отправляется на страницу входа
+	location := authorizeTestRequest(t, ts, 0, params)
+	assert.Equal(t, "/login", location.Path)
+
+	// Пользователь еще не дал согласия: перенаправление на страницу согласия
+	location = authorizeTestRequest(t, ts, user.ID, params)
+	assert.Equal(t, "/consent", location.Path)
+	assert.Equal(t, client.ClientID, location.Query().Get("client_id"))
+	assert.Equal(t, "user:read", location.Query().Get("scope"))
+	require.NoError(t, server.GrantConsent(user.ID, client.ClientID, []string{"user:read"}))
+
+	location = authorizeTestRequest(t, ts, user.ID, params)
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	// Неверный verifier
+	status, body := postTokenRequest(t, ts, url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {client.ClientID},
+		"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {"wrong"},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	// Код был израсходован неудачной попыткой, получаем новый
+	location = authorizeTestRequest(t, ts, user.ID, params)
+	code = location.Query().Get("code")
+
+	status, body = postTokenRequest(t, ts, url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {client.ClientID},
+		"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {verifier},
+	})
+	require.Equal(t, http.StatusOK, status)
+	// user:delete отсутствует у пользователя и не попадает в токен
+	assert.Equal(t, "user:read", body["scope"])
+
+	tokenUser, scopes, err := server.ValidateAccessToken(body["access_token"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, tokenUser.ID)
+	assert.Equal(t, []string{"user:read"}, scopes)
+
+	// Обновление токена выдает новую пару и делает старый refresh токен недействительным
+	refreshToken := body["refresh_token"].(string)
+	status, refreshed := postTokenRequest(t, ts, url.Values{
+		"grant_type": {"refresh_token"}, "client_id": {client.ClientID}, "refresh_token": {refreshToken},
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEqual(t, refreshToken, refreshed["refresh_token"])
+
+	status, _ = postTokenRequest(t, ts, url.Values{
+		"grant_type": {"refresh_token"}, "client_id": {client.ClientID}, "refresh_token": {refreshToken},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Повторный обмен кода запрещен и отзывает токены, выданные по нему, включая обновленные
+	status, body = postTokenRequest(t, ts, url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {client.ClientID},
+		"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {verifier},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+	_, _, err = server.ValidateAccessToken(refreshed["access_token"].(string))
+	assert.Error(t, err)
+	status, _ = postTokenRequest(t, ts, url.Values{
+		"grant_type": {"refresh_token"}, "client_id": {client.ClientID}, "refresh_token": {refreshed["refresh_token"].(string)},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestOAuthConsent(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	server, err := NewOAuthServer(service, OAuthServerConfig{
+		CurrentUser: func(r *http.Request) (uint, error) {
+			id, err := strconv.ParseUint(r.Header.Get("X-Test-User"), 10, 64)
+			return uint(id), err
+		},
+	})
+	require.NoError(t, err)
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	user, err := service.CreateUser("owner@example.com", "password", "Owner", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "user:read"))
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "group:read"))
+	client, secret, err := server.RegisterClient("Portal", []string{testRedirectURI}, true, nil, "user:read", "group:read")
+	require.NoError(t, err)
+
+	params := url.Values{
+		"response_type": {"code"}, "client_id": {client.ClientID}, "redirect_uri": {testRedirectURI}, "scope": {"user:read"},
+	}
+	// Без страницы согласия недоверенный клиент получает отказ
+	location := authorizeTestRequest(t, ts, user.ID, params)
+	assert.Equal(t, "access_denied", location.Query().Get("error"))
+
+	// Согласие покрывает только разрешенные scope
+	require.NoError(t, server.GrantConsent(user.ID, client.ClientID, []string{"user:read"}))
+	location = authorizeTestRequest(t, ts, user.ID, params)
+	require.NotEmpty(t, location.Query().Get("code"))
+	params.Set("scope", "user:read group:read")
+	location = authorizeTestRequest(t, ts, user.ID, params)
+	assert.Equal(t, "access_denied", location.Query().Get("error"))
+
+	// Отзыв согласия отзывает и выданные токены
+	status, body := postTokenRequest(t, ts, url.Values{
+		"grant_type": {"authorization_code"}, "client_id": {client.ClientID}, "client_secret": {secret},
+		"code": {authorizeTestRequest(t, ts, user.ID, url.Values{
+			"response_type": {"code"}, "client_id": {client.ClientID}, "redirect_uri": {testRedirectURI}, "scope": {"user:read"},
+		}).Query().Get("code")}, "redirect_uri": {testRedirectURI},
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.NoError(t, server.RevokeConsent(user.ID, client.ClientID))
+	_, _, err = server.ValidateAccessToken(body["access_token"].(string))
+	assert.Error(t, err)
+	location = authorizeTestRequest(t, ts, user.ID, params)
+	assert.Equal(t, "access_denied", location.Query().Get("error"))
+
+	// Доверенный клиент не запрашивает согласия
+	require.NoError(t, server.SetClientTrusted(client.ClientID, true))
+	location = authorizeTestRequest(t, ts, user.ID, params)
+	assert.NotEmpty(t, location.Query().Get("code"))
+	assert.EqualError(t, server.SetClientTrusted("unknown", true), "клиент не найден")
+}
+
+func TestOAuthAuthorizeValidation(t *testing.T) {
+	service, server, ts := setupOAuthServer(t)
+
+	user, err := service.CreateUser("owner@example.com", "password", "Owner", UserTypeUser)
+	require.NoError(t, err)
+	client, _, err := server.RegisterClient("Partner", []string{testRedirectURI}, false, nil, "user:read")
+	require.NoError(t, err)
+
+	// Публичный клиент обязан использовать PKCE
+	location := authorizeTestRequest(t, ts, user.ID, url.Values{
+		"response_type": {"code"}, "client_id": {client.ClientID}, "redirect_uri": {testRedirectURI},
+	})
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+
+	// Scope, не разрешенный клиенту
+	location = authorizeTestRequest(t, ts, user.ID, url.Values{
+		"response_type": {"code"}, "client_id": {client.ClientID}, "redirect_uri": {testRedirectURI},
+		"scope": {"group:delete"}, "code_challenge": {oauth2.S256ChallengeFromVerifier(oauth2.GenerateVerifier())},
+		"code_challenge_method": {"S256"},
+	})
+	assert.Equal(t, "invalid_scope", location.Query().Get("error"))
+
+	// Незарегистрированный или пустой redirect_uri не используется для перенаправления
+	for _, redirectURI := range []string{"https://evil.example.com/", ""} {
+		resp, err := http.Get(ts.URL + "/authorize?" + url.Values{
+			"response_type": {"code"}, "client_id": {client.ClientID}, "redirect_uri": {redirectURI},
+		}.Encode())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, redirectURI)
+	}
+
+	// Клиент без redirect URI не принимает пустой redirect_uri
+	backend, _, err := server.RegisterClient("Backend", nil, true, nil)
+	require.NoError(t, err)
+	resp, err := http.Get(ts.URL + "/authorize?" + url.Values{
+		"response_type": {"code"}, "client_id": {backend.ClientID}, "redirect_uri": {""},
+	}.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, _, err = server.RegisterClient("Broken", []string{testRedirectURI, ""}, false, nil)
+	assert.EqualError(t, err, "redirect URI не может быть пустым")
+}
+
+func TestOAuthClientCredentialsAndRevoke(t *testing.T) {
+	service, server, ts := setupOAuthServer(t)
+
+	account, err := service.CreateServiceAccount("partner@service.local", "Partner")
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(account.ID, "group:read"))
+
+	client, secret, err := server.RegisterClient("Partner backend", nil, true, &account.ID, "group:read")
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+
+	// Неверный секрет
+	status, body := postTokenRequest(t, ts, url.Values{
+		"grant_type": {"client_credentials"}, "client_id": {client.ClientID}, "client_secret": {"wrong"},
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	status, body = postTokenRequest(t, ts, url.Values{
+		"grant_type": {"client_credentials"}, "client_id": {client.ClientID}, "client_secret": {secret},
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Nil(t, body["refresh_token"])
+
+	accessToken := body["access_token"].(string)
+	tokenUser, scopes, err := server.ValidateAccessToken(accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, tokenUser.ID)
+	assert.Equal(t, []string{"group:read"}, scopes)
+
+	resp, err := http.PostForm(ts.URL+"/revoke", url.Values{
+		"client_id": {client.ClientID}, "client_secret": {secret}, "token": {accessToken},
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, _, err = server.ValidateAccessToken(accessToken)
+	assert.Error(t, err)
+
+	// Удаление клиента
+	require.NoError(t, server.DeleteClient(client.ClientID))
+	clients, err := server.ListClients()
+	require.NoError(t, err)
+	assert.Empty(t, clients)
+}
@@ -0,0 +1,318 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// OIDCProviderConfig описывает внешний провайдер идентификации OpenID Connect
+type OIDCProviderConfig struct {
+	Name            string // идентификатор провайдера, например "google"
+	IssuerURL       string // адрес издателя, по нему загружается discovery документ
+	ClientID        string
+	ClientSecret    string
+	RedirectURL     string
+	Scopes          []string // дополнительные scope помимо openid, email, profile
+	AutoProvision   bool     // создавать пользователя, если он не найден
+	DefaultUserType UserType // тип создаваемых пользователей, по умолчанию UserTypeUser
+	DefaultGroupIDs []uint   // группы создаваемых пользователей
+}
+
+// OIDCService реализует вход через внешних OIDC провайдеров и привязку учетных записей
+type OIDCService struct {
+	svc       *AccessGoService
+	timeout   time.Duration
+	mu        sync.RWMutex
+	providers map[string]*oidcProvider
+	flows     sync.Map
+}
+
+type oidcProvider struct {
+	config   OIDCProviderConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+type oidcFlow struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	LinkUserID   uint
+	ExpiresAt    time.Time
+}
+
+type oidcClaims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// NewOIDCService создает новый экземпляр OIDCService
+func NewOIDCService(svc *AccessGoService) (*OIDCService, error) {
+	if err := svc.db.AutoMigrate(&ExternalIdentity{}); err != nil {
+		return nil, err
+	}
+	return &OIDCService{
+		svc:       svc,
+		timeout:   10 * time.Minute,
+		providers: make(map[string]*oidcProvider),
+	}, nil
+}
+
+// RegisterProvider регистрирует провайдера, загружая его discovery документ
+func (o *OIDCService) RegisterProvider(ctx context.Context, cfg OIDCProviderConfig) error {
+	if cfg.Name == "" {
+		return errors.New("имя провайдера обязательно")
+	}
+	if cfg.DefaultUserType == "" {
+		cfg.DefaultUserType = UserTypeUser
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.providers[cfg.Name] = &oidcProvider{
+		config: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID, "email", "profile"}, cfg.Scopes...),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}
+	return nil
+}
+
+// BeginLogin начинает вход через провайдера.
+// Возвращает адрес авторизации для перенаправления пользователя и state для FinishLogin
+func (o *OIDCService) BeginLogin(provider string) (string, string, error) {
+	return o.begin(provider, 0)
+}
+
+// BeginLink начинает привязку учетной записи провайдера к существующему пользователю
+func (o *OIDCService) BeginLink(userID uint, provider string) (string, string, error) {
+	if _, err := o.svc.GetUserByID(userID); err != nil {
+		return "", "", err
+	}
+	return o.begin(provider, userID)
+}
+
+// FinishLogin обменивает код авторизации на токены, проверяет ID токен
+// и возвращает привязанного, найденного по email или созданного пользователя
+func (o *OIDCService) FinishLogin(ctx context.Context, state, code string) (*User, error) {
+	user, err := o.finishLogin(ctx, state, code)
+	var email string
+	if user != nil {
+		email = user.Email
+	}
+	return o.svc.WithContext(ctx).completeLogin(LoginMethodOIDC, email, user, err)
+}
+
+func (o *OIDCService) finishLogin(ctx context.Context, state, code string) (*User, error) {
+	value, ok := o.flows.LoadAndDelete(state)
+	if !ok {
+		return nil, errors.New("неизвестный state")
+	}
+	flow := value.(oidcFlow)
+	if time.Now().After(flow.ExpiresAt) {
+		return nil, errors.New("время входа истекло")
+	}
+
+	p, err := o.provider(flow.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(flow.CodeVerifier))
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("провайдер не вернул id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != flow.Nonce {
+		return nil, errors.New("неверный nonce")
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	user, err := o.resolveUser(p, flow.LinkUserID, claims)
+	if err != nil {
+		return nil, err
+	}
+	if user.UserType == string(UserTypeBlocked) {
+		return user, errors.New("пользователь заблокирован")
+	}
+	return user, nil
+}
+
+// ListIdentities возвращает внешние учетные записи пользователя
+func (o *OIDCService) ListIdentities(userID uint) ([]ExternalIdentity, error) {
+	var identities []ExternalIdentity
+	if err := o.svc.db.Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity отвязывает внешнюю учетную запись от пользователя
+func (o *OIDCService) UnlinkIdentity(userID, identityID uint) error {
+	result := o.svc.db.Where("user_id = ?", userID).Delete(&ExternalIdentity{}, identityID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("внешняя учетная запись не найдена")
+	}
+	return nil
+}
+
+func (o *OIDCService) begin(provider string, linkUserID uint) (string, string, error) {
+	p, err := o.provider(provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	o.cleanupExpiredFlows()
+	flow := oidcFlow{
+		Provider:     provider,
+		Nonce:        uuid.NewString(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(o.timeout),
+	}
+	state := uuid.NewString()
+	o.flows.Store(state, flow)
+
+	authURL := p.oauth2.AuthCodeURL(state, oidc.Nonce(flow.Nonce), oauth2.S256ChallengeOption(flow.CodeVerifier))
+	return authURL, state, nil
+}
+
+func (o *OIDCService) resolveUser(p *oidcProvider, linkUserID uint, claims oidcClaims) (*User, error) {
+	if claims.Subject == "" {
+		return nil, errors.New("в id_token отсутствует sub")
+	}
+
+	now := time.Now()
+	var identity ExternalIdentity
+	err := o.svc.db.Where("provider = ? AND subject = ?", p.config.Name, claims.Subject).First(&identity).Error
+	if err == nil {
+		if linkUserID != 0 && identity.UserID != linkUserID {
+			return nil, errors.New("учетная запись провайдера уже привязана к другому пользователю")
+		}
+		identity.Email = claims.Email
+		identity.LastLoginAt = &now
+		if err := o.svc.db.Save(&identity).Error; err != nil {
+			return nil, err
+		}
+		return o.svc.GetUserByID(identity.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Пользователь, его подтверждение и привязка создаются в одной транзакции,
+	// чтобы ошибка не оставила локального пользователя без привязки
+	var user *User
+	err = o.svc.transaction(func(tx *AccessGoService) error {
+		var err error
+		switch {
+		case linkUserID != 0:
+			user, err = tx.GetUserByID(linkUserID)
+		case claims.Email == "" || !claims.EmailVerified:
+			return errors.New("провайдер не подтвердил email")
+		default:
+			user, err = tx.GetUserByEmail(claims.Email)
+			if err == nil && !user.EmailValidate {
+				// Не привязываем учетную запись к неподтвержденному email, иначе
+				// зарегистрировавший его заранее получит доступ к чужому аккаунту
+				return errors.New("email не подтвержден")
+			}
+			if errors.Is(err, ErrUserNotFound) && p.config.AutoProvision {
+				user, err = o.provisionUser(tx, p, claims)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.db.Create(&ExternalIdentity{
+			UserID:      user.ID,
+			Provider:    p.config.Name,
+			Subject:     claims.Subject,
+			Email:       claims.Email,
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// provisionUser создает пользователя с подтвержденным email и группами по
+// умолчанию в транзакции tx
+func (o *OIDCService) provisionUser(tx *AccessGoService, p *oidcProvider, claims oidcClaims) (*User, error) {
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+	user, err := tx.CreateUser(claims.Email, uuid.NewString(), name, p.config.DefaultUserType)
+	if err != nil {
+		return nil, err
+	}
+	user, err = tx.updateUser(user.ID, func(user *User) {
+		user.EmailValidate = true
+		user.EmailValidationToken = ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(p.config.DefaultGroupIDs) > 0 {
+		if err := tx.SetUserGroups(user.ID, p.config.DefaultGroupIDs...); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+func (o *OIDCService) provider(name string) (*oidcProvider, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	p, ok := o.providers[name]
+	if !ok {
+		return nil, errors.New("провайдер не найден")
+	}
+	return p, nil
+}
+
+func (o *OIDCService) cleanupExpiredFlows() {
+	o.flows.Range(func(key, value interface{}) bool {
+		if time.Now().After(value.(oidcFlow).ExpiresAt) {
+			o.flows.Delete(key)
+		}
+		return true
+	})
+}
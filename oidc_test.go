@@ -0,0 +1,292 @@
+package accessgo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeOIDCProvider - локальный OIDC провайдер для тестов
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]fakeOIDCCode
+}
+
+type fakeOIDCCode struct {
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &fakeOIDCProvider{key: key, codes: make(map[string]fakeOIDCCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &p.key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", p.handleToken)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize имитирует согласие пользователя и возвращает код авторизации
+func (p *fakeOIDCProvider) authorize(t *testing.T, authURL string, claims map[string]interface{}) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := u.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	claims["nonce"] = query.Get("nonce")
+	claims["aud"] = query.Get("client_id")
+
+	code := uuid.NewString()
+	p.mu.Lock()
+	p.codes[code] = fakeOIDCCode{challenge: query.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *fakeOIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	code, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeTestJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	code.claims["iss"] = p.server.URL
+	code.claims["iat"] = time.Now().Unix()
+	code.claims["exp"] = time.Now().Add(time.Hour).Unix()
+	payload, _ := json.Marshal(code.claims)
+
+	signer, _ := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	signed, _ := signer.Sign(payload)
+	idToken, _ := signed.CompactSerialize()
+
+	writeTestJSON(w, map[string]interface{}{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func writeTestJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func setupOIDCService(t *testing.T, autoProvision bool) (*AccessGoService, *OIDCService, *fakeOIDCProvider) {
+	service := newTestService(t, setupTestDB(t))
+	provider := newFakeOIDCProvider(t)
+
+	oidcService, err := NewOIDCService(service)
+	require.NoError(t, err)
+	require.NoError(t, oidcService.RegisterProvider(context.Background(), OIDCProviderConfig{
+		Name:          "fake",
+		IssuerURL:     provider.server.URL,
+		ClientID:      "accessgo",
+		ClientSecret:  "secret",
+		RedirectURL:   "https://app.example.com/callback",
+		AutoProvision: autoProvision,
+	}))
+	return service, oidcService, provider
+}
+
+func TestOIDCLoginProvisionsUser(t *testing.T) {
+	service, oidcService, provider := setupOIDCService(t, true)
+	group, err := service.CreateGroup("External")
+	require.NoError(t, err)
+	oidcService.providers["fake"].config.DefaultGroupIDs = []uint{group.ID}
+
+	authURL, state, err := oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code := provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-1", "email": "new@example.com", "email_verified": true, "name": "New User",
+	})
+
+	user, err := oidcService.FinishLogin(context.Background(), state, code)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.Equal(t, "New User", user.Name)
+	assert.True(t, user.EmailValidate)
+
+	groups, err := service.GetUserGroups(user.ID)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetUser, Action: AuditUserUpdate})
+	require.NoError(t, err)
+	assert.Len(t, events, 1, "подтверждение email записывается в аудит")
+
+	// Ошибка при создании откатывает пользователя вместе с привязкой
+	oidcService.providers["fake"].config.DefaultGroupIDs = []uint{group.ID, 999}
+	authURL, state, err = oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code = provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-5", "email": "broken@example.com", "email_verified": true,
+	})
+	_, err = oidcService.FinishLogin(context.Background(), state, code)
+	assert.Error(t, err)
+	_, err = service.GetUserByEmail("broken@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	oidcService.providers["fake"].config.DefaultGroupIDs = []uint{group.ID}
+
+	// Повторный вход находит пользователя по привязке, даже если email у провайдера изменился
+	authURL, state, err = oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code = provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-1", "email": "renamed@example.com", "email_verified": true,
+	})
+	again, err := oidcService.FinishLogin(context.Background(), state, code)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+
+	// state одноразовый
+	_, err = oidcService.FinishLogin(context.Background(), state, code)
+	assert.Error(t, err)
+	// Ошибка базы при поиске по email не приводит к созданию пользователя
+	require.NoError(t, service.db.Callback().Query().Before("gorm:query").Register("test:fail_users", func(db *gorm.DB) {
+		if db.Statement.Table == "users" {
+			db.AddError(errors.New("база недоступна"))
+		}
+	}))
+	authURL, state, err = oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code = provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-4", "email": "other@example.com", "email_verified": true,
+	})
+	_, err = oidcService.FinishLogin(context.Background(), state, code)
+	assert.EqualError(t, err, "база недоступна")
+	require.NoError(t, service.db.Callback().Query().Remove("test:fail_users"))
+	_, err = service.GetUserByEmail("other@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestOIDCLoginLinksByVerifiedEmail(t *testing.T) {
+	service, oidcService, provider := setupOIDCService(t, false)
+
+	existing, err := service.CreateUser("linked@example.com", "password", "Linked", UserTypeEmployee)
+	require.NoError(t, err)
+
+	// Пока email не подтвержден локально, привязка запрещена
+	authURL, state, err := oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code := provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-2", "email": "linked@example.com", "email_verified": true,
+	})
+	_, err = oidcService.FinishLogin(context.Background(), state, code)
+	assert.Error(t, err)
+
+	require.NoError(t, service.ValidateEmail(existing.EmailValidationToken))
+
+	// Неподтвержденный у провайдера email не используется для привязки
+	authURL, state, err = oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code = provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-2", "email": "linked@example.com", "email_verified": false,
+	})
+	_, err = oidcService.FinishLogin(context.Background(), state, code)
+	assert.Error(t, err)
+
+	authURL, state, err = oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code = provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-2", "email": "linked@example.com", "email_verified": true,
+	})
+	user, err := oidcService.FinishLogin(context.Background(), state, code)
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID, user.ID)
+
+	identities, err := oidcService.ListIdentities(existing.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Equal(t, "ext-2", identities[0].Subject)
+
+	// Без автосоздания неизвестный пользователь не входит
+	authURL, state, err = oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code = provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-3", "email": "stranger@example.com", "email_verified": true,
+	})
+	_, err = oidcService.FinishLogin(context.Background(), state, code)
+	assert.Error(t, err)
+}
+
+func TestOIDCExplicitLinkAndUnlink(t *testing.T) {
+	service, oidcService, provider := setupOIDCService(t, false)
+
+	user, err := service.CreateUser("owner@example.com", "password", "Owner", UserTypeUser)
+	require.NoError(t, err)
+
+	authURL, state, err := oidcService.BeginLink(user.ID, "fake")
+	require.NoError(t, err)
+	code := provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-4", "email": "other-address@example.com", "email_verified": false,
+	})
+	linked, err := oidcService.FinishLogin(context.Background(), state, code)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, linked.ID)
+
+	identities, err := oidcService.ListIdentities(user.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	require.NoError(t, oidcService.UnlinkIdentity(user.ID, identities[0].ID))
+	assert.Error(t, oidcService.UnlinkIdentity(user.ID, identities[0].ID))
+}
+
+func TestOIDCRejectsWrongPKCEVerifier(t *testing.T) {
+	_, oidcService, provider := setupOIDCService(t, true)
+
+	authURL, state, err := oidcService.BeginLogin("fake")
+	require.NoError(t, err)
+	code := provider.authorize(t, authURL, map[string]interface{}{
+		"sub": "ext-5", "email": "pkce@example.com", "email_verified": true,
+	})
+
+	// Подменяем verifier в сохраненном flow
+	value, _ := oidcService.flows.Load(state)
+	flow := value.(oidcFlow)
+	flow.CodeVerifier = "tampered-verifier-tampered-verifier-tampered"
+	oidcService.flows.Store(state, flow)
+
+	_, err = oidcService.FinishLogin(context.Background(), state, code)
+	assert.Error(t, err)
+}
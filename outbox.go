@@ -0,0 +1,333 @@
+package accessgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OutboxEvent - событие, сохраненное в outbox в транзакции изменения и
+// ожидающее доставки. ID используется получателями для отбрасывания повторов
+type OutboxEvent struct {
+	ID            uint            `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Type          EventType       `gorm:"size:64;not null" json:"type"`
+	Payload       json.RawMessage `gorm:"not null" json:"payload"`
+	ActorID       *uint           `json:"actor_id,omitempty"`
+	RequestID     string          `gorm:"size:64" json:"request_id,omitempty"`
+	Attempts      int             `gorm:"not null;default:0" json:"-"`
+	NextAttemptAt time.Time       `gorm:"not null;index:idx_outbox_pending" json:"-"`
+	DeliveredAt   *time.Time      `gorm:"index:idx_outbox_pending" json:"-"`
+	LastError     string          `json:"-"`
+}
+
+var outboxDecoders = map[EventType]func(payload []byte) (Event, error){
+	EventUserCreated:            decodeEvent[UserCreated],
+	EventUserUpdated:            decodeEvent[UserUpdated],
+	EventUserDeleted:            decodeEvent[UserDeleted],
+	EventGroupMembershipChanged: decodeEvent[GroupMembershipChanged],
+	EventAccessGranted:          decodeEvent[AccessGranted],
+	EventAccessRevoked:          decodeEvent[AccessRevoked],
+	EventLoginSucceeded:         decodeEvent[LoginSucceeded],
+	EventLoginFailed:            decodeEvent[LoginFailed],
+}
+
+func decodeEvent[E Event](payload []byte) (Event, error) {
+	var event E
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
+
+// Event восстанавливает типизированное событие из Payload
+func (e *OutboxEvent) Event() (Event, error) {
+	decode, ok := outboxDecoders[e.Type]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный тип события %q", e.Type)
+	}
+	return decode(e.Payload)
+}
+
+// writeOutbox сохраняет событие в outbox. В транзакции запись фиксируется вместе с изменением
+func (s *AccessGoService) writeOutbox(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	record := OutboxEvent{
+		Type:          event.EventType(),
+		Payload:       payload,
+		RequestID:     RequestIDFromContext(s.ctx),
+		NextAttemptAt: time.Now(),
+	}
+	if actorID, ok := ActorFromContext(s.ctx); ok {
+		record.ActorID = &actorID
+	}
+	return s.db.Create(&record).Error
+}
+
+// OutboxSink - получатель событий из outbox. Событие может быть доставлено
+// повторно, поэтому получатель должен отбрасывать уже обработанные ID
+type OutboxSink interface {
+	Deliver(ctx context.Context, event OutboxEvent) error
+}
+
+// OutboxConfig содержит настройки доставки событий из outbox
+type OutboxConfig struct {
+	Sink         OutboxSink
+	BatchSize    int           // по умолчанию 100
+	PollInterval time.Duration // по умолчанию 5 секунд
+	MinBackoff   time.Duration // задержка после первой ошибки, по умолчанию 1 секунда
+	MaxBackoff   time.Duration // по умолчанию 10 минут
+	// LeaseTimeout - время, на которое событие закрепляется за экземпляром
+	// на время доставки, по умолчанию 1 минута
+	LeaseTimeout time.Duration
+	// OnError вызывается при ошибке доставки или чтения outbox
+	OnError func(error)
+}
+
+// OutboxRelay доставляет события из outbox получателю с повторами до успеха
+type OutboxRelay struct {
+	svc    *AccessGoService
+	cfg    OutboxConfig
+	wake   chan struct{}
+	cancel context.CancelFunc
+}
+
+// NewOutboxRelay создает новый экземпляр OutboxRelay и включает запись событий
+// сервиса в outbox. События, опубликованные до вызова, в outbox не попадают
+func NewOutboxRelay(svc *AccessGoService, cfg OutboxConfig) (*OutboxRelay, error) {
+	if cfg.Sink == nil {
+		return nil, errors.New("не задан получатель событий")
+	}
+	if err := svc.db.AutoMigrate(&OutboxEvent{}); err != nil {
+		return nil, err
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 10 * time.Minute
+	}
+	if cfg.LeaseTimeout == 0 {
+		cfg.LeaseTimeout = time.Minute
+	}
+
+	relay := &OutboxRelay{svc: svc, cfg: cfg, wake: make(chan struct{}, 1)}
+	svc.events.outbox.Store(true)
+	// Новые события доставляются сразу после фиксации, не дожидаясь опроса
+	svc.AddAfterHook("", func(ctx context.Context, event Event) {
+		select {
+		case relay.wake <- struct{}{}:
+		default:
+		}
+	})
+	return relay, nil
+}
+
+// DeliverPending доставляет готовые к отправке события и возвращает число доставленных.
+// Ошибки доставки отдельных событий передаются в OnError и откладывают их повтор
+func (r *OutboxRelay) DeliverPending(ctx context.Context) (int, error) {
+	db := r.svc.db.WithContext(ctx)
+	now := time.Now()
+	var events []OutboxEvent
+	if err := db.Where("delivered_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").Limit(r.cfg.BatchSize).Find(&events).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, event := range events {
+		// Закрепляем событие, чтобы другие экземпляры не доставляли его одновременно
+		claim := db.Model(&OutboxEvent{}).
+			Where("id = ? AND delivered_at IS NULL AND next_attempt_at <= ?", event.ID, now).
+			Update("next_attempt_at", now.Add(r.cfg.LeaseTimeout))
+		if claim.Error != nil {
+			return delivered, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		attempts := event.Attempts + 1
+		if err := r.cfg.Sink.Deliver(ctx, event); err != nil {
+			if r.cfg.OnError != nil {
+				r.cfg.OnError(fmt.Errorf("доставка события %d: %w", event.ID, err))
+			}
+			if err := db.Model(&event).Updates(map[string]interface{}{
+				"attempts":        attempts,
+				"next_attempt_at": time.Now().Add(r.backoff(attempts)),
+				"last_error":      err.Error(),
+			}).Error; err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		if err := db.Model(&event).Updates(map[string]interface{}{
+			"attempts":     attempts,
+			"delivered_at": time.Now(),
+			"last_error":   "",
+		}).Error; err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// backoff возвращает задержку перед повтором после attempts неудачных попыток
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	return retryBackoff(r.cfg.MinBackoff, r.cfg.MaxBackoff, attempts)
+}
+
+// retryBackoff возвращает minDelay, удваиваемую с каждой попыткой, но не больше maxDelay
+func retryBackoff(minDelay, maxDelay time.Duration, attempts int) time.Duration {
+	delay := minDelay
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// Purge удаляет события, доставленные раньше before
+func (r *OutboxRelay) Purge(before time.Time) (int64, error) {
+	result := r.svc.db.Where("delivered_at IS NOT NULL AND delivered_at < ?", before).Delete(&OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// Start запускает доставку событий до отмены контекста или вызова Stop
+func (r *OutboxRelay) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	go r.relayRoutine(ctx)
+}
+
+// Stop останавливает доставку событий
+func (r *OutboxRelay) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+func (r *OutboxRelay) relayRoutine(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Полная пачка означает, что в outbox могут остаться готовые события
+		for {
+			delivered, err := r.DeliverPending(ctx)
+			if err != nil && ctx.Err() == nil && r.cfg.OnError != nil {
+				r.cfg.OnError(err)
+			}
+			if err != nil || delivered < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// WebhookSink отправляет события POST запросом с телом OutboxEvent в формате JSON.
+// Заголовок Idempotency-Key содержит ID события
+type WebhookSink struct {
+	URL    string
+	Client *http.Client // по умолчанию http.DefaultClient
+	Header http.Header  // дополнительные заголовки, например Authorization
+}
+
+// Deliver отправляет событие и считает доставленным при ответе 2xx
+func (w *WebhookSink) Deliver(ctx context.Context, event OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range w.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatUint(uint64(event.ID), 10))
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("получатель ответил %s", resp.Status)
+	}
+	return nil
+}
+
+// Publisher - клиент брокера сообщений (NATS, Kafka и т.п.)
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// PublisherSink публикует события в брокер в тему Prefix + тип события,
+// например "accessgo.user.created"
+type PublisherSink struct {
+	Publisher Publisher
+	Prefix    string
+}
+
+// Deliver публикует событие в формате JSON
+func (p *PublisherSink) Deliver(ctx context.Context, event OutboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.Publisher.Publish(ctx, p.Prefix+string(event.Type), data)
+}
+
+// ChannelSink передает события в канал. Доставка ожидает читателя канала
+type ChannelSink chan<- OutboxEvent
+
+// Deliver отправляет событие в канал
+func (c ChannelSink) Deliver(ctx context.Context, event OutboxEvent) error {
+	select {
+	case c <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FanoutSink доставляет события всем получателям. При ошибке одного из них
+// событие повторяется для всех, поэтому остальные получат его еще раз
+type FanoutSink []OutboxSink
+
+// Deliver доставляет событие всем получателям и объединяет их ошибки
+func (f FanoutSink) Deliver(ctx context.Context, event OutboxEvent) error {
+	var errs []error
+	for _, sink := range f {
+		if err := sink.Deliver(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
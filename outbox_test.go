@@ -0,0 +1,191 @@
+package accessgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySink отказывает первые failures раз, затем передает события в канал
+type flakySink struct {
+	failures int
+	events   chan OutboxEvent
+}
+
+func (f *flakySink) Deliver(ctx context.Context, event OutboxEvent) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("получатель недоступен")
+	}
+	f.events <- event
+	return nil
+}
+
+type recordingPublisher struct {
+	subjects []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+	p.subjects = append(p.subjects, subject)
+	return nil
+}
+
+func TestOutboxWrittenWithMutation(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+	events := make(chan OutboxEvent, 10)
+	relay, err := NewOutboxRelay(service, OutboxConfig{Sink: ChannelSink(events)})
+	require.NoError(t, err)
+
+	OnBefore(service, func(ctx context.Context, event UserDeleted) error {
+		return errors.New("удаление запрещено")
+	})
+
+	ctx := ContextWithRequestID(ContextWithActor(context.Background(), 7), "req-1")
+	user, err := service.CreateUserCtx(ctx, "bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	require.Error(t, service.DeleteUser(user.ID))
+	service.WaitEvents()
+
+	var stored []OutboxEvent
+	require.NoError(t, db.Find(&stored).Error)
+	require.Len(t, stored, 1)
+	assert.Equal(t, EventUserCreated, stored[0].Type)
+	assert.Equal(t, "req-1", stored[0].RequestID)
+	require.NotNil(t, stored[0].ActorID)
+	assert.Equal(t, uint(7), *stored[0].ActorID)
+	assert.NotContains(t, string(stored[0].Payload), "password")
+	assert.NotContains(t, string(stored[0].Payload), user.Password)
+
+	delivered, err := relay.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	message := <-events
+	event, err := message.Event()
+	require.NoError(t, err)
+	created, ok := event.(UserCreated)
+	require.True(t, ok)
+	assert.Equal(t, user.ID, created.User.ID)
+	assert.Equal(t, "bob@example.com", created.User.Email)
+
+	delivered, err = relay.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+
+	purged, err := relay.Purge(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
+
+func TestOutboxRetryWithBackoff(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+	sink := &flakySink{failures: 2, events: make(chan OutboxEvent, 2)}
+	var errs []error
+	relay, err := NewOutboxRelay(service, OutboxConfig{
+		Sink:       sink,
+		MinBackoff: 50 * time.Millisecond,
+		MaxBackoff: 80 * time.Millisecond,
+		OnError:    func(err error) { errs = append(errs, err) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, relay.backoff(1))
+	assert.Equal(t, 80*time.Millisecond, relay.backoff(2))
+	assert.Equal(t, 80*time.Millisecond, relay.backoff(30))
+
+	user, err := service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "user:read"))
+
+	delivered, err := relay.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	var stored OutboxEvent
+	require.NoError(t, db.Where("type = ?", EventUserCreated).First(&stored).Error)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, "получатель недоступен", stored.LastError)
+	assert.Nil(t, stored.DeliveredAt)
+
+	// До истечения задержки повтор не выполняется
+	delivered, err = relay.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	time.Sleep(100 * time.Millisecond)
+	delivered, err = relay.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, EventUserCreated, (<-sink.events).Type)
+	assert.Equal(t, EventAccessGranted, (<-sink.events).Type)
+	assert.Len(t, errs, 2)
+
+	require.NoError(t, db.First(&stored, stored.ID).Error)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.NotNil(t, stored.DeliveredAt)
+	assert.Empty(t, stored.LastError)
+}
+
+func TestOutboxRelayStart(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	events := make(chan OutboxEvent)
+	relay, err := NewOutboxRelay(service, OutboxConfig{Sink: ChannelSink(events), PollInterval: time.Hour})
+	require.NoError(t, err)
+	relay.Start(context.Background())
+	defer relay.Stop()
+
+	_, err = service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+
+	// Доставка запускается фиксацией изменения, а не опросом
+	select {
+	case event := <-events:
+		assert.Equal(t, EventUserCreated, event.Type)
+	case <-time.After(5 * time.Second):
+		t.Fatal("событие не доставлено")
+	}
+}
+
+func TestOutboxSinks(t *testing.T) {
+	event := OutboxEvent{ID: 12, Type: EventAccessGranted, Payload: json.RawMessage(`{"access":"user:read"}`)}
+
+	var received OutboxEvent
+	var idempotencyKey, authorization string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		authorization = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook := &WebhookSink{URL: server.URL, Header: http.Header{"Authorization": {"Bearer token"}}}
+	require.NoError(t, webhook.Deliver(context.Background(), event))
+	assert.Equal(t, "12", idempotencyKey)
+	assert.Equal(t, "Bearer token", authorization)
+	assert.Equal(t, event.Type, received.Type)
+	decoded, err := received.Event()
+	require.NoError(t, err)
+	assert.Equal(t, AccessGranted{Access: "user:read"}, decoded)
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, webhook.Deliver(context.Background(), event), "получатель ответил 503 Service Unavailable")
+
+	publisher := &recordingPublisher{}
+	fanout := FanoutSink{&PublisherSink{Publisher: publisher, Prefix: "accessgo."}, &flakySink{failures: 1}}
+	assert.EqualError(t, fanout.Deliver(context.Background(), event), "получатель недоступен")
+	assert.Equal(t, []string{"accessgo.access.granted"}, publisher.subjects)
+
+	_, err = (&OutboxEvent{Type: "unknown"}).Event()
+	assert.Error(t, err)
+}
@@ -0,0 +1,357 @@
+package accessgo
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// PasskeyConfig описывает параметры проверяющей стороны (Relying Party) для WebAuthn
+type PasskeyConfig struct {
+	RPID          string        // домен проверяющей стороны, например "example.com"
+	RPDisplayName string        // отображаемое имя сервиса
+	RPOrigins     []string      // разрешенные origin, например "https://example.com"
+	Timeout       time.Duration // время жизни церемонии, по умолчанию 5 минут
+}
+
+// PasskeyService реализует регистрацию и вход по ключам доступа (passkeys)
+type PasskeyService struct {
+	svc        *AccessGoService
+	webauthn   *webauthn.WebAuthn
+	timeout    time.Duration
+	ceremonies sync.Map
+}
+
+type passkeyCeremony struct {
+	UserID    uint
+	Session   webauthn.SessionData
+	ExpiresAt time.Time
+}
+
+// passkeyUser адаптирует User к интерфейсу webauthn.User
+type passkeyUser struct {
+	user        *User
+	credentials []PasskeyCredential
+}
+
+// NewPasskeyService создает новый экземпляр PasskeyService
+func NewPasskeyService(svc *AccessGoService, cfg PasskeyConfig) (*PasskeyService, error) {
+	if err := svc.db.AutoMigrate(&PasskeyCredential{}); err != nil {
+		return nil, err
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.Timeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.Timeout},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyService{svc: svc, webauthn: w, timeout: cfg.Timeout}, nil
+}
+
+// BeginRegistration начинает регистрацию нового ключа доступа пользователя.
+// Возвращает идентификатор церемонии и параметры для navigator.credentials.create()
+func (p *PasskeyService) BeginRegistration(userID uint) (string, *protocol.CredentialCreation, error) {
+	pu, err := p.loadUser(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(pu.credentials))
+	for _, c := range pu.WebAuthnCredentials() {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+
+	options, session, err := p.webauthn.BeginRegistration(pu,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return p.storeCeremony(userID, session), options, nil
+}
+
+// FinishRegistration завершает регистрацию ключа доступа.
+// response - JSON ответа navigator.credentials.create(), name - название ключа для пользователя
+func (p *PasskeyService) FinishRegistration(ceremonyID, name string, response []byte) (*PasskeyCredential, error) {
+	ceremony, err := p.takeCeremony(ceremonyID)
+	if err != nil {
+		return nil, err
+	}
+
+	pu, err := p.loadUser(ceremony.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := p.webauthn.CreateCredential(pu, ceremony.Session, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	passkey := &PasskeyCredential{
+		UserID:          ceremony.UserID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		Transports:      strings.Join(transports, ","),
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+
+	if err := p.svc.db.Create(passkey).Error; err != nil {
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+// BeginLogin начинает вход по ключу доступа.
+// Если email пустой, используется вход по обнаруживаемым ключам (без указания пользователя)
+func (p *PasskeyService) BeginLogin(email string) (string, *protocol.CredentialAssertion, error) {
+	if email == "" {
+		options, session, err := p.webauthn.BeginDiscoverableLogin()
+		if err != nil {
+			return "", nil, err
+		}
+		return p.storeCeremony(0, session), options, nil
+	}
+
+	user, err := p.svc.GetUserByEmail(email)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pu, err := p.loadUser(user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pu.credentials) == 0 {
+		return "", nil, errors.New("у пользователя нет ключей доступа")
+	}
+
+	options, session, err := p.webauthn.BeginLogin(pu)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return p.storeCeremony(user.ID, session), options, nil
+}
+
+// FinishLogin завершает вход по ключу доступа и возвращает аутентифицированного пользователя.
+// response - JSON ответа navigator.credentials.get()
+func (p *PasskeyService) FinishLogin(ceremonyID string, response []byte) (*User, error) {
+	user, err := p.finishLogin(ceremonyID, response)
+	var email string
+	if user != nil {
+		email = user.Email
+	}
+	return p.svc.completeLogin(LoginMethodPasskey, email, user, err)
+}
+
+func (p *PasskeyService) finishLogin(ceremonyID string, response []byte) (*User, error) {
+	ceremony, err := p.takeCeremony(ceremonyID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pu         *passkeyUser
+		credential *webauthn.Credential
+	)
+
+	if ceremony.UserID == 0 {
+		var user webauthn.User
+		user, credential, err = p.webauthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			userID, err := strconv.ParseUint(string(userHandle), 10, 64)
+			if err != nil {
+				return nil, ErrUserNotFound
+			}
+			return p.loadUser(uint(userID))
+		}, ceremony.Session, parsed)
+		if err != nil {
+			return nil, err
+		}
+		pu = user.(*passkeyUser)
+	} else {
+		pu, err = p.loadUser(ceremony.UserID)
+		if err != nil {
+			return nil, err
+		}
+		credential, err = p.webauthn.ValidateLogin(pu, ceremony.Session, parsed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !pu.user.EmailValidate {
+		return pu.user, errors.New("email не подтвержден")
+	}
+	if pu.user.UserType == string(UserTypeBlocked) {
+		return pu.user, errors.New("пользователь заблокирован")
+	}
+
+	for i := range pu.credentials {
+		passkey := &pu.credentials[i]
+		if !bytes.Equal(passkey.CredentialID, credential.ID) {
+			continue
+		}
+		if credential.Authenticator.CloneWarning {
+			passkey.CloneWarning = true
+			if err := p.svc.db.Save(passkey).Error; err != nil {
+				return nil, err
+			}
+			return pu.user, errors.New("обнаружен возможный клон ключа доступа")
+		}
+		now := time.Now()
+		passkey.SignCount = credential.Authenticator.SignCount
+		passkey.BackupState = credential.Flags.BackupState
+		passkey.LastUsedAt = &now
+		if err := p.svc.db.Save(passkey).Error; err != nil {
+			return nil, err
+		}
+		return pu.user, nil
+	}
+
+	return nil, errors.New("ключ доступа не найден")
+}
+
+// ListCredentials возвращает список ключей доступа пользователя
+func (p *PasskeyService) ListCredentials(userID uint) ([]PasskeyCredential, error) {
+	var credentials []PasskeyCredential
+	if err := p.svc.db.Where("user_id = ?", userID).Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// DeleteCredential удаляет ключ доступа пользователя
+func (p *PasskeyService) DeleteCredential(userID, credentialID uint) error {
+	result := p.svc.db.Where("user_id = ?", userID).Delete(&PasskeyCredential{}, credentialID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("ключ доступа не найден")
+	}
+	return nil
+}
+
+func (p *PasskeyService) loadUser(userID uint) (*passkeyUser, error) {
+	user, err := p.svc.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := p.ListCredentials(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &passkeyUser{user: user, credentials: credentials}, nil
+}
+
+func (p *PasskeyService) storeCeremony(userID uint, session *webauthn.SessionData) string {
+	p.cleanupExpiredCeremonies()
+	ceremonyID := uuid.NewString()
+	p.ceremonies.Store(ceremonyID, passkeyCeremony{
+		UserID:    userID,
+		Session:   *session,
+		ExpiresAt: time.Now().Add(p.timeout),
+	})
+	return ceremonyID
+}
+
+func (p *PasskeyService) takeCeremony(ceremonyID string) (passkeyCeremony, error) {
+	value, ok := p.ceremonies.LoadAndDelete(ceremonyID)
+	if !ok {
+		return passkeyCeremony{}, errors.New("церемония не найдена")
+	}
+	ceremony := value.(passkeyCeremony)
+	if time.Now().After(ceremony.ExpiresAt) {
+		return passkeyCeremony{}, errors.New("церемония истекла")
+	}
+	return ceremony, nil
+}
+
+func (p *PasskeyService) cleanupExpiredCeremonies() {
+	p.ceremonies.Range(func(key, value interface{}) bool {
+		if time.Now().After(value.(passkeyCeremony).ExpiresAt) {
+			p.ceremonies.Delete(key)
+		}
+		return true
+	})
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.user.ID), 10))
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		var transports []protocol.AuthenticatorTransport
+		if c.Transports != "" {
+			for _, t := range strings.Split(c.Transports, ",") {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       c.AAGUID,
+				SignCount:    c.SignCount,
+				CloneWarning: c.CloneWarning,
+			},
+		})
+	}
+	return credentials
+}
@@ -0,0 +1,233 @@
+package accessgo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// softAuthenticator - программный аутентификатор для тестов (ES256, attestation "none")
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, typ, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func (a *softAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) []byte {
+	a.userHandle = options.Response.User.ID.(protocol.URLEncodedBase64)
+
+	coseKey, err := cbor.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, coseKey...)
+
+	attestationObject, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(0x45, attested), // UP | UV | AT
+	})
+	require.NoError(t, err)
+
+	clientData := a.clientData(t, "webauthn.create", options.Response.Challenge.String())
+	return a.marshalResponse(t, map[string]string{
+		"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+		"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+	})
+}
+
+func (a *softAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion) []byte {
+	a.signCount++
+	authData := a.authData(0x05, nil) // UP | UV
+	clientData := a.clientData(t, "webauthn.get", options.Response.Challenge.String())
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.marshalResponse(t, map[string]string{
+		"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+		"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+		"signature":         base64.RawURLEncoding.EncodeToString(signature),
+		"userHandle":        base64.RawURLEncoding.EncodeToString(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) marshalResponse(t *testing.T, response map[string]string) []byte {
+	id := base64.RawURLEncoding.EncodeToString(a.credentialID)
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       id,
+		"rawId":    id,
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func setupPasskeyService(t *testing.T) (*PasskeyService, *User) {
+	service := newTestService(t, setupTestDB(t))
+	passkeys, err := NewPasskeyService(service, PasskeyConfig{
+		RPID:          testRPID,
+		RPDisplayName: "AccessGo",
+		RPOrigins:     []string{testOrigin},
+	})
+	require.NoError(t, err)
+
+	user, err := service.CreateUser("passkey@example.com", "password", "Passkey User", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateEmail(user.EmailValidationToken))
+
+	return passkeys, user
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	passkeys, user := setupPasskeyService(t)
+	authenticator := newSoftAuthenticator(t)
+
+	// Регистрируем ключ доступа
+	ceremonyID, creation, err := passkeys.BeginRegistration(user.ID)
+	require.NoError(t, err)
+
+	credential, err := passkeys.FinishRegistration(ceremonyID, "Ноутбук", authenticator.create(t, creation))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, credential.UserID)
+	assert.Equal(t, authenticator.credentialID, credential.CredentialID)
+
+	// Повторно использовать церемонию нельзя
+	_, err = passkeys.FinishRegistration(ceremonyID, "Ноутбук", authenticator.create(t, creation))
+	assert.Error(t, err)
+
+	// Входим по email
+	ceremonyID, assertion, err := passkeys.BeginLogin(user.Email)
+	require.NoError(t, err)
+
+	loggedIn, err := passkeys.FinishLogin(ceremonyID, authenticator.get(t, assertion))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+
+	// Входим без указания пользователя (discoverable)
+	ceremonyID, assertion, err = passkeys.BeginLogin("")
+	require.NoError(t, err)
+
+	loggedIn, err = passkeys.FinishLogin(ceremonyID, authenticator.get(t, assertion))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+
+	credentials, err := passkeys.ListCredentials(user.ID)
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+	assert.Equal(t, uint32(2), credentials[0].SignCount)
+	assert.NotNil(t, credentials[0].LastUsedAt)
+}
+
+func TestPasskeyMultipleAuthenticators(t *testing.T) {
+	passkeys, user := setupPasskeyService(t)
+	first := newSoftAuthenticator(t)
+	second := newSoftAuthenticator(t)
+
+	for _, authenticator := range []*softAuthenticator{first, second} {
+		ceremonyID, creation, err := passkeys.BeginRegistration(user.ID)
+		require.NoError(t, err)
+		_, err = passkeys.FinishRegistration(ceremonyID, "Ключ", authenticator.create(t, creation))
+		require.NoError(t, err)
+	}
+
+	credentials, err := passkeys.ListCredentials(user.ID)
+	require.NoError(t, err)
+	require.Len(t, credentials, 2)
+
+	// Оба ключа позволяют войти
+	for _, authenticator := range []*softAuthenticator{first, second} {
+		ceremonyID, assertion, err := passkeys.BeginLogin(user.Email)
+		require.NoError(t, err)
+		_, err = passkeys.FinishLogin(ceremonyID, authenticator.get(t, assertion))
+		assert.NoError(t, err)
+	}
+
+	// После удаления ключа вход им невозможен
+	require.NoError(t, passkeys.DeleteCredential(user.ID, credentials[0].ID))
+
+	ceremonyID, assertion, err := passkeys.BeginLogin(user.Email)
+	require.NoError(t, err)
+	_, err = passkeys.FinishLogin(ceremonyID, first.get(t, assertion))
+	assert.Error(t, err)
+}
+
+func TestPasskeySignCounterRegression(t *testing.T) {
+	passkeys, user := setupPasskeyService(t)
+	authenticator := newSoftAuthenticator(t)
+
+	ceremonyID, creation, err := passkeys.BeginRegistration(user.ID)
+	require.NoError(t, err)
+	_, err = passkeys.FinishRegistration(ceremonyID, "Ключ", authenticator.create(t, creation))
+	require.NoError(t, err)
+
+	authenticator.signCount = 10
+	ceremonyID, assertion, err := passkeys.BeginLogin(user.Email)
+	require.NoError(t, err)
+	_, err = passkeys.FinishLogin(ceremonyID, authenticator.get(t, assertion))
+	require.NoError(t, err)
+
+	// Счетчик откатился назад - возможен клон ключа
+	authenticator.signCount = 3
+	ceremonyID, assertion, err = passkeys.BeginLogin(user.Email)
+	require.NoError(t, err)
+	_, err = passkeys.FinishLogin(ceremonyID, authenticator.get(t, assertion))
+	assert.Error(t, err)
+
+	credentials, err := passkeys.ListCredentials(user.ID)
+	require.NoError(t, err)
+	assert.True(t, credentials[0].CloneWarning)
+}
@@ -0,0 +1,222 @@
+package accessgo
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PermissionCacheConfig содержит настройки кэша эффективных прав пользователей
+type PermissionCacheConfig struct {
+	Size int           // число пользователей в кэше, по умолчанию 10000
+	TTL  time.Duration // по умолчанию 1 минута
+	// Publisher рассылает сбросы кэша другим экземплярам в тему Subject.
+	// Полученные сообщения передаются в (*PermissionCache).HandleInvalidation
+	Publisher Publisher
+	Subject   string // по умолчанию "accessgo.permissions.invalidate"
+	// OnError вызывается при ошибке рассылки сброса
+	OnError func(error)
+}
+
+// PermissionInvalidation описывает сброс кэша: права пользователей UserIDs,
+// участников групп GroupIDs или, при All, всех пользователей
+type PermissionInvalidation struct {
+	UserIDs  []uint `json:"user_ids,omitempty"`
+	GroupIDs []uint `json:"group_ids,omitempty"`
+	All      bool   `json:"all,omitempty"`
+}
+
+// PermissionCacheStats содержит счетчики кэша прав
+type PermissionCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+// PermissionCache хранит эффективные права пользователей (прямые и через группы)
+// для CheckUserAccess и GetUserSummaryAccessLevels. Вытесняются давно не
+// использованные записи и записи старше TTL
+type PermissionCache struct {
+	cfg     PermissionCacheConfig
+	mu      sync.Mutex
+	entries map[uint]*list.Element
+	lru     *list.List
+	// generation увеличивается при каждом сбросе. Права, прочитанные из базы
+	// до сброса, в кэш не сохраняются
+	generation uint64
+	hits       uint64
+	misses     uint64
+}
+
+type permissionEntry struct {
+	userID      uint
+	permissions map[string]bool
+	groupIDs    []uint
+	expiresAt   time.Time
+}
+
+// NewPermissionCache создает новый экземпляр PermissionCache и включает его в сервисе.
+// Кэш сбрасывается при выдаче и отзыве прав, изменении членства, удалении
+// пользователей и групп и изменении прав доступа
+func NewPermissionCache(svc *AccessGoService, cfg PermissionCacheConfig) (*PermissionCache, error) {
+	if cfg.Size == 0 {
+		cfg.Size = 10000
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = time.Minute
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = "accessgo.permissions.invalidate"
+	}
+	cache := &PermissionCache{cfg: cfg, entries: make(map[uint]*list.Element), lru: list.New()}
+	svc.permissionCache.Store(cache)
+	return cache, nil
+}
+
+// Stats возвращает счетчики попаданий и промахов и число записей
+func (c *PermissionCache) Stats() PermissionCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return PermissionCacheStats{Hits: c.hits, Misses: c.misses, Size: c.lru.Len()}
+}
+
+// HandleInvalidation применяет сброс, полученный от другого экземпляра
+func (c *PermissionCache) HandleInvalidation(data []byte) error {
+	var invalidation PermissionInvalidation
+	if err := json.Unmarshal(data, &invalidation); err != nil {
+		return err
+	}
+	c.Invalidate(invalidation)
+	return nil
+}
+
+// Invalidate сбрасывает права из кэша этого экземпляра
+func (c *PermissionCache) Invalidate(invalidation PermissionInvalidation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if invalidation.All {
+		c.entries = make(map[uint]*list.Element)
+		c.lru.Init()
+		return
+	}
+	for _, userID := range invalidation.UserIDs {
+		if element, ok := c.entries[userID]; ok {
+			c.remove(element)
+		}
+	}
+	if len(invalidation.GroupIDs) == 0 {
+		return
+	}
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*permissionEntry)
+		for _, groupID := range invalidation.GroupIDs {
+			if slices.Contains(entry.groupIDs, groupID) {
+				c.remove(element)
+				break
+			}
+		}
+		element = next
+	}
+}
+
+// get возвращает права пользователя из кэша и поколение кэша для последующего put
+func (c *PermissionCache) get(userID uint) (map[string]bool, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[userID]; ok {
+		entry := element.Value.(*permissionEntry)
+		if time.Now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(element)
+			c.hits++
+			return entry.permissions, c.generation, true
+		}
+		c.remove(element)
+	}
+	c.misses++
+	return nil, c.generation, false
+}
+
+// put сохраняет права, если с момента get кэш не сбрасывался
+func (c *PermissionCache) put(generation uint64, userID uint, permissions map[string]bool, groupIDs []uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if element, ok := c.entries[userID]; ok {
+		c.remove(element)
+	}
+	c.entries[userID] = c.lru.PushFront(&permissionEntry{
+		userID:      userID,
+		permissions: permissions,
+		groupIDs:    groupIDs,
+		expiresAt:   time.Now().Add(c.cfg.TTL),
+	})
+	for c.lru.Len() > c.cfg.Size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *PermissionCache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*permissionEntry).userID)
+	c.lru.Remove(element)
+}
+
+// publish рассылает сброс другим экземплярам
+func (c *PermissionCache) publish(ctx context.Context, invalidation PermissionInvalidation) {
+	if c.cfg.Publisher == nil {
+		return
+	}
+	data, err := json.Marshal(invalidation)
+	if err == nil {
+		err = c.cfg.Publisher.Publish(ctx, c.cfg.Subject, data)
+	}
+	if err != nil && c.cfg.OnError != nil {
+		c.cfg.OnError(err)
+	}
+}
+
+// userPermissions возвращает эффективные права пользователя, используя кэш, если он включен.
+// Права, прочитанные в транзакции, в кэш не сохраняются: они могут включать
+// незафиксированные изменения, которые будут отменены откатом
+func (s *AccessGoService) userPermissions(userID uint) (map[string]bool, error) {
+	cache := s.permissionCache.Load()
+	var generation uint64
+	if cache != nil {
+		permissions, gen, ok := cache.get(userID)
+		if ok {
+			return permissions, nil
+		}
+		generation = gen
+	}
+
+	permissions, groupIDs, err := s.loadUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache != nil && !s.inTransaction {
+		cache.put(generation, userID, permissions, groupIDs)
+	}
+	return permissions, nil
+}
+
+// invalidatePermissions сбрасывает кэш прав. В транзакции сброс повторяется
+// после фиксации, чтобы параллельное чтение не сохранило права до изменения
+func (s *AccessGoService) invalidatePermissions(invalidation PermissionInvalidation) {
+	cache := s.permissionCache.Load()
+	if cache == nil {
+		return
+	}
+	cache.Invalidate(invalidation)
+	if s.pendingInvalidations != nil {
+		*s.pendingInvalidations = append(*s.pendingInvalidations, invalidation)
+		return
+	}
+	cache.publish(context.WithoutCancel(s.ctx), invalidation)
+}
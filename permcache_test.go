@@ -0,0 +1,195 @@
+package accessgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBus - общий канал сброса кэша для нескольких экземпляров
+type memoryBus struct {
+	mu       sync.Mutex
+	messages []string
+	caches   []*PermissionCache
+}
+
+func (b *memoryBus) Publish(ctx context.Context, subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, subject+" "+string(data))
+	for _, cache := range b.caches {
+		if err := cache.HandleInvalidation(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestPermissionCacheHitsAndInvalidation(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	cache, err := NewPermissionCache(service, PermissionCacheConfig{})
+	require.NoError(t, err)
+
+	bob, err := service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	alice, err := service.CreateUser("alice@example.com", "password", "Alice", UserTypeUser)
+	require.NoError(t, err)
+	group, err := service.CreateGroup("readers")
+	require.NoError(t, err)
+	require.NoError(t, service.AssignUserToGroup(bob.ID, group.ID))
+
+	allowed, err := service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	_, err = service.CheckUserAccess(alice.ID, "user:read")
+	require.NoError(t, err)
+	_, err = service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	assert.Equal(t, PermissionCacheStats{Hits: 1, Misses: 2, Size: 2}, cache.Stats())
+
+	// Выдача права группе сбрасывает только ее участников
+	require.NoError(t, service.AddGroupAccessLevel(group.ID, "user:read"))
+	assert.Equal(t, 1, cache.Stats().Size)
+	allowed, err = service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, service.ExcludeUserFromGroup(bob.ID, group.ID))
+	allowed, err = service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	require.NoError(t, service.AddUserAccessLevel(alice.ID, "group:read"))
+	permissions, err := service.GetUserSummaryAccessLevels(alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"group:read"}, permissions)
+	require.NoError(t, service.RemoveUserAccessLevel(alice.ID, "group:read"))
+	allowed, err = service.CheckUserAccess(alice.ID, "group:read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Переименование права сбрасывает весь кэш
+	require.NoError(t, service.AddUserAccessLevel(alice.ID, "group:read"))
+	_, err = service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	access, err := service.GetAccessByName("group:read")
+	require.NoError(t, err)
+	_, err = service.UpdateAccess(access.ID, "group:view", access.Description)
+	require.NoError(t, err)
+	assert.Zero(t, cache.Stats().Size)
+	allowed, err = service.CheckUserAccess(alice.ID, "group:view")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Ошибка изменения не сбрасывает кэш
+	_, err = service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	require.Error(t, service.RemoveUserAccessLevel(bob.ID, "user:read"))
+	assert.Equal(t, 2, cache.Stats().Size)
+
+	_, err = service.CheckUserAccess(999, "user:read")
+	assert.EqualError(t, err, "пользователь не найден")
+}
+
+func TestPermissionCacheIgnoresRolledBackTransaction(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	cache, err := NewPermissionCache(service, PermissionCacheConfig{})
+	require.NoError(t, err)
+	bob, err := service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+
+	// Права, прочитанные после выдачи в транзакции, не попадают в кэш до фиксации
+	rollback := errors.New("откат")
+	err = service.transaction(func(tx *AccessGoService) error {
+		if err := tx.AddUserAccessLevel(bob.ID, "user:read"); err != nil {
+			return err
+		}
+		allowed, err := tx.CheckUserAccess(bob.ID, "user:read")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+	assert.Equal(t, 0, cache.Stats().Size)
+
+	allowed, err := service.CheckUserAccess(bob.ID, "user:read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestPermissionCacheEvictionAndTTL(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	cache, err := NewPermissionCache(service, PermissionCacheConfig{Size: 2, TTL: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	var users []*User
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		user, err := service.CreateUser(email, "password", "User", UserTypeUser)
+		require.NoError(t, err)
+		users = append(users, user)
+	}
+
+	for _, user := range users[:2] {
+		_, err := service.CheckUserAccess(user.ID, "user:read")
+		require.NoError(t, err)
+	}
+	// Обращение к первому пользователю делает вытесняемым второго
+	_, err = service.CheckUserAccess(users[0].ID, "user:read")
+	require.NoError(t, err)
+	_, err = service.CheckUserAccess(users[2].ID, "user:read")
+	require.NoError(t, err)
+	assert.Equal(t, PermissionCacheStats{Hits: 1, Misses: 3, Size: 2}, cache.Stats())
+	_, err = service.CheckUserAccess(users[0].ID, "user:read")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), cache.Stats().Hits)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = service.CheckUserAccess(users[0].ID, "user:read")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), cache.Stats().Hits)
+	assert.Equal(t, uint64(4), cache.Stats().Misses)
+
+	// Права, прочитанные до сброса, не сохраняются
+	_, generation, ok := cache.get(users[1].ID)
+	require.False(t, ok)
+	cache.Invalidate(PermissionInvalidation{UserIDs: []uint{users[2].ID}})
+	cache.put(generation, users[1].ID, map[string]bool{"user:read": true}, nil)
+	_, _, ok = cache.get(users[1].ID)
+	assert.False(t, ok)
+}
+
+func TestPermissionCacheSharedInvalidation(t *testing.T) {
+	db := setupTestDB(t)
+	bus := &memoryBus{}
+	first := newTestService(t, db)
+	firstCache, err := NewPermissionCache(first, PermissionCacheConfig{Publisher: bus})
+	require.NoError(t, err)
+	second, err := NewAccessGoService(db)
+	require.NoError(t, err)
+	secondCache, err := NewPermissionCache(second, PermissionCacheConfig{Publisher: bus})
+	require.NoError(t, err)
+	bus.caches = []*PermissionCache{firstCache, secondCache}
+
+	user, err := first.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	allowed, err := second.CheckUserAccess(user.ID, "user:read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 1, secondCache.Stats().Size)
+
+	require.NoError(t, first.AddUserAccessLevel(user.ID, "user:read"))
+	assert.Zero(t, secondCache.Stats().Size)
+	allowed, err = second.CheckUserAccess(user.ID, "user:read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	assert.Equal(t, []string{`accessgo.permissions.invalidate {"user_ids":[1]}`}, bus.messages)
+	assert.Error(t, secondCache.HandleInvalidation([]byte("not json")))
+}
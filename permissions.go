@@ -0,0 +1,186 @@
+package accessgo
+
+import (
+	"context"
+
+	"gorm.io/gorm/clause"
+)
+
+// userPermissionsQuery выбирает одним запросом группы пользователя с их правами,
+// его прямые права и строку-признак существования пользователя. Группы без прав
+// тоже попадают в выборку: по ним сбрасывается кэш прав. Ветка групп идет первой,
+// чтобы PostgreSQL определил типы столбцов не по NULL. Таблица groups передается
+// параметром, чтобы имя экранировалось по правилам базы (в MySQL это ключевое слово)
+const userPermissionsQuery = `
+SELECT 'group' AS kind, accesses.name AS name, user_groups.group_id AS group_id
+FROM user_groups
+JOIN ? ON g.id = user_groups.group_id AND g.deleted_at IS NULL
+LEFT JOIN access_levels ON access_levels.group_id = user_groups.group_id AND access_levels.deleted_at IS NULL
+	AND access_levels.access_condition = ''
+LEFT JOIN accesses ON accesses.id = access_levels.access_id AND accesses.deleted_at IS NULL
+WHERE user_groups.user_id = ?
+UNION ALL
+SELECT 'direct', accesses.name, NULL
+FROM access_levels
+JOIN accesses ON accesses.id = access_levels.access_id AND accesses.deleted_at IS NULL
+WHERE access_levels.user_id = ? AND access_levels.deleted_at IS NULL AND access_levels.access_condition = ''
+UNION ALL
+SELECT 'user', NULL, NULL
+FROM users
+WHERE users.id = ? AND users.deleted_at IS NULL`
+
+type userPermissionRow struct {
+	Kind    string
+	Name    *string
+	GroupID *uint
+}
+
+// loadUserPermissions возвращает эффективные права пользователя (прямые и через группы,
+// без условий) и ID его групп
+func (s *AccessGoService) loadUserPermissions(userID uint) (map[string]bool, []uint, error) {
+	var rows []userPermissionRow
+	groupsTable := clause.Table{Name: "groups", Alias: "g"}
+	if err := s.db.Raw(userPermissionsQuery, groupsTable, userID, userID, userID).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	found := false
+	permissions := make(map[string]bool)
+	groups := make(map[uint]bool)
+	var groupIDs []uint
+	for _, row := range rows {
+		if row.Kind == "user" {
+			found = true
+			continue
+		}
+		if row.Name != nil {
+			permissions[*row.Name] = true
+		}
+		if row.GroupID != nil && !groups[*row.GroupID] {
+			groups[*row.GroupID] = true
+			groupIDs = append(groupIDs, *row.GroupID)
+		}
+	}
+	if !found {
+		return nil, nil, ErrUserNotFound
+	}
+	return permissions, groupIDs, nil
+}
+
+// usersAccessQuery выбирает существующих пользователей из списка и тех из них,
+// у кого есть право напрямую или через группу
+const usersAccessQuery = `
+SELECT 'user' AS kind, users.id AS user_id
+FROM users
+WHERE users.id IN ? AND users.deleted_at IS NULL
+UNION ALL
+SELECT 'granted', access_levels.user_id
+FROM access_levels
+JOIN accesses ON accesses.id = access_levels.access_id AND accesses.deleted_at IS NULL
+WHERE access_levels.user_id IN ? AND access_levels.deleted_at IS NULL AND access_levels.access_condition = ''
+	AND accesses.name = ?
+UNION ALL
+SELECT 'granted', user_groups.user_id
+FROM user_groups
+JOIN ? ON g.id = user_groups.group_id AND g.deleted_at IS NULL
+JOIN access_levels ON access_levels.group_id = user_groups.group_id AND access_levels.deleted_at IS NULL
+	AND access_levels.access_condition = ''
+JOIN accesses ON accesses.id = access_levels.access_id AND accesses.deleted_at IS NULL
+WHERE user_groups.user_id IN ? AND accesses.name = ?`
+
+// CheckUserAccessMany проверяет несколько прав пользователя за одно обращение к базе
+func (s *AccessGoService) CheckUserAccessMany(userID uint, accessNames []string) (map[string]bool, error) {
+	return s.CheckUserAccessManyCtx(s.ctx, userID, accessNames)
+}
+
+// CheckUserAccessManyCtx - CheckUserAccessMany с контекстом ctx
+func (s *AccessGoService) CheckUserAccessManyCtx(ctx context.Context, userID uint, accessNames []string) (map[string]bool, error) {
+	s = s.WithContext(ctx)
+	permissions, err := s.userPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(accessNames))
+	for _, accessName := range accessNames {
+		result[accessName] = permissions[accessName]
+	}
+	return result, nil
+}
+
+// CheckUsersAccess проверяет право у нескольких пользователей за одно обращение к базе.
+// Пользователи, права которых есть в кэше, в базе не проверяются
+func (s *AccessGoService) CheckUsersAccess(userIDs []uint, accessName string) (map[uint]bool, error) {
+	return s.CheckUsersAccessCtx(s.ctx, userIDs, accessName)
+}
+
+// CheckUsersAccessCtx - CheckUsersAccess с контекстом ctx
+func (s *AccessGoService) CheckUsersAccessCtx(ctx context.Context, userIDs []uint, accessName string) (map[uint]bool, error) {
+	s = s.WithContext(ctx)
+	result := make(map[uint]bool, len(userIDs))
+	var missing []uint
+	cache := s.permissionCache.Load()
+	for _, userID := range userIDs {
+		if _, ok := result[userID]; ok {
+			continue
+		}
+		if cache != nil {
+			if permissions, _, ok := cache.get(userID); ok {
+				result[userID] = permissions[accessName]
+				continue
+			}
+		}
+		result[userID] = false
+		missing = append(missing, userID)
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		Kind   string
+		UserID uint
+	}
+	groupsTable := clause.Table{Name: "groups", Alias: "g"}
+	err := s.db.Raw(usersAccessQuery, missing, missing, accessName, groupsTable, missing, accessName).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	found := 0
+	for _, row := range rows {
+		if row.Kind == "user" {
+			found++
+		} else {
+			result[row.UserID] = true
+		}
+	}
+	if found != len(missing) {
+		return nil, ErrUserNotFound
+	}
+	return result, nil
+}
+
+// FilterAuthorized оставляет элементы, на которые у пользователя есть право.
+// accessName возвращает имя права для элемента, например "project:42:read",
+// или пустую строку, если право не требуется. Права загружаются один раз
+func FilterAuthorized[T any](s *AccessGoService, userID uint, items []T, accessName func(T) string) ([]T, error) {
+	return FilterAuthorizedCtx(s.ctx, s, userID, items, accessName)
+}
+
+// FilterAuthorizedCtx - FilterAuthorized с контекстом ctx
+func FilterAuthorizedCtx[T any](ctx context.Context, s *AccessGoService, userID uint, items []T, accessName func(T) string) ([]T, error) {
+	s = s.WithContext(ctx)
+	permissions, err := s.userPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := make([]T, 0, len(items))
+	for _, item := range items {
+		if name := accessName(item); name == "" || permissions[name] {
+			authorized = append(authorized, item)
+		}
+	}
+	return authorized, nil
+}
@@ -0,0 +1,209 @@
+package accessgo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadUserPermissions(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	user, err := service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	readers, err := service.CreateGroup("readers")
+	require.NoError(t, err)
+	empty, err := service.CreateGroup("empty")
+	require.NoError(t, err)
+	removed, err := service.CreateGroup("removed")
+	require.NoError(t, err)
+	require.NoError(t, service.SetUserGroups(user.ID, readers.ID, empty.ID, removed.ID))
+
+	_, err = service.CreateAccess("report:read", "Чтение отчетов")
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "user:read"))
+	require.NoError(t, service.AddUserAccessLevel(user.ID, "report:read"))
+	require.NoError(t, service.AddGroupAccessLevel(readers.ID, "group:read"))
+	require.NoError(t, service.AddGroupAccessLevel(readers.ID, "user:read"))
+	require.NoError(t, service.AddGroupAccessLevel(removed.ID, "group:delete"))
+
+	// Права удаленных групп и удаленные права не действуют
+	require.NoError(t, service.DeleteGroup(removed.ID))
+	report, err := service.GetAccessByName("report:read")
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteAccess(report.ID))
+
+	permissions, groupIDs, err := service.loadUserPermissions(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"user:read": true, "group:read": true}, permissions)
+	assert.ElementsMatch(t, []uint{readers.ID, empty.ID}, groupIDs)
+
+	summary, err := service.GetUserSummaryAccessLevels(user.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user:read", "group:read"}, summary)
+
+	other, err := service.CreateUser("alice@example.com", "password", "Alice", UserTypeUser)
+	require.NoError(t, err)
+	permissions, groupIDs, err = service.loadUserPermissions(other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, permissions)
+	assert.Empty(t, groupIDs)
+
+	// Оставшиеся назначения удаленного пользователя не делают его найденным
+	require.NoError(t, service.DeleteUser(user.ID))
+	_, _, err = service.loadUserPermissions(user.ID)
+	assert.EqualError(t, err, "пользователь не найден")
+	allowed, err := service.CheckUserAccess(user.ID, "user:read")
+	assert.EqualError(t, err, "пользователь не найден")
+	assert.False(t, allowed)
+}
+
+func TestBatchAccessChecks(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	bob, err := service.CreateUser("bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	alice, err := service.CreateUser("alice@example.com", "password", "Alice", UserTypeUser)
+	require.NoError(t, err)
+	carol, err := service.CreateUser("carol@example.com", "password", "Carol", UserTypeUser)
+	require.NoError(t, err)
+	group, err := service.CreateGroup("readers")
+	require.NoError(t, err)
+	require.NoError(t, service.AssignUserToGroup(alice.ID, group.ID))
+	require.NoError(t, service.AddGroupAccessLevel(group.ID, "user:read"))
+	require.NoError(t, service.AddUserAccessLevel(bob.ID, "user:read"))
+	require.NoError(t, service.AddUserAccessLevel(bob.ID, "group:read"))
+
+	checks, err := service.CheckUserAccessMany(bob.ID, []string{"user:read", "group:read", "user:delete"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"user:read": true, "group:read": true, "user:delete": false}, checks)
+	_, err = service.CheckUserAccessMany(999, []string{"user:read"})
+	assert.EqualError(t, err, "пользователь не найден")
+
+	users, err := service.CheckUsersAccess([]uint{bob.ID, alice.ID, carol.ID, bob.ID}, "user:read")
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]bool{bob.ID: true, alice.ID: true, carol.ID: false}, users)
+	_, err = service.CheckUsersAccess([]uint{bob.ID, 999}, "user:read")
+	assert.EqualError(t, err, "пользователь не найден")
+
+	// С кэшем права части пользователей берутся из него, остальные из базы
+	cache, err := NewPermissionCache(service, PermissionCacheConfig{})
+	require.NoError(t, err)
+	_, err = service.CheckUserAccess(alice.ID, "user:read")
+	require.NoError(t, err)
+	users, err = service.CheckUsersAccess([]uint{alice.ID, carol.ID}, "user:read")
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]bool{alice.ID: true, carol.ID: false}, users)
+	assert.Equal(t, uint64(1), cache.Stats().Hits)
+
+	projects := []uint{1, 2, 3}
+	_, err = service.CreateAccess("project:2:read", "Чтение проекта 2")
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevel(carol.ID, "project:2:read"))
+	visible, err := FilterAuthorized(service, carol.ID, projects, func(id uint) string {
+		if id == 3 {
+			return ""
+		}
+		return fmt.Sprintf("project:%d:read", id)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2, 3}, visible)
+}
+
+// setupPermissionsBenchmark создает пользователя в groups группах, каждой из которых
+// выдано grantsPerGroup прав, и с directGrants прямыми правами
+func setupPermissionsBenchmark(b *testing.B, groups, grantsPerGroup, directGrants int) (*AccessGoService, uint) {
+	db := setupTestDB(b)
+	service := newTestService(b, db)
+
+	accesses := make([]Access, 1000)
+	for i := range accesses {
+		accesses[i] = Access{Name: fmt.Sprintf("bench:%d", i)}
+	}
+	require.NoError(b, db.CreateInBatches(&accesses, 500).Error)
+
+	user := User{Email: "bench@example.com", Password: "-", Name: "Bench", UserType: string(UserTypeUser)}
+	require.NoError(b, db.Create(&user).Error)
+
+	members := make([]map[string]interface{}, 0, groups)
+	var levels []AccessLevel
+	for i := 0; i < groups; i++ {
+		group := Group{Name: fmt.Sprintf("group-%d", i)}
+		require.NoError(b, db.Create(&group).Error)
+		members = append(members, map[string]interface{}{"user_id": user.ID, "group_id": group.ID})
+		for j := 0; j < grantsPerGroup; j++ {
+			levels = append(levels, AccessLevel{GroupID: &group.ID, AccessID: accesses[(i*grantsPerGroup+j)%len(accesses)].ID})
+		}
+	}
+	for i := 0; i < directGrants; i++ {
+		levels = append(levels, AccessLevel{UserID: &user.ID, AccessID: accesses[i%len(accesses)].ID})
+	}
+	if len(members) > 0 {
+		require.NoError(b, db.Table("user_groups").CreateInBatches(members, 500).Error)
+	}
+	require.NoError(b, db.CreateInBatches(&levels, 500).Error)
+	return service, user.ID
+}
+
+var permissionsBenchmarkSizes = []struct {
+	groups, grantsPerGroup, directGrants int
+}{
+	{groups: 10, grantsPerGroup: 10, directGrants: 10},
+	{groups: 300, grantsPerGroup: 10, directGrants: 200},
+	{groups: 500, grantsPerGroup: 20, directGrants: 1000},
+}
+
+func benchmarkPermissions(b *testing.B, fn func(b *testing.B, service *AccessGoService, userID uint)) {
+	for _, size := range permissionsBenchmarkSizes {
+		name := fmt.Sprintf("groups=%d/grants=%d", size.groups, size.groups*size.grantsPerGroup+size.directGrants)
+		b.Run(name, func(b *testing.B) {
+			service, userID := setupPermissionsBenchmark(b, size.groups, size.grantsPerGroup, size.directGrants)
+			b.ResetTimer()
+			fn(b, service, userID)
+		})
+	}
+}
+
+func BenchmarkCheckUserAccess(b *testing.B) {
+	benchmarkPermissions(b, func(b *testing.B, service *AccessGoService, userID uint) {
+		for i := 0; i < b.N; i++ {
+			if _, err := service.CheckUserAccess(userID, "bench:999"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetUserSummaryAccessLevels(b *testing.B) {
+	benchmarkPermissions(b, func(b *testing.B, service *AccessGoService, userID uint) {
+		for i := 0; i < b.N; i++ {
+			if _, err := service.GetUserSummaryAccessLevels(userID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkCheckUserAccessPreload - прежняя загрузка прав через Preload, для сравнения
+func BenchmarkCheckUserAccessPreload(b *testing.B) {
+	benchmarkPermissions(b, func(b *testing.B, service *AccessGoService, userID uint) {
+		for i := 0; i < b.N; i++ {
+			var user User
+			if err := service.db.Preload("Accesses.Access").Preload("Groups.Accesses.Access").First(&user, userID).Error; err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCheckUserAccessCached(b *testing.B) {
+	benchmarkPermissions(b, func(b *testing.B, service *AccessGoService, userID uint) {
+		_, err := NewPermissionCache(service, PermissionCacheConfig{})
+		require.NoError(b, err)
+		for i := 0; i < b.N; i++ {
+			if _, err := service.CheckUserAccess(userID, "bench:999"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
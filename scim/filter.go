@@ -0,0 +1,151 @@
+package scim
+
+import (
+	"errors"
+	"strings"
+)
+
+// filter - разобранное выражение фильтра SCIM вида
+// `attr op "value" [and|or attr op "value" ...]`, and имеет приоритет над or.
+// Поддерживаются операторы eq, ne, co, sw, ew и pr, скобки не поддерживаются
+type filter [][]clause
+
+type clause struct {
+	attr  string
+	op    string
+	value string
+}
+
+func parseFilter(expr string) (filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	var (
+		result filter
+		group  []clause
+	)
+	for i := 0; i < len(tokens); {
+		if len(tokens) < i+2 {
+			return nil, errors.New("invalid filter")
+		}
+		c := clause{attr: strings.ToLower(tokens[i]), op: strings.ToLower(tokens[i+1])}
+		i += 2
+		switch c.op {
+		case "pr":
+		case "eq", "ne", "co", "sw", "ew":
+			if i >= len(tokens) {
+				return nil, errors.New("invalid filter")
+			}
+			c.value = tokens[i]
+			i++
+		default:
+			return nil, errors.New("unsupported filter operator " + c.op)
+		}
+		group = append(group, c)
+
+		if i == len(tokens) {
+			break
+		}
+		switch strings.ToLower(tokens[i]) {
+		case "and":
+		case "or":
+			result = append(result, group)
+			group = nil
+		default:
+			return nil, errors.New("invalid filter")
+		}
+		i++
+		if i == len(tokens) {
+			return nil, errors.New("invalid filter")
+		}
+	}
+	return append(result, group), nil
+}
+
+// match проверяет ресурс; values возвращает значения атрибута по имени в нижнем регистре
+func (f filter) match(values func(attr string) []string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, group := range f {
+		ok := true
+		for _, c := range group {
+			if !c.match(values(c.attr)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c clause) match(values []string) bool {
+	if c.op == "pr" {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if c.op == "ne" {
+		for _, v := range values {
+			if strings.EqualFold(v, c.value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	expected := strings.ToLower(c.value)
+	for _, v := range values {
+		v = strings.ToLower(v)
+		switch {
+		case c.op == "eq" && v == expected,
+			c.op == "co" && strings.Contains(v, expected),
+			c.op == "sw" && strings.HasPrefix(v, expected),
+			c.op == "ew" && strings.HasSuffix(v, expected):
+			return true
+		}
+	}
+	return false
+}
+
+func tokenizeFilter(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		switch {
+		case expr[i] == ' ':
+			i++
+		case expr[i] == '"':
+			var sb strings.Builder
+			i++
+			for ; i < len(expr) && expr[i] != '"'; i++ {
+				if expr[i] == '\\' && i+1 < len(expr) {
+					i++
+				}
+				sb.WriteByte(expr[i])
+			}
+			if i >= len(expr) {
+				return nil, errors.New("unterminated string in filter")
+			}
+			tokens = append(tokens, sb.String())
+			i++
+		default:
+			start := i
+			for i < len(expr) && expr[i] != ' ' {
+				i++
+			}
+			tokens = append(tokens, expr[start:i])
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,384 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axgrid/accessgo"
+)
+
+// groupResource - представление группы AccessGo в схеме SCIM Group
+type groupResource struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []memberRef `json:"members,omitempty"`
+	Meta        *meta       `json:"meta,omitempty"`
+}
+
+func (h *Handler) listGroups(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+
+	groups, err := h.svc.GetAllGroups()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	// Azure AD и Okta запрашивают группы без участников
+	withMembers := !strings.Contains(strings.ToLower(r.URL.Query().Get("excludedAttributes")), "members")
+	resources := make([]groupResource, 0, len(groups))
+	for _, group := range groups {
+		resource, err := h.groupResource(&group, withMembers)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		if f.match(resource.filterValues) {
+			resources = append(resources, *resource)
+		}
+	}
+	writeList(w, r, resources)
+}
+
+func (h *Handler) createGroup(w http.ResponseWriter, r *http.Request) {
+	var resource groupResource
+	if err := decodeBody(r, &resource); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if resource.DisplayName == "" {
+		writeError(w, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	memberIDs, err := parseMemberIDs(resource.Members)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if err := h.checkGroupName(resource.DisplayName, 0); err != nil {
+		writeGroupError(w, err)
+		return
+	}
+
+	group, err := h.svc.CreateGroup(resource.DisplayName)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	if err := h.setMembers(group.ID, memberIDs); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	h.writeGroup(w, http.StatusCreated, group.ID)
+}
+
+func (h *Handler) getGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "", "group not found")
+		return
+	}
+	h.writeGroup(w, http.StatusOK, id)
+}
+
+func (h *Handler) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+	if !ok {
+		return
+	}
+	var resource groupResource
+	if err := decodeBody(r, &resource); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if resource.DisplayName == "" {
+		writeError(w, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	memberIDs, err := parseMemberIDs(resource.Members)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if err := h.renameGroup(group, resource.DisplayName); err != nil {
+		writeGroupError(w, err)
+		return
+	}
+	if err := h.setMembers(group.ID, memberIDs); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	h.writeGroup(w, http.StatusOK, group.ID)
+}
+
+func (h *Handler) patchGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+	if !ok {
+		return
+	}
+	var patch patchRequest
+	if err := decodeBody(r, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	for _, op := range patch.Operations {
+		if err := h.applyGroupPatch(group, strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+			writeGroupError(w, err)
+			return
+		}
+	}
+	h.writeGroup(w, http.StatusOK, group.ID)
+}
+
+func (h *Handler) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.findGroup(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteGroup(group.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errGroupExists = errors.New("group already exists")
+
+func (h *Handler) applyGroupPatch(group *accessgo.Group, op, path string, value json.RawMessage) error {
+	if path == "" {
+		if op == "remove" {
+			return errors.New("path is required for remove")
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attrs); err != nil {
+			return err
+		}
+		for attr, v := range attrs {
+			if err := h.applyGroupPatch(group, op, attr, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	attr := strings.ToLower(path)
+	switch {
+	case attr == "displayname" && op != "remove":
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil {
+			return err
+		}
+		return h.renameGroup(group, name)
+	case attr == "members":
+		var ids []uint
+		if len(value) > 0 {
+			var members []memberRef
+			if err := json.Unmarshal(value, &members); err != nil {
+				return err
+			}
+			var err error
+			if ids, err = parseMemberIDs(members); err != nil {
+				return err
+			}
+		}
+		switch op {
+		case "add":
+			return h.addMembers(group.ID, ids)
+		case "replace":
+			return h.setMembers(group.ID, ids)
+		case "remove":
+			if len(value) == 0 {
+				return h.setMembers(group.ID, nil)
+			}
+			return h.removeMembers(group.ID, ids)
+		}
+	case strings.HasPrefix(attr, "members[") && op == "remove":
+		// members[value eq "42"]
+		f, err := parseFilter(strings.TrimSuffix(path[len("members["):], "]"))
+		if err != nil {
+			return err
+		}
+		members, err := h.svc.GetGroupUsers(group.ID)
+		if err != nil {
+			return err
+		}
+		var ids []uint
+		for _, user := range members {
+			id := strconv.FormatUint(uint64(user.ID), 10)
+			if f.match(func(attr string) []string {
+				if attr == "value" {
+					return []string{id}
+				}
+				return nil
+			}) {
+				ids = append(ids, user.ID)
+			}
+		}
+		return h.removeMembers(group.ID, ids)
+	}
+	return errors.New("unsupported operation " + op + " on " + path)
+}
+
+func (h *Handler) findGroup(w http.ResponseWriter, r *http.Request) (*accessgo.Group, bool) {
+	id, ok := parseID(r)
+	if ok {
+		group, err := h.svc.GetGroupByID(id)
+		if err == nil {
+			return group, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "", "group not found")
+	return nil, false
+}
+
+func (h *Handler) renameGroup(group *accessgo.Group, name string) error {
+	if name == group.Name {
+		return nil
+	}
+	if err := h.checkGroupName(name, group.ID); err != nil {
+		return err
+	}
+	if _, err := h.svc.UpdateGroup(group.ID, name); err != nil {
+		return err
+	}
+	group.Name = name
+	return nil
+}
+
+func (h *Handler) checkGroupName(name string, exceptID uint) error {
+	groups, err := h.svc.GetAllGroups()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.ID != exceptID && g.Name == name {
+			return errGroupExists
+		}
+	}
+	return nil
+}
+
+func writeGroupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errGroupExists) {
+		writeError(w, http.StatusConflict, "uniqueness", err.Error())
+		return
+	}
+	writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+}
+
+// setMembers устанавливает точный состав группы
+func (h *Handler) setMembers(groupID uint, userIDs []uint) error {
+	members, err := h.svc.GetGroupUsers(groupID)
+	if err != nil {
+		return err
+	}
+	var removed []uint
+	for _, user := range members {
+		if !slices.Contains(userIDs, user.ID) {
+			removed = append(removed, user.ID)
+		}
+	}
+	if err := h.removeMembers(groupID, removed); err != nil {
+		return err
+	}
+	return h.addMembers(groupID, userIDs)
+}
+
+func (h *Handler) addMembers(groupID uint, userIDs []uint) error {
+	for _, userID := range userIDs {
+		if err := h.svc.AssignUserToGroup(userID, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) removeMembers(groupID uint, userIDs []uint) error {
+	for _, userID := range userIDs {
+		if err := h.svc.ExcludeUserFromGroup(userID, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) writeGroup(w http.ResponseWriter, status int, id uint) {
+	group, err := h.svc.GetGroupByID(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "", "group not found")
+		return
+	}
+	resource, err := h.groupResource(group, true)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	writeJSON(w, status, resource)
+}
+
+func (h *Handler) groupResource(group *accessgo.Group, withMembers bool) (*groupResource, error) {
+	resource := &groupResource{
+		Schemas:     []string{schemaGroup},
+		ID:          strconv.FormatUint(uint64(group.ID), 10),
+		DisplayName: group.Name,
+		Meta: &meta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: group.UpdatedAt.UTC().Format(time.RFC3339),
+		},
+	}
+	if !withMembers {
+		return resource, nil
+	}
+
+	users, err := h.svc.GetGroupUsers(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		resource.Members = append(resource.Members, memberRef{
+			Value:   strconv.FormatUint(uint64(user.ID), 10),
+			Display: user.Name,
+		})
+	}
+	return resource, nil
+}
+
+func (g *groupResource) filterValues(attr string) []string {
+	switch attr {
+	case "id":
+		return []string{g.ID}
+	case "displayname":
+		return []string{g.DisplayName}
+	case "members", "members.value":
+		values := make([]string, 0, len(g.Members))
+		for _, m := range g.Members {
+			values = append(values, m.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+func parseMemberIDs(members []memberRef) ([]uint, error) {
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m.Value, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid member " + m.Value)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,205 @@
+// Package scim реализует SCIM 2.0 (RFC 7643, RFC 7644) endpoint /Users и /Groups
+// поверх AccessGoService для автоматического провижининга из HR систем и IdP.
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/axgrid/accessgo"
+)
+
+const (
+	schemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	schemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	contentType     = "application/scim+json"
+	defaultPageSize = 100
+)
+
+// Handler обслуживает SCIM запросы. Клиент аутентифицируется API ключом AccessGo
+// (Authorization: Bearer <ключ>), права ключа проверяются для каждой операции
+type Handler struct {
+	svc         *accessgo.AccessGoService
+	mux         *http.ServeMux
+	handler     http.Handler
+	allowAdmins bool
+}
+
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type listResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+type patchRequest struct {
+	Schemas    []string  `json:"schemas"`
+	Operations []patchOp `json:"Operations"`
+}
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// NewHandler создает SCIM обработчик, который можно смонтировать в любом роутере:
+//
+//	mux.Handle("/scim/v2/", http.StripPrefix("/scim/v2", scim.NewHandler(service)))
+func NewHandler(svc *accessgo.AccessGoService) *Handler {
+	h := &Handler{svc: svc, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /Users", h.guard("user:read", (*Handler).listUsers))
+	h.mux.HandleFunc("POST /Users", h.guard("user:create", (*Handler).createUser))
+	h.mux.HandleFunc("GET /Users/{id}", h.guard("user:read", (*Handler).getUser))
+	h.mux.HandleFunc("PUT /Users/{id}", h.guard("user:update", (*Handler).replaceUser))
+	h.mux.HandleFunc("PATCH /Users/{id}", h.guard("user:update", (*Handler).patchUser))
+	h.mux.HandleFunc("DELETE /Users/{id}", h.guard("user:delete", (*Handler).deleteUser))
+
+	h.mux.HandleFunc("GET /Groups", h.guard("group:read", (*Handler).listGroups))
+	h.mux.HandleFunc("POST /Groups", h.guard("group:create", (*Handler).createGroup))
+	h.mux.HandleFunc("GET /Groups/{id}", h.guard("group:read", (*Handler).getGroup))
+	h.mux.HandleFunc("PUT /Groups/{id}", h.guard("group:update", (*Handler).replaceGroup))
+	h.mux.HandleFunc("PATCH /Groups/{id}", h.guard("group:update", (*Handler).patchGroup))
+	h.mux.HandleFunc("DELETE /Groups/{id}", h.guard("group:delete", (*Handler).deleteGroup))
+
+	h.mux.HandleFunc("GET /ServiceProviderConfig", h.serviceProviderConfig)
+	h.handler = accessgo.RequestID(h.mux)
+	return h
+}
+
+// EnableAdminProvisioning разрешает создавать пользователей с типом admin,
+// назначать этот тип и изменять или удалять существующих администраторов через SCIM.
+// По умолчанию такие запросы отклоняются с 403, чтобы API ключ провижининга
+// не мог выдать права администратора или перехватить чужую учетную запись admin
+func (h *Handler) EnableAdminProvisioning() {
+	h.allowAdmins = true
+}
+
+// ServeHTTP реализует http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// guard аутентифицирует запрос и вызывает next с обработчиком, чей сервис
+// записывает изменения в аудит от имени владельца API ключа
+func (h *Handler) guard(accessName string, next func(*Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "", "bearer token required")
+			return
+		}
+		user, scopes, err := h.svc.AuthenticateAPIKey(token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "", err.Error())
+			return
+		}
+		allowed, err := h.svc.CheckScopedAccess(user.ID, scopes, accessName)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		if !allowed {
+			writeError(w, http.StatusForbidden, "", "permission "+accessName+" required")
+			return
+		}
+		ctx := accessgo.ContextWithActor(r.Context(), user.ID)
+		scoped := *h
+		scoped.svc = h.svc.WithContext(ctx)
+		next(&scoped, w, r.WithContext(ctx))
+	}
+}
+
+func (h *Handler) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{schemaSPConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": defaultPageSize},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type": "oauthbearertoken", "name": "API key", "description": "AccessGo API key", "primary": true,
+		}},
+	})
+}
+
+// paginate применяет startIndex и count (индексация с 1) к списку
+func paginate[T any](r *http.Request, items []T) ([]T, int) {
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 || count > defaultPageSize {
+		count = defaultPageSize
+	}
+
+	from := startIndex - 1
+	if from > len(items) {
+		from = len(items)
+	}
+	to := from + count
+	if to > len(items) {
+		to = len(items)
+	}
+	return items[from:to], startIndex
+}
+
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, startIndex := paginate(r, items)
+	writeJSON(w, http.StatusOK, listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: len(items),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, scimType, detail string) {
+	writeJSON(w, status, scimError{
+		Schemas:  []string{schemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func parseID(r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	return uint(id), err == nil
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
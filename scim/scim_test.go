@@ -0,0 +1,253 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/axgrid/accessgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type testClient struct {
+	t       *testing.T
+	handler http.Handler
+	token   string
+}
+
+func setupSCIM(t *testing.T, scopes ...string) (*accessgo.AccessGoService, *testClient) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	service, err := accessgo.NewAccessGoService(db)
+	require.NoError(t, err)
+
+	account, err := service.CreateServiceAccount("idp@service.local", "IdP")
+	require.NoError(t, err)
+	for _, access := range []string{"user:read", "user:create", "user:update", "user:delete",
+		"group:read", "group:create", "group:update", "group:delete"} {
+		require.NoError(t, service.AddUserAccessLevel(account.ID, access))
+	}
+	token, _, err := service.CreateAPIKey(account.ID, "scim", nil, scopes...)
+	require.NoError(t, err)
+
+	return service, &testClient{t: t, handler: NewHandler(service), token: token}
+}
+
+func (c *testClient) do(method, path string, body interface{}) (int, map[string]interface{}) {
+	var reader bytes.Buffer
+	if body != nil {
+		require.NoError(c.t, json.NewEncoder(&reader).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+
+	var result map[string]interface{}
+	if rec.Body.Len() > 0 {
+		require.NoError(c.t, json.Unmarshal(rec.Body.Bytes(), &result))
+	}
+	return rec.Code, result
+}
+
+func TestSCIMUserLifecycle(t *testing.T) {
+	service, client := setupSCIM(t)
+
+	status, created := client.do(http.MethodPost, "/Users", map[string]interface{}{
+		"schemas":  []string{schemaUser},
+		"userName": "Alice@Example.com",
+		"name":     map[string]string{"givenName": "Alice", "familyName": "Smith"},
+		"active":   true,
+	})
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "alice@example.com", created["userName"])
+	assert.Equal(t, "Alice Smith", created["displayName"])
+	id := created["id"].(string)
+
+	// Повторное создание
+	status, body := client.do(http.MethodPost, "/Users", map[string]interface{}{"userName": "alice@example.com"})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "uniqueness", body["scimType"])
+
+	status, list := client.do(http.MethodGet, "/Users?filter="+url.QueryEscape(`userName eq "alice@example.com"`), nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.EqualValues(t, 1, list["totalResults"])
+
+	// Отключение пользователя через PATCH в стиле Azure AD
+	status, patched := client.do(http.MethodPatch, "/Users/"+id, map[string]interface{}{
+		"schemas":    []string{schemaPatchOp},
+		"Operations": []map[string]interface{}{{"op": "Replace", "value": map[string]interface{}{"active": "False"}}},
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, false, patched["active"])
+
+	user, err := service.GetUserByEmail("alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, string(accessgo.UserTypeBlocked), user.UserType)
+
+	// PUT без active не снимает блокировку
+	status, replaced := client.do(http.MethodPut, "/Users/"+id, map[string]interface{}{
+		"userName": "alice@example.com", "displayName": "Alice S.",
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Alice S.", replaced["displayName"])
+	assert.Equal(t, false, replaced["active"])
+	assert.Equal(t, string(accessgo.UserTypeUser), replaced["userType"])
+
+	status, _ = client.do(http.MethodDelete, "/Users/"+id, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = client.do(http.MethodGet, "/Users/"+id, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestSCIMUserTypeAndActivation(t *testing.T) {
+	service, client := setupSCIM(t)
+
+	status, created := client.do(http.MethodPost, "/Users", map[string]interface{}{
+		"userName": "bob@example.com", "userType": "employee", "active": false,
+	})
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, false, created["active"])
+	assert.Equal(t, "employee", created["userType"])
+	id := created["id"].(string)
+
+	// Активация возвращает исходный тип, а не user
+	status, patched := client.do(http.MethodPatch, "/Users/"+id, map[string]interface{}{
+		"Operations": []map[string]interface{}{{"op": "replace", "path": "active", "value": true}},
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, patched["active"])
+	user, err := service.GetUserByEmail("bob@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, string(accessgo.UserTypeEmployee), user.UserType)
+
+	// PUT без userType не меняет тип
+	status, replaced := client.do(http.MethodPut, "/Users/"+id, map[string]interface{}{"userName": "bob@example.com"})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "employee", replaced["userType"])
+
+	// Тип admin назначается только при EnableAdminProvisioning
+	status, _ = client.do(http.MethodPost, "/Users", map[string]interface{}{"userName": "root@example.com", "userType": "admin"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = client.do(http.MethodPatch, "/Users/"+id, map[string]interface{}{
+		"Operations": []map[string]interface{}{{"op": "replace", "path": "userType", "value": "admin"}},
+	})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	client.handler.(*Handler).EnableAdminProvisioning()
+	status, patched = client.do(http.MethodPatch, "/Users/"+id, map[string]interface{}{
+		"Operations": []map[string]interface{}{{"op": "replace", "path": "userType", "value": "admin"}},
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "admin", patched["userType"])
+}
+
+func TestSCIMExistingAdminIsProtected(t *testing.T) {
+	service, client := setupSCIM(t)
+
+	admin, err := service.CreateUser("root@example.com", "password", "Root", accessgo.UserTypeAdmin)
+	require.NoError(t, err)
+	id := strconv.FormatUint(uint64(admin.ID), 10)
+
+	// Без EnableAdminProvisioning администратора нельзя изменить, понизить, заблокировать или удалить
+	status, _ := client.do(http.MethodPatch, "/Users/"+id, map[string]interface{}{
+		"Operations": []map[string]interface{}{{"op": "replace", "path": "password", "value": "hijacked"}},
+	})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = client.do(http.MethodPut, "/Users/"+id, map[string]interface{}{
+		"userName": "root@example.com", "userType": "user",
+	})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = client.do(http.MethodPatch, "/Users/"+id, map[string]interface{}{
+		"Operations": []map[string]interface{}{{"op": "replace", "path": "active", "value": false}},
+	})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = client.do(http.MethodDelete, "/Users/"+id, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	user, err := service.GetUserByID(admin.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(accessgo.UserTypeAdmin), user.UserType)
+	assert.Equal(t, admin.Password, user.Password)
+
+	client.handler.(*Handler).EnableAdminProvisioning()
+	status, replaced := client.do(http.MethodPut, "/Users/"+id, map[string]interface{}{
+		"userName": "root@example.com", "userType": "user",
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "user", replaced["userType"])
+}
+
+func TestSCIMGroupMembership(t *testing.T) {
+	service, client := setupSCIM(t)
+
+	alice, err := service.CreateUser("alice@example.com", "password", "Alice", accessgo.UserTypeUser)
+	require.NoError(t, err)
+	bob, err := service.CreateUser("bob@example.com", "password", "Bob", accessgo.UserTypeUser)
+	require.NoError(t, err)
+	aliceID := strconv.FormatUint(uint64(alice.ID), 10)
+	bobID := strconv.FormatUint(uint64(bob.ID), 10)
+
+	status, created := client.do(http.MethodPost, "/Groups", map[string]interface{}{
+		"displayName": "Engineering",
+		"members":     []map[string]string{{"value": aliceID}},
+	})
+	require.Equal(t, http.StatusCreated, status)
+	groupID := created["id"].(string)
+
+	status, _ = client.do(http.MethodPatch, "/Groups/"+groupID, map[string]interface{}{
+		"Operations": []map[string]interface{}{
+			{"op": "add", "path": "members", "value": []map[string]string{{"value": bobID}}},
+			{"op": "remove", "path": `members[value eq "` + aliceID + `"]`},
+			{"op": "replace", "path": "displayName", "value": "R&D"},
+		},
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	groups, err := service.GetUserGroups(bob.ID)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "R&D", groups[0].Name)
+	groups, err = service.GetUserGroups(alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	// Пользователь видит свои группы
+	_, user := client.do(http.MethodGet, "/Users/"+bobID, nil)
+	assert.Len(t, user["groups"], 1)
+
+	// PUT задает точный состав
+	status, replaced := client.do(http.MethodPut, "/Groups/"+groupID, map[string]interface{}{
+		"displayName": "R&D",
+		"members":     []map[string]string{{"value": aliceID}},
+	})
+	require.Equal(t, http.StatusOK, status)
+	members := replaced["members"].([]interface{})
+	require.Len(t, members, 1)
+	assert.Equal(t, aliceID, members[0].(map[string]interface{})["value"])
+
+	status, _ = client.do(http.MethodPost, "/Groups", map[string]interface{}{"displayName": "R&D"})
+	assert.Equal(t, http.StatusConflict, status)
+}
+
+func TestSCIMAuthorization(t *testing.T) {
+	_, client := setupSCIM(t, "user:read")
+
+	status, _ := client.do(http.MethodGet, "/Users", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Ключ ограничен user:read
+	status, _ = client.do(http.MethodPost, "/Users", map[string]interface{}{"userName": "eve@example.com"})
+	assert.Equal(t, http.StatusForbidden, status)
+
+	client.token = "ag_invalid"
+	status, _ = client.do(http.MethodGet, "/Users", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,436 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axgrid/accessgo"
+	"github.com/google/uuid"
+)
+
+// userResource - представление пользователя AccessGo в схеме SCIM User.
+// userName соответствует email, displayName и name.formatted - имени пользователя
+type userResource struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *userName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	UserType    string      `json:"userType,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Emails      []userEmail `json:"emails,omitempty"`
+	Password    string      `json:"password,omitempty"`
+	Groups      []memberRef `json:"groups,omitempty"`
+	Meta        *meta       `json:"meta,omitempty"`
+}
+
+type userName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type userEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type memberRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+
+	users, err := h.svc.GetAllUsersWithGroups()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	resources := make([]userResource, 0, len(users))
+	for _, user := range users {
+		resource := newUserResource(&user, user.Groups)
+		if f.match(resource.filterValues) {
+			resources = append(resources, *resource)
+		}
+	}
+	writeList(w, r, resources)
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var resource userResource
+	if err := decodeBody(r, &resource); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	email, name, userType, err := resource.attributes()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	if userType == "" {
+		userType = accessgo.UserTypeUser
+	}
+	if userType == accessgo.UserTypeAdmin && !h.allowAdmins {
+		writeError(w, http.StatusForbidden, "", "userType admin is not allowed")
+		return
+	}
+	if _, err := h.svc.GetUserByEmail(email); err == nil {
+		writeError(w, http.StatusConflict, "uniqueness", "user "+email+" already exists")
+		return
+	}
+
+	password := resource.Password
+	if password == "" {
+		// Пользователи из IdP входят через SSO, локальный пароль им не нужен
+		password = uuid.NewString()
+	}
+	user, err := h.svc.CreateUser(email, password, name, userType)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	// Email подтвержден провайдером, который провижинит пользователя
+	if err := h.svc.ValidateEmail(user.EmailValidationToken); err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	if resource.Active != nil && !*resource.Active {
+		// Блокировка через UpdateUser запоминает исходный тип для активации
+		if _, err := h.svc.UpdateUser(user.ID, user.Email, "", user.Name, accessgo.UserTypeBlocked); err != nil {
+			writeError(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+
+	h.writeUser(w, http.StatusCreated, user.ID)
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+	h.writeUser(w, http.StatusOK, id)
+}
+
+func (h *Handler) replaceUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+	var resource userResource
+	if err := decodeBody(r, &resource); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	h.updateUser(w, user, &resource)
+}
+
+func (h *Handler) patchUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+	var patch patchRequest
+	if err := decodeBody(r, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	groups, err := h.svc.GetUserGroups(user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resource := newUserResource(user, groups)
+	for _, op := range patch.Operations {
+		if err := resource.applyPatch(strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+			writeError(w, http.StatusBadRequest, "invalidPath", err.Error())
+			return
+		}
+	}
+	h.updateUser(w, user, resource)
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+	if baseUserType(user) == accessgo.UserTypeAdmin && !h.allowAdmins {
+		writeError(w, http.StatusForbidden, "", "deleting admin users is not allowed")
+		return
+	}
+	if err := h.svc.DeleteUser(user.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) findUser(w http.ResponseWriter, r *http.Request) (*accessgo.User, bool) {
+	id, ok := parseID(r)
+	if ok {
+		user, err := h.svc.GetUserByID(id)
+		if err == nil {
+			return user, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "", "user not found")
+	return nil, false
+}
+
+func (h *Handler) updateUser(w http.ResponseWriter, user *accessgo.User, resource *userResource) {
+	email, name, userType, err := resource.attributes()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	// Тип хранится отдельно от активности: у заблокированного пользователя
+	// исходный тип в BlockedUserType и восстанавливается при активации
+	active := user.UserType != string(accessgo.UserTypeBlocked)
+	currentType := baseUserType(user)
+	if userType == "" {
+		userType = currentType
+	}
+	// Без EnableAdminProvisioning IdP не может ни назначить тип admin,
+	// ни изменить существующего администратора, включая его пароль и блокировку
+	if currentType == accessgo.UserTypeAdmin && !h.allowAdmins {
+		writeError(w, http.StatusForbidden, "", "modifying admin users is not allowed")
+		return
+	}
+	if userType == accessgo.UserTypeAdmin && !h.allowAdmins {
+		writeError(w, http.StatusForbidden, "", "userType admin is not allowed")
+		return
+	}
+	if resource.Active != nil {
+		active = *resource.Active
+	}
+	if !active {
+		userType = accessgo.UserTypeBlocked
+	}
+	if email != user.Email {
+		if _, err := h.svc.GetUserByEmail(email); err == nil {
+			writeError(w, http.StatusConflict, "uniqueness", "user "+email+" already exists")
+			return
+		}
+	}
+
+	if _, err := h.svc.UpdateUser(user.ID, email, resource.Password, name, userType); err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	h.writeUser(w, http.StatusOK, user.ID)
+}
+
+// baseUserType возвращает тип пользователя без учета блокировки: у заблокированного
+// это сохраненный в BlockedUserType исходный тип
+func baseUserType(user *accessgo.User) accessgo.UserType {
+	if user.UserType != string(accessgo.UserTypeBlocked) {
+		return accessgo.UserType(user.UserType)
+	}
+	if user.BlockedUserType == "" {
+		return accessgo.UserTypeUser
+	}
+	return accessgo.UserType(user.BlockedUserType)
+}
+
+func (h *Handler) writeUser(w http.ResponseWriter, status int, id uint) {
+	user, err := h.svc.GetUserByID(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+	groups, err := h.svc.GetUserGroups(user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	writeJSON(w, status, newUserResource(user, groups))
+}
+
+// newUserResource строит SCIM-представление пользователя по уже загруженным группам
+func newUserResource(user *accessgo.User, groups []accessgo.Group) *userResource {
+	active := user.UserType != string(accessgo.UserTypeBlocked)
+	userType := user.UserType
+	if !active {
+		userType = user.BlockedUserType
+	}
+	resource := &userResource{
+		Schemas:     []string{schemaUser},
+		ID:          strconv.FormatUint(uint64(user.ID), 10),
+		UserName:    user.Email,
+		Name:        &userName{Formatted: user.Name},
+		DisplayName: user.Name,
+		UserType:    userType,
+		Active:      &active,
+		Emails:      []userEmail{{Value: user.Email, Type: "work", Primary: true}},
+		Meta: &meta{
+			ResourceType: "User",
+			Created:      user.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: user.UpdatedAt.UTC().Format(time.RFC3339),
+		},
+	}
+	for _, group := range groups {
+		resource.Groups = append(resource.Groups, memberRef{
+			Value:   strconv.FormatUint(uint64(group.ID), 10),
+			Display: group.Name,
+		})
+	}
+	return resource
+}
+
+// attributes извлекает из ресурса email, имя и тип пользователя AccessGo.
+// Пустой тип означает, что он не передан. Блокировка задается атрибутом active
+func (u *userResource) attributes() (string, string, accessgo.UserType, error) {
+	// userName используется как email, если он им является, иначе берется основной адрес
+	email := u.UserName
+	if !strings.Contains(email, "@") {
+		for _, e := range u.Emails {
+			if email == "" || !strings.Contains(email, "@") || e.Primary {
+				email = e.Value
+			}
+		}
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", "", "", errors.New("userName is required")
+	}
+
+	name := u.DisplayName
+	if name == "" && u.Name != nil {
+		name = u.Name.Formatted
+		if name == "" {
+			name = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+		}
+	}
+	if name == "" {
+		name = email
+	}
+
+	userType := accessgo.UserType(strings.ToLower(u.UserType))
+	switch userType {
+	case accessgo.UserTypeBlocked:
+		userType = ""
+	case "", accessgo.UserTypeAdmin, accessgo.UserTypeEmployee, accessgo.UserTypeUser, accessgo.UserTypeService:
+	default:
+		return "", "", "", errors.New("unsupported userType " + u.UserType)
+	}
+	return email, name, userType, nil
+}
+
+// applyPatch применяет одну операцию PATCH. Без path значение - объект с атрибутами
+func (u *userResource) applyPatch(op, path string, value json.RawMessage) error {
+	if op != "add" && op != "replace" && op != "remove" {
+		return errors.New("unsupported operation " + op)
+	}
+	if path == "" {
+		if op == "remove" {
+			return errors.New("path is required for remove")
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attrs); err != nil {
+			return err
+		}
+		for attr, v := range attrs {
+			if err := u.applyPatch(op, attr, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	attr := strings.ToLower(path)
+	if strings.HasPrefix(attr, "emails") {
+		attr = "emails"
+	}
+	if op == "remove" {
+		switch attr {
+		case "displayname":
+			u.DisplayName = ""
+		case "name", "name.formatted":
+			u.Name = nil
+		case "usertype":
+			u.UserType = ""
+		default:
+			return errors.New("attribute " + path + " can not be removed")
+		}
+		return nil
+	}
+
+	switch attr {
+	case "active":
+		// Некоторые IdP передают булевы значения строкой
+		var active bool
+		if err := json.Unmarshal(value, &active); err != nil {
+			var s string
+			if json.Unmarshal(value, &s) != nil {
+				return err
+			}
+			active = strings.EqualFold(s, "true")
+		}
+		u.Active = &active
+		return nil
+	case "username":
+		return json.Unmarshal(value, &u.UserName)
+	case "displayname":
+		return json.Unmarshal(value, &u.DisplayName)
+	case "usertype":
+		return json.Unmarshal(value, &u.UserType)
+	case "password":
+		return json.Unmarshal(value, &u.Password)
+	case "name":
+		u.DisplayName = ""
+		return json.Unmarshal(value, &u.Name)
+	case "name.formatted":
+		u.DisplayName = ""
+		u.Name = &userName{}
+		return json.Unmarshal(value, &u.Name.Formatted)
+	case "emails":
+		var email string
+		if json.Unmarshal(value, &email) == nil {
+			u.Emails = []userEmail{{Value: email, Primary: true}}
+		} else if err := json.Unmarshal(value, &u.Emails); err != nil {
+			return err
+		}
+		// userName AccessGo и есть email, поэтому он следует за основным адресом
+		u.UserName = ""
+		return nil
+	}
+	return errors.New("unsupported attribute " + path)
+}
+
+func (u *userResource) filterValues(attr string) []string {
+	switch attr {
+	case "id":
+		return []string{u.ID}
+	case "username":
+		return []string{u.UserName}
+	case "displayname", "name.formatted":
+		return []string{u.DisplayName}
+	case "usertype":
+		return []string{u.UserType}
+	case "active":
+		return []string{strconv.FormatBool(*u.Active)}
+	case "emails", "emails.value":
+		values := make([]string, 0, len(u.Emails))
+		for _, e := range u.Emails {
+			values = append(values, e.Value)
+		}
+		return values
+	}
+	return nil
+}
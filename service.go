@@ -1,23 +1,65 @@
 package accessgo
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"sync"
+	"sync/atomic"
 )
 
 // AccessGoService представляет сервис для управления пользователями и группами
 type AccessGoService struct {
-	db *gorm.DB
+	db              *gorm.DB
+	ctx             context.Context
+	authenticators  *sync.Map
+	auditChain      *auditChain
+	events          *eventBus
+	permissionCache *atomic.Pointer[PermissionCache]
+	inTransaction   bool
+	pendingEvents   *[]Event
+	// pendingInvalidations - сбросы кэша прав, повторяемые после фиксации транзакции
+	pendingInvalidations *[]PermissionInvalidation
+}
+
+// ErrNotFound - запрошенной записи нет. Ошибки об отсутствии пользователя, группы
+// или права доступа имеют свой текст, но проверяются errors.Is(err, ErrNotFound)
+var ErrNotFound = errors.New("запись не найдена")
+
+// ErrUserNotFound возвращается, если пользователя с указанным ID или email нет
+var ErrUserNotFound = notFound("пользователь не найден")
+
+// notFoundError - ошибка отсутствия записи со своим текстом, совместимая с ErrNotFound
+type notFoundError struct {
+	message string
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+func (e *notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func notFound(message string) error {
+	return &notFoundError{message: message}
+}
+
+// PasswordAuthenticator проверяет пароль пользователя во внешнем источнике учетных записей
+type PasswordAuthenticator interface {
+	AuthenticatePassword(user *User, password string) error
 }
 
 // NewAccessGoService создает новый экземпляр AccessGoService
 func NewAccessGoService(db *gorm.DB) (*AccessGoService, error) {
-	if err := db.AutoMigrate(&User{}, &Group{}, &Access{}, &AccessLevel{}); err != nil {
+	if err := db.AutoMigrate(&User{}, &Group{}, &Access{}, &AccessLevel{}, &APIKey{}, &Invitation{}, &AuditEvent{}, &AuditChainHead{}, &WebhookSubscription{}); err != nil {
 		return nil, err
 	}
-	res := &AccessGoService{db: db}
+	res := &AccessGoService{db: db, ctx: context.Background(), authenticators: &sync.Map{}, auditChain: &auditChain{}, events: newEventBus(),
+		permissionCache: &atomic.Pointer[PermissionCache]{}}
 	var cnt int64
 	if err := db.Model(&Access{}).Count(&cnt).Error; err != nil {
 		return nil, err
@@ -26,12 +68,93 @@ func NewAccessGoService(db *gorm.DB) (*AccessGoService, error) {
 		if err := res.SetupDefaultPermissions(); err != nil {
 			return nil, err
 		}
+	} else if err := res.ensureDefaultAccess("user:admin"); err != nil {
+		return nil, err
 	}
 	return res, nil
 }
 
+// ensureDefaultAccess создает стандартное право name, добавленное в новой версии,
+// в уже заполненной базе. Право, удаленное администратором, не восстанавливается
+func (s *AccessGoService) ensureDefaultAccess(name string) error {
+	var cnt int64
+	if err := s.db.Unscoped().Model(&Access{}).Where("name = ?", name).Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return nil
+	}
+	for _, perm := range DefaultManifest().Accesses {
+		if perm.Name == name {
+			_, err := s.CreateAccess(perm.Name, perm.Description)
+			return err
+		}
+	}
+	return nil
+}
+
+// WithContext возвращает копию сервиса, работающую в контексте ctx.
+// Из контекста берутся инициатор изменений (ContextWithActor) и идентификатор
+// запроса (ContextWithRequestID) для журнала аудита. Если ctx передан
+// обработчику AddBeforeHook, копия работает в транзакции изменения
+func (s *AccessGoService) WithContext(ctx context.Context) *AccessGoService {
+	if tx, ok := ctx.Value(txContextKey{}).(*AccessGoService); ok && tx.auditChain == s.auditChain {
+		s = tx
+	}
+	clone := *s
+	clone.ctx = ctx
+	clone.db = s.db.WithContext(ctx)
+	return &clone
+}
+
+// transaction выполняет fn в транзакции. Сервис tx работает с транзакцией,
+// поэтому изменения и записи аудита фиксируются вместе. Внешняя транзакция
+// удерживает блокировку цепочки аудита до фиксации, чтобы записи не ссылались
+// на одну и ту же предыдущую запись. Обработчики AddAfterHook и повторный
+// сброс кэша прав выполняются после фиксации внешней транзакции
+func (s *AccessGoService) transaction(fn func(tx *AccessGoService) error) error {
+	if s.inTransaction {
+		return s.db.Transaction(func(db *gorm.DB) error {
+			clone := *s
+			clone.db = db
+			return fn(&clone)
+		})
+	}
+
+	var pending []Event
+	var invalidations []PermissionInvalidation
+	if err := s.auditChain.lock(); err != nil {
+		return err
+	}
+	err := s.db.Transaction(func(db *gorm.DB) error {
+		clone := *s
+		clone.db = db
+		clone.inTransaction = true
+		clone.pendingEvents = &pending
+		clone.pendingInvalidations = &invalidations
+		return fn(&clone)
+	})
+	s.auditChain.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, invalidation := range invalidations {
+		s.invalidatePermissions(invalidation)
+	}
+	for _, event := range pending {
+		s.dispatchEvent(event)
+	}
+	return nil
+}
+
 // CreateUser создает нового пользователя
 func (s *AccessGoService) CreateUser(email, password, name string, userType UserType) (*User, error) {
+	return s.CreateUserCtx(s.ctx, email, password, name, userType)
+}
+
+// CreateUserCtx - CreateUser с контекстом ctx
+func (s *AccessGoService) CreateUserCtx(ctx context.Context, email, password, name string, userType UserType) (*User, error) {
+	s = s.WithContext(ctx)
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
@@ -42,12 +165,24 @@ func (s *AccessGoService) CreateUser(email, password, name string, userType User
 		Password:             string(hashedPassword),
 		Name:                 name,
 		UserType:             string(userType),
+		Source:               string(UserSourceLocal),
 		EmailValidationToken: uuid.NewString(),
 	}
 
-	result := s.db.Create(user)
-	if result.Error != nil {
-		return nil, result.Error
+	err = s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(UserCreated{User: newEventUser(user)}); err != nil {
+			return err
+		}
+		if err := tx.db.Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(UserCreated{User: newEventUser(user)}); err != nil {
+			return err
+		}
+		return tx.audit(AuditUserCreate, AuditTargetUser, user.ID, nil, userSnapshot(user))
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return user, nil
@@ -55,24 +190,62 @@ func (s *AccessGoService) CreateUser(email, password, name string, userType User
 
 // UpdateUser обновляет информацию о пользователе
 func (s *AccessGoService) UpdateUser(userID uint, email, password, name string, userType UserType) (*User, error) {
-	var user User
-	if err := s.db.First(&user, userID).Error; err != nil {
-		return nil, err
-	}
-
-	user.Email = email
-	user.Name = name
-	user.UserType = string(userType)
+	return s.UpdateUserCtx(s.ctx, userID, email, password, name, userType)
+}
 
+// UpdateUserCtx - UpdateUser с контекстом ctx
+func (s *AccessGoService) UpdateUserCtx(ctx context.Context, userID uint, email, password, name string, userType UserType) (*User, error) {
+	s = s.WithContext(ctx)
+	var hashedPassword []byte
 	if password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err != nil {
+		var err error
+		if hashedPassword, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost); err != nil {
 			return nil, err
 		}
-		user.Password = string(hashedPassword)
 	}
+	return s.updateUser(userID, func(user *User) {
+		user.Email = email
+		user.Name = name
+		user.UserType = string(userType)
+		if hashedPassword != nil {
+			user.Password = string(hashedPassword)
+		}
+	})
+}
 
-	if err := s.db.Save(&user).Error; err != nil {
+// updateUser изменяет пользователя функцией update и сохраняет его в транзакции
+// с событием UserUpdated и записью аудита. При блокировке прежний тип
+// запоминается в BlockedUserType
+func (s *AccessGoService) updateUser(userID uint, update func(user *User)) (*User, error) {
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	previous := newEventUser(&user)
+	before := userSnapshot(&user)
+	userType := user.UserType
+	update(&user)
+	switch {
+	case user.UserType != string(UserTypeBlocked):
+		user.BlockedUserType = ""
+	case userType != string(UserTypeBlocked):
+		user.BlockedUserType = userType
+	}
+
+	event := UserUpdated{Before: previous, After: newEventUser(&user)}
+	err := s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Save(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		return tx.audit(AuditUserUpdate, AuditTargetUser, user.ID, before, userSnapshot(&user))
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -81,25 +254,94 @@ func (s *AccessGoService) UpdateUser(userID uint, email, password, name string,
 
 // DeleteUser удаляет пользователя
 func (s *AccessGoService) DeleteUser(userID uint) error {
-	result := s.db.Delete(&User{}, userID)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("пользователь не найден")
+	return s.DeleteUserCtx(s.ctx, userID)
+}
+
+// DeleteUserCtx - DeleteUser с контекстом ctx
+func (s *AccessGoService) DeleteUserCtx(ctx context.Context, userID uint) error {
+	s = s.WithContext(ctx)
+	var user User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
 	}
-	return nil
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(UserDeleted{User: newEventUser(&user)}); err != nil {
+			return err
+		}
+		if err := tx.db.Delete(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(UserDeleted{User: newEventUser(&user)}); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{user.ID}})
+		return tx.audit(AuditUserDelete, AuditTargetUser, user.ID, userSnapshot(&user), nil)
+	})
+}
+
+// userCredentialModels - записи, через которые пользователь входит в систему.
+// Таблиц необязательных сервисов (OIDC, LDAP, ключи доступа, OAuth) может не быть
+var userCredentialModels = []interface{}{&APIKey{}, &Invitation{}, &ExternalIdentity{}, &PasskeyCredential{},
+	&OAuthAuthorizationCode{}, &OAuthToken{}, &OAuthConsent{}}
+
+// restoreUser восстанавливает удаленного пользователя с прежним ID и полями user.
+// Выдачи, членство в группах, API ключи и способы входа, оставшиеся с момента
+// удаления, удаляются: пользователь восстанавливается, как новый
+func (s *AccessGoService) restoreUser(user *User) error {
+	user.DeletedAt = gorm.DeletedAt{}
+	event := UserCreated{User: newEventUser(user)}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Unscoped().Where("user_id = ?", user.ID).Delete(&AccessLevel{}).Error; err != nil {
+			return err
+		}
+		for _, model := range userCredentialModels {
+			if !tx.db.Migrator().HasTable(model) {
+				continue
+			}
+			if err := tx.db.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.db.Model(user).Association("Groups").Clear(); err != nil {
+			return err
+		}
+		if err := tx.db.Unscoped().Omit("Groups", "Accesses").Save(user).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{user.ID}})
+		return tx.audit(AuditUserCreate, AuditTargetUser, user.ID, nil, userSnapshot(user))
+	})
 }
 
 // CreateGroup создает новую группу
 func (s *AccessGoService) CreateGroup(name string) (*Group, error) {
+	return s.CreateGroupCtx(s.ctx, name)
+}
+
+// CreateGroupCtx - CreateGroup с контекстом ctx
+func (s *AccessGoService) CreateGroupCtx(ctx context.Context, name string) (*Group, error) {
+	s = s.WithContext(ctx)
 	group := &Group{
 		Name: name,
 	}
 
-	result := s.db.Create(group)
-	if result.Error != nil {
-		return nil, result.Error
+	err := s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Create(group).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditGroupCreate, AuditTargetGroup, group.ID, nil, groupSnapshot(group))
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return group, nil
@@ -107,6 +349,12 @@ func (s *AccessGoService) CreateGroup(name string) (*Group, error) {
 
 // ValidateEmail проверяет токен валидации email
 func (s *AccessGoService) ValidateEmail(token string) error {
+	return s.ValidateEmailCtx(s.ctx, token)
+}
+
+// ValidateEmailCtx - ValidateEmail с контекстом ctx
+func (s *AccessGoService) ValidateEmailCtx(ctx context.Context, token string) error {
+	s = s.WithContext(ctx)
 	if token == "" {
 		return errors.New("token is required")
 	}
@@ -118,21 +366,48 @@ func (s *AccessGoService) ValidateEmail(token string) error {
 		}
 		return err
 	}
+	previous := newEventUser(&user)
+	before := userSnapshot(&user)
 	user.EmailValidate = true
 	user.EmailValidationToken = ""
-	return s.db.Save(&user).Error
+	event := UserUpdated{Before: previous, After: newEventUser(&user)}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Save(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		return tx.audit(AuditUserValidateEmail, AuditTargetUser, user.ID, before, userSnapshot(&user))
+	})
 }
 
 // UpdateGroup обновляет информацию о группе
 func (s *AccessGoService) UpdateGroup(groupID uint, name string) (*Group, error) {
+	return s.UpdateGroupCtx(s.ctx, groupID, name)
+}
+
+// UpdateGroupCtx - UpdateGroup с контекстом ctx
+func (s *AccessGoService) UpdateGroupCtx(ctx context.Context, groupID uint, name string) (*Group, error) {
+	s = s.WithContext(ctx)
 	var group Group
 	if err := s.db.First(&group, groupID).Error; err != nil {
 		return nil, err
 	}
 
+	before := groupSnapshot(&group)
 	group.Name = name
 
-	if err := s.db.Save(&group).Error; err != nil {
+	err := s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Save(&group).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditGroupUpdate, AuditTargetGroup, group.ID, before, groupSnapshot(&group))
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &group, nil
@@ -140,26 +415,67 @@ func (s *AccessGoService) UpdateGroup(groupID uint, name string) (*Group, error)
 
 // DeleteGroup удаляет группу
 func (s *AccessGoService) DeleteGroup(groupID uint) error {
-	result := s.db.Delete(&Group{}, groupID)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("группа не найдена")
+	return s.DeleteGroupCtx(s.ctx, groupID)
+}
+
+// DeleteGroupCtx - DeleteGroup с контекстом ctx
+func (s *AccessGoService) DeleteGroupCtx(ctx context.Context, groupID uint) error {
+	s = s.WithContext(ctx)
+	var group Group
+	if err := s.db.First(&group, groupID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFound("группа не найдена")
+		}
+		return err
 	}
-	return nil
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Delete(&group).Error; err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{GroupIDs: []uint{group.ID}})
+		return tx.audit(AuditGroupDelete, AuditTargetGroup, group.ID, groupSnapshot(&group), nil)
+	})
+}
+
+// restoreGroup восстанавливает удаленную группу с прежним ID. Права и участники,
+// оставшиеся у нее с момента удаления, не возвращаются: группа пуста, как новая
+func (s *AccessGoService) restoreGroup(group *Group) error {
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Unscoped().Where("group_id = ?", group.ID).Delete(&AccessLevel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.db.Model(group).Association("Users").Clear(); err != nil {
+			return err
+		}
+		if err := tx.db.Unscoped().Model(group).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{GroupIDs: []uint{group.ID}})
+		return tx.audit(AuditGroupCreate, AuditTargetGroup, group.ID, nil, groupSnapshot(group))
+	})
 }
 
 // CreateAccess создает новое право доступа
 func (s *AccessGoService) CreateAccess(name, description string) (*Access, error) {
+	return s.CreateAccessCtx(s.ctx, name, description)
+}
+
+// CreateAccessCtx - CreateAccess с контекстом ctx
+func (s *AccessGoService) CreateAccessCtx(ctx context.Context, name, description string) (*Access, error) {
+	s = s.WithContext(ctx)
 	access := &Access{
 		Name:        name,
 		Description: description,
 	}
 
-	result := s.db.Create(access)
-	if result.Error != nil {
-		return nil, result.Error
+	err := s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Create(access).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditAccessCreate, AuditTargetAccess, access.ID, nil, accessSnapshot(access))
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return access, nil
@@ -167,15 +483,29 @@ func (s *AccessGoService) CreateAccess(name, description string) (*Access, error
 
 // UpdateAccess обновляет информацию о праве доступа
 func (s *AccessGoService) UpdateAccess(accessID uint, name, description string) (*Access, error) {
+	return s.UpdateAccessCtx(s.ctx, accessID, name, description)
+}
+
+// UpdateAccessCtx - UpdateAccess с контекстом ctx
+func (s *AccessGoService) UpdateAccessCtx(ctx context.Context, accessID uint, name, description string) (*Access, error) {
+	s = s.WithContext(ctx)
 	var access Access
 	if err := s.db.First(&access, accessID).Error; err != nil {
 		return nil, err
 	}
 
+	before := accessSnapshot(&access)
 	access.Name = name
 	access.Description = description
 
-	if err := s.db.Save(&access).Error; err != nil {
+	err := s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Save(&access).Error; err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{All: true})
+		return tx.audit(AuditAccessUpdate, AuditTargetAccess, access.ID, before, accessSnapshot(&access))
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -184,22 +514,56 @@ func (s *AccessGoService) UpdateAccess(accessID uint, name, description string)
 
 // DeleteAccess удаляет право доступа
 func (s *AccessGoService) DeleteAccess(accessID uint) error {
-	result := s.db.Delete(&Access{}, accessID)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("право доступа не найдено")
+	return s.DeleteAccessCtx(s.ctx, accessID)
+}
+
+// DeleteAccessCtx - DeleteAccess с контекстом ctx
+func (s *AccessGoService) DeleteAccessCtx(ctx context.Context, accessID uint) error {
+	s = s.WithContext(ctx)
+	var access Access
+	if err := s.db.First(&access, accessID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFound("право доступа не найдено")
+		}
+		return err
 	}
-	return nil
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Delete(&access).Error; err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{All: true})
+		return tx.audit(AuditAccessDelete, AuditTargetAccess, access.ID, accessSnapshot(&access), nil)
+	})
+}
+
+// restoreAccess восстанавливает удаленное право доступа с прежним ID и описанием
+// description. Выдачи, оставшиеся с момента удаления, не возвращаются
+func (s *AccessGoService) restoreAccess(access *Access, description string) error {
+	access.Description = description
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Unscoped().Where("access_id = ?", access.ID).Delete(&AccessLevel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.db.Unscoped().Model(access).Updates(map[string]interface{}{"deleted_at": nil, "description": description}).Error; err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{All: true})
+		return tx.audit(AuditAccessCreate, AuditTargetAccess, access.ID, nil, accessSnapshot(access))
+	})
 }
 
 // GetAccessByName получает право доступа по имени
 func (s *AccessGoService) GetAccessByName(name string) (*Access, error) {
+	return s.GetAccessByNameCtx(s.ctx, name)
+}
+
+// GetAccessByNameCtx - GetAccessByName с контекстом ctx
+func (s *AccessGoService) GetAccessByNameCtx(ctx context.Context, name string) (*Access, error) {
+	s = s.WithContext(ctx)
 	var access Access
 	if err := s.db.Where("name = ?", name).First(&access).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("право доступа не найдено")
+			return nil, notFound("право доступа не найдено")
 		}
 		return nil, err
 	}
@@ -208,6 +572,12 @@ func (s *AccessGoService) GetAccessByName(name string) (*Access, error) {
 
 // ListAccesses возвращает список всех прав доступа
 func (s *AccessGoService) ListAccesses() ([]Access, error) {
+	return s.ListAccessesCtx(s.ctx)
+}
+
+// ListAccessesCtx - ListAccesses с контекстом ctx
+func (s *AccessGoService) ListAccessesCtx(ctx context.Context) ([]Access, error) {
+	s = s.WithContext(ctx)
 	var accesses []Access
 	if err := s.db.Find(&accesses).Error; err != nil {
 		return nil, err
@@ -217,48 +587,85 @@ func (s *AccessGoService) ListAccesses() ([]Access, error) {
 
 // AssignUserToGroup добавляет пользователя в группу
 func (s *AccessGoService) AssignUserToGroup(userID, groupID uint) error {
+	return s.AssignUserToGroupCtx(s.ctx, userID, groupID)
+}
+
+// AssignUserToGroupCtx - AssignUserToGroup с контекстом ctx
+func (s *AccessGoService) AssignUserToGroupCtx(ctx context.Context, userID, groupID uint) error {
+	s = s.WithContext(ctx)
 	var user User
 	if err := s.db.First(&user, userID).Error; err != nil {
-		return errors.New("пользователь не найден")
+		return ErrUserNotFound
 	}
 
 	var group Group
 	if err := s.db.First(&group, groupID).Error; err != nil {
-		return errors.New("группа не найдена")
-	}
-
-	if err := s.db.Model(&user).Association("Groups").Append(&group); err != nil {
-		return err
+		return notFound("группа не найдена")
 	}
 
-	return nil
+	event := GroupMembershipChanged{UserID: user.ID, Added: []uint{group.ID}}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Model(&user).Association("Groups").Append(&group); err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{user.ID}})
+		return tx.audit(AuditGroupMemberAdd, AuditTargetGroup, group.ID, nil, memberSnapshot(&user))
+	})
 }
 
 // ExcludeUserFromGroup удаляет пользователя из группы
 func (s *AccessGoService) ExcludeUserFromGroup(userID, groupID uint) error {
+	return s.ExcludeUserFromGroupCtx(s.ctx, userID, groupID)
+}
+
+// ExcludeUserFromGroupCtx - ExcludeUserFromGroup с контекстом ctx
+func (s *AccessGoService) ExcludeUserFromGroupCtx(ctx context.Context, userID, groupID uint) error {
+	s = s.WithContext(ctx)
 	var user User
 	if err := s.db.First(&user, userID).Error; err != nil {
-		return errors.New("пользователь не найден")
+		return ErrUserNotFound
 	}
 
 	var group Group
 	if err := s.db.First(&group, groupID).Error; err != nil {
-		return errors.New("группа не найдена")
-	}
-
-	if err := s.db.Model(&user).Association("Groups").Delete(&group); err != nil {
-		return err
+		return notFound("группа не найдена")
 	}
 
-	return nil
+	event := GroupMembershipChanged{UserID: user.ID, Removed: []uint{group.ID}}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Model(&user).Association("Groups").Delete(&group); err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{user.ID}})
+		return tx.audit(AuditGroupMemberRemove, AuditTargetGroup, group.ID, memberSnapshot(&user), nil)
+	})
 }
 
 // SetUserGroups устанавливает точный список групп для пользователя
 func (s *AccessGoService) SetUserGroups(userID uint, groupIDs ...uint) error {
+	return s.SetUserGroupsCtx(s.ctx, userID, groupIDs...)
+}
+
+// SetUserGroupsCtx - SetUserGroups с контекстом ctx
+func (s *AccessGoService) SetUserGroupsCtx(ctx context.Context, userID uint, groupIDs ...uint) error {
+	s = s.WithContext(ctx)
 	var user User
-	if err := s.db.First(&user, userID).Error; err != nil {
-		return errors.New("пользователь не найден")
+	if err := s.db.Preload("Groups").First(&user, userID).Error; err != nil {
+		return ErrUserNotFound
 	}
+	before := userGroupsSnapshot(user.Groups)
 
 	var groups []Group
 	if len(groupIDs) > 0 {
@@ -267,22 +674,37 @@ func (s *AccessGoService) SetUserGroups(userID uint, groupIDs ...uint) error {
 		}
 
 		if len(groups) != len(groupIDs) {
-			return errors.New("одна или несколько групп не найдены")
+			return notFound("одна или несколько групп не найдены")
 		}
 	}
 
-	if err := s.db.Model(&user).Association("Groups").Replace(&groups); err != nil {
-		return err
-	}
-
-	return nil
+	event := membershipChange(user.ID, user.Groups, groups)
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Model(&user).Association("Groups").Replace(&groups); err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{user.ID}})
+		return tx.audit(AuditUserGroupsSet, AuditTargetUser, user.ID, before, userGroupsSnapshot(groups))
+	})
 }
 
 // GetUserGroups возвращает список групп пользователя
 func (s *AccessGoService) GetUserGroups(userID uint) ([]Group, error) {
+	return s.GetUserGroupsCtx(s.ctx, userID)
+}
+
+// GetUserGroupsCtx - GetUserGroups с контекстом ctx
+func (s *AccessGoService) GetUserGroupsCtx(ctx context.Context, userID uint) ([]Group, error) {
+	s = s.WithContext(ctx)
 	var user User
 	if err := s.db.Preload("Groups").First(&user, userID).Error; err != nil {
-		return nil, errors.New("пользователь не найден")
+		return nil, ErrUserNotFound
 	}
 
 	return user.Groups, nil
@@ -290,9 +712,15 @@ func (s *AccessGoService) GetUserGroups(userID uint) ([]Group, error) {
 
 // GetGroupUsers возвращает список пользователей в группе
 func (s *AccessGoService) GetGroupUsers(groupID uint) ([]User, error) {
+	return s.GetGroupUsersCtx(s.ctx, groupID)
+}
+
+// GetGroupUsersCtx - GetGroupUsers с контекстом ctx
+func (s *AccessGoService) GetGroupUsersCtx(ctx context.Context, groupID uint) ([]User, error) {
+	s = s.WithContext(ctx)
 	var group Group
 	if err := s.db.Preload("Users").First(&group, groupID).Error; err != nil {
-		return nil, errors.New("группа не найдена")
+		return nil, notFound("группа не найдена")
 	}
 
 	return group.Users, nil
@@ -300,133 +728,209 @@ func (s *AccessGoService) GetGroupUsers(groupID uint) ([]User, error) {
 
 // AddUserAccessLevel добавляет уровень доступа пользователю
 func (s *AccessGoService) AddUserAccessLevel(userID uint, accessName string) error {
+	return s.AddUserAccessLevelCtx(s.ctx, userID, accessName)
+}
+
+// AddUserAccessLevelCtx - AddUserAccessLevel с контекстом ctx
+func (s *AccessGoService) AddUserAccessLevelCtx(ctx context.Context, userID uint, accessName string) error {
+	return s.AddUserAccessLevelWithConditionCtx(ctx, userID, accessName, "")
+}
+
+// AddUserAccessLevelWithCondition добавляет пользователю уровень доступа, действующий
+// при выполнении условия condition (см. CheckUserAccessWithContext). Пустое условие -
+// безусловная выдача
+func (s *AccessGoService) AddUserAccessLevelWithCondition(userID uint, accessName, condition string) error {
+	return s.AddUserAccessLevelWithConditionCtx(s.ctx, userID, accessName, condition)
+}
+
+// AddUserAccessLevelWithConditionCtx - AddUserAccessLevelWithCondition с контекстом ctx
+func (s *AccessGoService) AddUserAccessLevelWithConditionCtx(ctx context.Context, userID uint, accessName, condition string) error {
+	s = s.WithContext(ctx)
+	if condition != "" {
+		if err := ValidateCondition(condition); err != nil {
+			return err
+		}
+	}
 	var user User
 	if err := s.db.First(&user, userID).Error; err != nil {
-		return errors.New("пользователь не найден")
+		return ErrUserNotFound
 	}
 
 	var access Access
 	if err := s.db.Where("name = ?", accessName).First(&access).Error; err != nil {
-		return errors.New("право доступа не найдено")
+		return notFound("право доступа не найдено")
 	}
 
 	accessLevel := AccessLevel{
-		UserID:   &userID,
-		AccessID: access.ID,
-	}
-
-	if err := s.db.Create(&accessLevel).Error; err != nil {
-		return err
+		UserID:    &userID,
+		AccessID:  access.ID,
+		Condition: condition,
 	}
 
-	return nil
+	event := AccessGranted{Access: access.Name, UserID: &userID, Condition: condition}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Create(&accessLevel).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{userID}})
+		return tx.audit(AuditUserAccessGrant, AuditTargetUser, userID, nil, conditionalGrantSnapshot(&access, condition))
+	})
 }
 
 // RemoveUserAccessLevel удаляет уровень доступа у пользователя
 func (s *AccessGoService) RemoveUserAccessLevel(userID uint, accessName string) error {
+	return s.RemoveUserAccessLevelCtx(s.ctx, userID, accessName)
+}
+
+// RemoveUserAccessLevelCtx - RemoveUserAccessLevel с контекстом ctx
+func (s *AccessGoService) RemoveUserAccessLevelCtx(ctx context.Context, userID uint, accessName string) error {
+	s = s.WithContext(ctx)
 	var access Access
 	if err := s.db.Where("name = ?", accessName).First(&access).Error; err != nil {
-		return errors.New("право доступа не найдено")
-	}
-
-	result := s.db.Where("user_id = ? AND access_id = ?", userID, access.ID).Delete(&AccessLevel{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("уровень доступа не найден у пользователя")
+		return notFound("право доступа не найдено")
 	}
 
-	return nil
+	event := AccessRevoked{Access: access.Name, UserID: &userID}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		result := tx.db.Where("user_id = ? AND access_id = ?", userID, access.ID).Delete(&AccessLevel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return notFound("уровень доступа не найден у пользователя")
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{UserIDs: []uint{userID}})
+		return tx.audit(AuditUserAccessRevoke, AuditTargetUser, userID, grantSnapshot(&access), nil)
+	})
 }
 
 // AddGroupAccessLevel добавляет уровень доступа группе
 func (s *AccessGoService) AddGroupAccessLevel(groupID uint, accessName string) error {
+	return s.AddGroupAccessLevelCtx(s.ctx, groupID, accessName)
+}
+
+// AddGroupAccessLevelCtx - AddGroupAccessLevel с контекстом ctx
+func (s *AccessGoService) AddGroupAccessLevelCtx(ctx context.Context, groupID uint, accessName string) error {
+	return s.AddGroupAccessLevelWithConditionCtx(ctx, groupID, accessName, "")
+}
+
+// AddGroupAccessLevelWithCondition добавляет группе уровень доступа, действующий
+// при выполнении условия condition
+func (s *AccessGoService) AddGroupAccessLevelWithCondition(groupID uint, accessName, condition string) error {
+	return s.AddGroupAccessLevelWithConditionCtx(s.ctx, groupID, accessName, condition)
+}
+
+// AddGroupAccessLevelWithConditionCtx - AddGroupAccessLevelWithCondition с контекстом ctx
+func (s *AccessGoService) AddGroupAccessLevelWithConditionCtx(ctx context.Context, groupID uint, accessName, condition string) error {
+	s = s.WithContext(ctx)
+	if condition != "" {
+		if err := ValidateCondition(condition); err != nil {
+			return err
+		}
+	}
 	var group Group
 	if err := s.db.First(&group, groupID).Error; err != nil {
-		return errors.New("группа не найдена")
+		return notFound("группа не найдена")
 	}
 
 	var access Access
 	if err := s.db.Where("name = ?", accessName).First(&access).Error; err != nil {
-		return errors.New("право доступа не найдено")
+		return notFound("право доступа не найдено")
 	}
 
 	accessLevel := AccessLevel{
-		GroupID:  &groupID,
-		AccessID: access.ID,
+		GroupID:   &groupID,
+		AccessID:  access.ID,
+		Condition: condition,
 	}
 
-	if err := s.db.Create(&accessLevel).Error; err != nil {
-		return err
-	}
-
-	return nil
+	event := AccessGranted{Access: access.Name, GroupID: &groupID, Condition: condition}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		if err := tx.db.Create(&accessLevel).Error; err != nil {
+			return err
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{GroupIDs: []uint{groupID}})
+		return tx.audit(AuditGroupAccessGrant, AuditTargetGroup, groupID, nil, conditionalGrantSnapshot(&access, condition))
+	})
 }
 
 // RemoveGroupAccessLevel удаляет уровень доступа у группы
 func (s *AccessGoService) RemoveGroupAccessLevel(groupID uint, accessName string) error {
+	return s.RemoveGroupAccessLevelCtx(s.ctx, groupID, accessName)
+}
+
+// RemoveGroupAccessLevelCtx - RemoveGroupAccessLevel с контекстом ctx
+func (s *AccessGoService) RemoveGroupAccessLevelCtx(ctx context.Context, groupID uint, accessName string) error {
+	s = s.WithContext(ctx)
 	var access Access
 	if err := s.db.Where("name = ?", accessName).First(&access).Error; err != nil {
-		return errors.New("право доступа не найдено")
-	}
-
-	result := s.db.Where("group_id = ? AND access_id = ?", groupID, access.ID).Delete(&AccessLevel{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("уровень доступа не найден у группы")
+		return notFound("право доступа не найдено")
 	}
 
-	return nil
+	event := AccessRevoked{Access: access.Name, GroupID: &groupID}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.beforeEvent(event); err != nil {
+			return err
+		}
+		result := tx.db.Where("group_id = ? AND access_id = ?", groupID, access.ID).Delete(&AccessLevel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return notFound("уровень доступа не найден у группы")
+		}
+		if err := tx.afterEvent(event); err != nil {
+			return err
+		}
+		tx.invalidatePermissions(PermissionInvalidation{GroupIDs: []uint{groupID}})
+		return tx.audit(AuditGroupAccessRevoke, AuditTargetGroup, groupID, grantSnapshot(&access), nil)
+	})
 }
 
 // CheckUserAccess проверяет, имеет ли пользователь указанный уровень доступа
 func (s *AccessGoService) CheckUserAccess(userID uint, accessName string) (bool, error) {
-	var user User
-	if err := s.db.Preload("Accesses.Access").Preload("Groups.Accesses.Access").First(&user, userID).Error; err != nil {
-		return false, errors.New("пользователь не найден")
-	}
-
-	// Проверяем прямые доступы пользователя
-	for _, access := range user.Accesses {
-		if access.Access.Name == accessName {
-			return true, nil
-		}
-	}
+	return s.CheckUserAccessCtx(s.ctx, userID, accessName)
+}
 
-	// Проверяем доступы групп пользователя
-	for _, group := range user.Groups {
-		for _, access := range group.Accesses {
-			if access.Access.Name == accessName {
-				return true, nil
-			}
-		}
+// CheckUserAccessCtx - CheckUserAccess с контекстом ctx
+func (s *AccessGoService) CheckUserAccessCtx(ctx context.Context, userID uint, accessName string) (bool, error) {
+	s = s.WithContext(ctx)
+	permissions, err := s.userPermissions(userID)
+	if err != nil {
+		return false, err
 	}
-
-	return false, nil
+	return permissions[accessName], nil
 }
 
 // GetUserSummaryAccessLevels возвращает все уровни доступа пользователя
 func (s *AccessGoService) GetUserSummaryAccessLevels(userID uint) ([]string, error) {
-	var user User
-	if err := s.db.Preload("Accesses.Access").Preload("Groups.Accesses.Access").First(&user, userID).Error; err != nil {
-		return nil, errors.New("пользователь не найден")
-	}
-
-	accessMap := make(map[string]bool)
-
-	// Добавляем прямые доступы пользователя
-	for _, access := range user.Accesses {
-		accessMap[access.Access.Name] = true
-	}
+	return s.GetUserSummaryAccessLevelsCtx(s.ctx, userID)
+}
 
-	// Добавляем доступы групп пользователя
-	for _, group := range user.Groups {
-		for _, access := range group.Accesses {
-			accessMap[access.Access.Name] = true
-		}
+// GetUserSummaryAccessLevelsCtx - GetUserSummaryAccessLevels с контекстом ctx
+func (s *AccessGoService) GetUserSummaryAccessLevelsCtx(ctx context.Context, userID uint) ([]string, error) {
+	s = s.WithContext(ctx)
+	accessMap, err := s.userPermissions(userID)
+	if err != nil {
+		return nil, err
 	}
 
 	accessList := make([]string, 0, len(accessMap))
@@ -439,9 +943,15 @@ func (s *AccessGoService) GetUserSummaryAccessLevels(userID uint) ([]string, err
 
 // GetGroupAccessLevels возвращает все уровни доступа группы
 func (s *AccessGoService) GetGroupAccessLevels(groupID uint) ([]string, error) {
+	return s.GetGroupAccessLevelsCtx(s.ctx, groupID)
+}
+
+// GetGroupAccessLevelsCtx - GetGroupAccessLevels с контекстом ctx
+func (s *AccessGoService) GetGroupAccessLevelsCtx(ctx context.Context, groupID uint) ([]string, error) {
+	s = s.WithContext(ctx)
 	var group Group
 	if err := s.db.Preload("Accesses.Access").First(&group, groupID).Error; err != nil {
-		return nil, errors.New("группа не найдена")
+		return nil, notFound("группа не найдена")
 	}
 
 	accessList := make([]string, 0, len(group.Accesses))
@@ -454,9 +964,15 @@ func (s *AccessGoService) GetGroupAccessLevels(groupID uint) ([]string, error) {
 
 // GetUserAccessLevels возвращает все уровни доступа пользователя
 func (s *AccessGoService) GetUserAccessLevels(userID uint) ([]string, error) {
+	return s.GetUserAccessLevelsCtx(s.ctx, userID)
+}
+
+// GetUserAccessLevelsCtx - GetUserAccessLevels с контекстом ctx
+func (s *AccessGoService) GetUserAccessLevelsCtx(ctx context.Context, userID uint) ([]string, error) {
+	s = s.WithContext(ctx)
 	var user User
 	if err := s.db.Preload("Accesses.Access").First(&user, userID).Error; err != nil {
-		return nil, errors.New("пользователь не найден")
+		return nil, ErrUserNotFound
 	}
 
 	accessList := make([]string, 0, len(user.Accesses))
@@ -468,36 +984,18 @@ func (s *AccessGoService) GetUserAccessLevels(userID uint) ([]string, error) {
 
 // SetupDefaultPermissions создает стандартные права доступа
 func (s *AccessGoService) SetupDefaultPermissions() error {
-	defaultPermissions := []struct {
-		Name        string
-		Description string
-	}{
-		{"user:create", "Создание пользователя"},
-		{"user:read", "Чтение информации о пользователе"},
-		{"user:update", "Обновление информации о пользователе"},
-		{"user:delete", "Удаление пользователя"},
-		{"group:create", "Создание группы"},
-		{"group:read", "Чтение информации о группе"},
-		{"group:update", "Обновление информации о группе"},
-		{"group:delete", "Удаление группы"},
-		{"access:create", "Создание права доступа"},
-		{"access:read", "Чтение информации о праве доступа"},
-		{"access:update", "Обновление информации о праве доступа"},
-		{"access:delete", "Удаление права доступа"},
-		{"user_access:set", "Установка прав доступа пользователю"},
-		{"group_access:set", "Установка прав доступа группе"},
-	}
-
-	for _, perm := range defaultPermissions {
+	return s.SetupDefaultPermissionsCtx(s.ctx)
+}
+
+// SetupDefaultPermissionsCtx - SetupDefaultPermissions с контекстом ctx
+func (s *AccessGoService) SetupDefaultPermissionsCtx(ctx context.Context) error {
+	s = s.WithContext(ctx)
+	for _, perm := range DefaultManifest().Accesses {
 		existingAccess := &Access{}
 		err := s.db.Where("name = ?", perm.Name).First(existingAccess).Error
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				newAccess := &Access{
-					Name:        perm.Name,
-					Description: perm.Description,
-				}
-				if err := s.db.Create(newAccess).Error; err != nil {
+				if _, err := s.CreateAccess(perm.Name, perm.Description); err != nil {
 					return err
 				}
 			} else {
@@ -511,10 +1009,16 @@ func (s *AccessGoService) SetupDefaultPermissions() error {
 
 // GetUserByEmail возвращает пользователя по email
 func (s *AccessGoService) GetUserByEmail(email string) (*User, error) {
+	return s.GetUserByEmailCtx(s.ctx, email)
+}
+
+// GetUserByEmailCtx - GetUserByEmail с контекстом ctx
+func (s *AccessGoService) GetUserByEmailCtx(ctx context.Context, email string) (*User, error) {
+	s = s.WithContext(ctx)
 	var user User
 	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("пользователь не найден")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -523,15 +1027,30 @@ func (s *AccessGoService) GetUserByEmail(email string) (*User, error) {
 
 // GetUserByID возвращает пользователя по ID
 func (s *AccessGoService) GetUserByID(userID uint) (*User, error) {
+	return s.GetUserByIDCtx(s.ctx, userID)
+}
+
+// GetUserByIDCtx - GetUserByID с контекстом ctx
+func (s *AccessGoService) GetUserByIDCtx(ctx context.Context, userID uint) (*User, error) {
+	s = s.WithContext(ctx)
 	var user User
 	if err := s.db.First(&user, userID).Error; err != nil {
-		return nil, errors.New("пользователь не найден")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
 	}
 	return &user, nil
 }
 
 // GetAllUsers возвращает список всех пользователей
 func (s *AccessGoService) GetAllUsers() ([]User, error) {
+	return s.GetAllUsersCtx(s.ctx)
+}
+
+// GetAllUsersCtx - GetAllUsers с контекстом ctx
+func (s *AccessGoService) GetAllUsersCtx(ctx context.Context) ([]User, error) {
+	s = s.WithContext(ctx)
 	var users []User
 	if err := s.db.Find(&users).Error; err != nil {
 		return nil, err
@@ -539,17 +1058,45 @@ func (s *AccessGoService) GetAllUsers() ([]User, error) {
 	return users, nil
 }
 
+// GetAllUsersWithGroups возвращает всех пользователей вместе с их группами,
+// загружая членство одним запросом
+func (s *AccessGoService) GetAllUsersWithGroups() ([]User, error) {
+	return s.GetAllUsersWithGroupsCtx(s.ctx)
+}
+
+// GetAllUsersWithGroupsCtx - GetAllUsersWithGroups с контекстом ctx
+func (s *AccessGoService) GetAllUsersWithGroupsCtx(ctx context.Context) ([]User, error) {
+	s = s.WithContext(ctx)
+	var users []User
+	if err := s.db.Preload("Groups").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // GetGroupByID возвращает группу по ID
 func (s *AccessGoService) GetGroupByID(groupID uint) (*Group, error) {
+	return s.GetGroupByIDCtx(s.ctx, groupID)
+}
+
+// GetGroupByIDCtx - GetGroupByID с контекстом ctx
+func (s *AccessGoService) GetGroupByIDCtx(ctx context.Context, groupID uint) (*Group, error) {
+	s = s.WithContext(ctx)
 	var group Group
 	if err := s.db.First(&group, groupID).Error; err != nil {
-		return nil, errors.New("группа не найдена")
+		return nil, notFound("группа не найдена")
 	}
 	return &group, nil
 }
 
 // GetAllGroups возвращает список всех групп
 func (s *AccessGoService) GetAllGroups() ([]Group, error) {
+	return s.GetAllGroupsCtx(s.ctx)
+}
+
+// GetAllGroupsCtx - GetAllGroups с контекстом ctx
+func (s *AccessGoService) GetAllGroupsCtx(ctx context.Context) ([]Group, error) {
+	s = s.WithContext(ctx)
 	var groups []Group
 	if err := s.db.Find(&groups).Error; err != nil {
 		return nil, err
@@ -559,36 +1106,69 @@ func (s *AccessGoService) GetAllGroups() ([]Group, error) {
 
 // CreateDefaultAdminUser создает пользователя-администратора с полными правами
 func (s *AccessGoService) CreateDefaultAdminUser(email, password, name string) error {
-	admin, err := s.CreateUser(email, password, name, UserTypeAdmin)
-	if err != nil {
-		return err
-	}
+	return s.CreateDefaultAdminUserCtx(s.ctx, email, password, name)
+}
 
-	var accesses []Access
-	if err := s.db.Find(&accesses).Error; err != nil {
-		return err
-	}
+// CreateDefaultAdminUserCtx - CreateDefaultAdminUser с контекстом ctx
+func (s *AccessGoService) CreateDefaultAdminUserCtx(ctx context.Context, email, password, name string) error {
+	s = s.WithContext(ctx)
+	return s.transaction(func(tx *AccessGoService) error {
+		admin, err := tx.CreateUser(email, password, name, UserTypeAdmin)
+		if err != nil {
+			return err
+		}
 
-	for _, access := range accesses {
-		if err := s.AddUserAccessLevel(admin.ID, access.Name); err != nil {
+		var accesses []Access
+		if err := tx.db.Find(&accesses).Error; err != nil {
 			return err
 		}
-	}
-	return nil
+
+		for _, access := range accesses {
+			if err := tx.AddUserAccessLevel(admin.ID, access.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // AuthenticateUser аутентифицирует пользователя по email и паролю
 func (s *AccessGoService) AuthenticateUser(email, password string) (*User, error) {
+	return s.AuthenticateUserCtx(s.ctx, email, password)
+}
+
+// AuthenticateUserCtx - AuthenticateUser с контекстом ctx
+func (s *AccessGoService) AuthenticateUserCtx(ctx context.Context, email, password string) (*User, error) {
+	s = s.WithContext(ctx)
+	user, err := s.authenticatePassword(email, password)
+	return s.completeLogin(LoginMethodPassword, email, user, err)
+}
+
+func (s *AccessGoService) authenticatePassword(email, password string) (*User, error) {
 	user, err := s.GetUserByEmail(email)
 	if err != nil {
 		return nil, err
 	}
 	if !user.EmailValidate {
-		return nil, errors.New("email не подтвержден")
+		return user, errors.New("email не подтвержден")
+	}
+	if user.UserType == string(UserTypeBlocked) {
+		return user, errors.New("пользователь заблокирован")
+	}
+	if authenticator, ok := s.authenticators.Load(user.Source); ok {
+		if err := authenticator.(PasswordAuthenticator).AuthenticatePassword(user, password); err != nil {
+			return user, errors.New("неверный пароль")
+		}
+		return user, nil
 	}
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, errors.New("неверный пароль")
+		return user, errors.New("неверный пароль")
 	}
 	return user, nil
 }
+
+// RegisterPasswordAuthenticator задает проверку паролей для пользователей из указанного источника
+func (s *AccessGoService) RegisterPasswordAuthenticator(source UserSource, authenticator PasswordAuthenticator) {
+	s.authenticators.Store(string(source), authenticator)
+}
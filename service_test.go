@@ -1,6 +1,7 @@
 package accessgo
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
@@ -8,7 +9,7 @@ import (
 	"testing"
 )
 
-func setupTestDB(t *testing.T) *gorm.DB {
+func setupTestDB(t testing.TB) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
@@ -18,9 +19,15 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	return db
 }
 
+func newTestService(t testing.TB, db *gorm.DB) *AccessGoService {
+	service, err := NewAccessGoService(db)
+	require.NoError(t, err)
+	return service
+}
+
 func TestCreateUser(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	user, err := service.CreateUser("test@example.com", "password", "Test User", UserTypeUser)
 	assert.NoError(t, err)
@@ -32,10 +39,18 @@ func TestCreateUser(t *testing.T) {
 
 func TestCreateAndAuthenticateUser(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	// Создаем пользователя
-	_, err := service.CreateUser("auth@example.com", "password", "Auth User", UserTypeUser)
+	created, err := service.CreateUser("auth@example.com", "password", "Auth User", UserTypeUser)
+	assert.NoError(t, err)
+
+	// Без подтверждения email вход запрещен
+	_, err = service.AuthenticateUser("auth@example.com", "password")
+	assert.Error(t, err)
+
+	// Подтверждаем email
+	err = service.ValidateEmail(created.EmailValidationToken)
 	assert.NoError(t, err)
 
 	// Аутентифицируем пользователя
@@ -51,7 +66,7 @@ func TestCreateAndAuthenticateUser(t *testing.T) {
 
 func TestCreateAndDeleteUser(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	user, err := service.CreateUser("delete@example.com", "password", "Delete User", UserTypeUser)
 	assert.NoError(t, err)
@@ -66,7 +81,7 @@ func TestCreateAndDeleteUser(t *testing.T) {
 
 func TestCreateAndUpdateUser(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	user, err := service.CreateUser("update@example.com", "password", "Update User", UserTypeUser)
 	assert.NoError(t, err)
@@ -80,7 +95,7 @@ func TestCreateAndUpdateUser(t *testing.T) {
 
 func TestCreateGroupAndAssignUser(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	user, err := service.CreateUser("group@example.com", "password", "Group User", UserTypeUser)
 	assert.NoError(t, err)
@@ -95,11 +110,17 @@ func TestCreateGroupAndAssignUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, userGroups, 1)
 	assert.Equal(t, group.ID, userGroups[0].ID)
+
+	// Отсутствие записей проверяется через ErrNotFound, текст ошибки сохраняется
+	err = service.AssignUserToGroup(user.ID, 999)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, "группа не найдена")
+	assert.ErrorIs(t, service.AddUserAccessLevel(user.ID, "unknown"), ErrNotFound)
 }
 
 func TestSetupDefaultPermissionsAndCreateAdmin(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	err := service.SetupDefaultPermissions()
 	assert.NoError(t, err)
@@ -116,9 +137,25 @@ func TestSetupDefaultPermissionsAndCreateAdmin(t *testing.T) {
 	assert.NotEmpty(t, accessLevels)
 }
 
+func TestNewServiceAddsUserAdminAccessToExistingDB(t *testing.T) {
+	db := setupTestDB(t)
+	// База, заполненная версией без права user:admin
+	require.NoError(t, db.Create(&Access{Name: "user:read", Description: "Чтение пользователей"}).Error)
+
+	service := newTestService(t, db)
+	access, err := service.GetAccessByName("user:admin")
+	require.NoError(t, err)
+
+	// Право, удаленное администратором, не восстанавливается при следующем запуске
+	require.NoError(t, service.DeleteAccess(access.ID))
+	service = newTestService(t, db)
+	_, err = service.GetAccessByName("user:admin")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
 func TestAddAndCheckUserAccess(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	user, err := service.CreateUser("access@example.com", "password", "Access User", UserTypeUser)
 	assert.NoError(t, err)
@@ -140,7 +177,7 @@ func TestAddAndCheckUserAccess(t *testing.T) {
 
 func TestAddAndCheckUserGroupAccess(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	user, err := service.CreateUser("access@example.com", "password", "Access User", UserTypeUser)
 	assert.NoError(t, err)
@@ -168,7 +205,7 @@ func TestAddAndCheckUserGroupAccess(t *testing.T) {
 
 func TestGetUserByEmail(t *testing.T) {
 	db := setupTestDB(t)
-	service := NewAccessGoService(db)
+	service := newTestService(t, db)
 
 	// Создаем пользователя
 	email := "getuser@example.com"
@@ -187,4 +224,37 @@ func TestGetUserByEmail(t *testing.T) {
 	_, err = service.GetUserByEmail("nonexistent@example.com")
 	assert.Error(t, err)
 	assert.Equal(t, "пользователь не найден", err.Error())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestContextVariants(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+	admin, err := service.CreateUser("admin@example.com", "password", "Admin", UserTypeAdmin)
+	require.NoError(t, err)
+
+	ctx := ContextWithActor(context.Background(), admin.ID)
+	user, err := service.CreateUserCtx(ctx, "bob@example.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.AddUserAccessLevelCtx(ctx, user.ID, "user:read"))
+
+	allowed, err := service.CheckUserAccessCtx(ctx, user.ID, "user:read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	events, err := service.QueryAuditLogCtx(ctx, AuditQuery{ActorID: &admin.ID})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	// Отмененный контекст прерывает запросы к базе
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = service.GetAllUsersCtx(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = service.CreateGroupCtx(canceled, "never")
+	assert.Error(t, err)
+	groups, err := service.GetAllGroups()
+	require.NoError(t, err)
+	assert.Empty(t, groups)
 }
@@ -11,6 +11,7 @@ import (
 type SessionService struct {
 	sessions sync.Map
 	cancel   context.CancelFunc
+	audit    *AccessGoService
 }
 
 func NewSessionService(ctx context.Context) *SessionService {
@@ -22,6 +23,11 @@ func NewSessionService(ctx context.Context) *SessionService {
 	return ss
 }
 
+// EnableAudit включает запись создания и отзыва сессий в журнал аудита svc
+func (s *SessionService) EnableAudit(svc *AccessGoService) {
+	s.audit = svc
+}
+
 func (s *SessionService) CreateSession(userID int, longTerm bool) (string, error) {
 	sessionID := uuid.NewString()
 
@@ -38,6 +44,12 @@ func (s *SessionService) CreateSession(userID int, longTerm bool) (string, error
 		IsLongTerm: longTerm,
 	}
 
+	if s.audit != nil {
+		if err := s.audit.auditSession(AuditSessionCreate, session); err != nil {
+			return "", err
+		}
+	}
+
 	s.sessions.Store(sessionID, session)
 
 	return sessionID, nil
@@ -56,7 +68,11 @@ func (s *SessionService) GetSession(sessionID string) (Session, error) {
 }
 
 func (s *SessionService) DeleteSession(sessionID string) {
-	s.sessions.Delete(sessionID)
+	sessionValue, ok := s.sessions.LoadAndDelete(sessionID)
+	if ok && s.audit != nil {
+		// Сессия уже отозвана, ошибка записи не должна ее вернуть
+		_ = s.audit.auditSession(AuditSessionRevoke, sessionValue.(Session))
+	}
 }
 
 func (s *SessionService) ExtendSession(sessionID string) error {
@@ -15,6 +15,8 @@ type User struct {
 	Password             string        `gorm:"size:64; not null"`
 	Name                 string        `gorm:"size:255; not null"`
 	UserType             string        `gorm:"size:15;not null"`
+	BlockedUserType      string        `gorm:"size:15"` // тип пользователя до блокировки, для восстановления при разблокировке
+	Source               string        `gorm:"size:32;not null;default:local"`
 	CreatedAt            time.Time     `gorm:"not null"`
 	UpdatedAt            time.Time     `gorm:"not null;index:idx_user_updated_at"`
 	Accesses             []AccessLevel `gorm:"foreignKey:UserID"`
@@ -27,6 +29,9 @@ type Group struct {
 	Name     string        `gorm:"unique;not null"`
 	Accesses []AccessLevel `gorm:"foreignKey:GroupID"`
 	Users    []User        `gorm:"many2many:user_groups;"`
+	// Source - источник группы: пусто для локальных групп, UserSourceLDAP для
+	// созданных синхронизацией LDAP, членство в которых она и отзывает
+	Source string `gorm:"size:15;not null;default:''"`
 }
 
 // Access представляет право доступа
@@ -43,6 +48,9 @@ type AccessLevel struct {
 	UserID   *uint  `gorm:"uniqueIndex:idx_user_group_access"`
 	GroupID  *uint  `gorm:"uniqueIndex:idx_user_group_access"`
 	Access   Access `gorm:"foreignKey:AccessID"`
+	// Condition - условие выдачи (см. CheckUserAccessWithContext). Право с условием
+	// не учитывается в CheckUserAccess
+	Condition string `gorm:"column:access_condition;size:1024;not null;default:''"`
 }
 
 // UserType представляет типы пользователей
@@ -53,8 +61,99 @@ const (
 	UserTypeEmployee UserType = "employee"
 	UserTypeUser     UserType = "user"
 	UserTypeBlocked  UserType = "blocked"
+	UserTypeService  UserType = "service"
 )
 
+// UserSource представляет источник учетной записи пользователя
+type UserSource string
+
+const (
+	UserSourceLocal UserSource = "local"
+	UserSourceLDAP  UserSource = "ldap"
+)
+
+// APIKey представляет персональный API ключ пользователя или сервисного аккаунта
+type APIKey struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index:idx_api_key_user"`
+	User       User   `gorm:"foreignKey:UserID"`
+	Name       string `gorm:"size:255;not null"`
+	Prefix     string `gorm:"size:16;not null;uniqueIndex:idx_api_key_prefix"`
+	SecretHash string `gorm:"size:64;not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	// Scoped - ключ ограничен правами Scopes. Если все они удалены, у ключа нет прав
+	Scoped bool     `gorm:"not null;default:false"`
+	Scopes []Access `gorm:"many2many:api_key_scopes;"`
+}
+
+// Invitation представляет приглашение пользователя, созданного без пароля.
+// В базе хранится только хеш токена
+type Invitation struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index:idx_invitation_user"`
+	User      User      `gorm:"foreignKey:UserID"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex:idx_invitation_token"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+// ExternalIdentity представляет привязку пользователя к учетной записи внешнего провайдера
+type ExternalIdentity struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index:idx_external_identity_user"`
+	Provider    string `gorm:"size:64;not null;uniqueIndex:idx_external_identity"`
+	Subject     string `gorm:"size:255;not null;uniqueIndex:idx_external_identity"`
+	Email       string `gorm:"size:255"`
+	LastLoginAt *time.Time
+}
+
+// OAuthClient представляет стороннее приложение, зарегистрированное в сервере авторизации OAuth2
+type OAuthClient struct {
+	gorm.Model
+	ClientID      string   `gorm:"size:64;not null;uniqueIndex:idx_oauth_client_id"`
+	SecretHash    string   `gorm:"size:64"`
+	Name          string   `gorm:"size:255;not null"`
+	RedirectURIs  string   `gorm:"type:text"`
+	Confidential  bool     `gorm:"not null;default:false"`
+	Trusted       bool     `gorm:"not null;default:false"` // собственное приложение: согласие пользователя не запрашивается
+	ServiceUserID *uint    // пользователь, от имени которого выдаются токены client_credentials
+	Scopes        []Access `gorm:"many2many:oauth_client_scopes;"`
+}
+
+// OAuthAuthorizationCode представляет выданный код авторизации
+type OAuthAuthorizationCode struct {
+	gorm.Model
+	CodeHash            string `gorm:"size:64;not null;uniqueIndex:idx_oauth_code_hash"`
+	ClientID            string `gorm:"size:64;not null"`
+	UserID              uint   `gorm:"not null"`
+	RedirectURI         string `gorm:"type:text"`
+	Scopes              string `gorm:"type:text"`
+	CodeChallenge       string `gorm:"size:128"`
+	CodeChallengeMethod string `gorm:"size:8"`
+	ExpiresAt           time.Time
+}
+
+// OAuthToken представляет выданную пару access/refresh токенов
+type OAuthToken struct {
+	gorm.Model
+	AccessTokenHash  string `gorm:"size:64;not null;uniqueIndex:idx_oauth_access_token"`
+	RefreshTokenHash string `gorm:"size:64;index:idx_oauth_refresh_token"`
+	ClientID         string `gorm:"size:64;not null;index:idx_oauth_token_client"`
+	UserID           uint   `gorm:"not null;index:idx_oauth_token_user"`
+	Scopes           string `gorm:"type:text"`
+	CodeHash         string `gorm:"size:64;index:idx_oauth_token_code"` // код авторизации, по которому выдан токен
+	ExpiresAt        time.Time
+	RefreshExpiresAt *time.Time
+}
+
+// OAuthConsent представляет согласие пользователя на выдачу клиенту токенов с указанными scope
+type OAuthConsent struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_oauth_consent"`
+	ClientID string `gorm:"size:64;not null;uniqueIndex:idx_oauth_consent"`
+	Scopes   string `gorm:"type:text"`
+}
+
 type Session struct {
 	ID         string
 	UserID     int
@@ -62,3 +161,20 @@ type Session struct {
 	ExpiresAt  time.Time
 	IsLongTerm bool
 }
+
+// PasskeyCredential представляет ключ доступа (WebAuthn) пользователя
+type PasskeyCredential struct {
+	gorm.Model
+	UserID          uint   `gorm:"not null;index:idx_passkey_user"`
+	Name            string `gorm:"size:255"`
+	CredentialID    []byte `gorm:"not null;uniqueIndex:idx_passkey_credential_id"`
+	PublicKey       []byte `gorm:"not null"`
+	AttestationType string `gorm:"size:32"`
+	AAGUID          []byte `gorm:"size:16"`
+	Transports      string `gorm:"size:255"`
+	SignCount       uint32 `gorm:"not null;default:0"`
+	CloneWarning    bool   `gorm:"not null;default:false"`
+	BackupEligible  bool   `gorm:"not null;default:false"`
+	BackupState     bool   `gorm:"not null;default:false"`
+	LastUsedAt      *time.Time
+}
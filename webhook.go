@@ -0,0 +1,625 @@
+package accessgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Заголовки запросов подписок на события
+const (
+	WebhookSignatureHeaderName = "X-AccessGo-Signature"
+	WebhookEventHeaderName     = "X-AccessGo-Event"
+	WebhookDeliveryHeaderName  = "X-AccessGo-Delivery"
+)
+
+// WebhookSubscription представляет подписку на события: события типов
+// EventTypes (через запятую, пусто - все) отправляются на URL с подписью Secret
+type WebhookSubscription struct {
+	gorm.Model
+	URL        string `gorm:"size:2048;not null"`
+	Secret     string `gorm:"size:128;not null"`
+	EventTypes string `gorm:"size:1024"`
+	Active     bool   `gorm:"not null;default:true"`
+}
+
+// Types возвращает типы событий подписки. Пустой список означает все события
+func (w *WebhookSubscription) Types() []EventType {
+	if w.EventTypes == "" {
+		return nil
+	}
+	var types []EventType
+	for _, eventType := range strings.Split(w.EventTypes, ",") {
+		types = append(types, EventType(eventType))
+	}
+	return types
+}
+
+// Matches проверяет, подписана ли подписка на события типа eventType
+func (w *WebhookSubscription) Matches(eventType EventType) bool {
+	types := w.Types()
+	return len(types) == 0 || slices.Contains(types, eventType)
+}
+
+// WebhookDeliveryStatus - состояние доставки события подписке
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery - доставка события подписке и журнал ее попыток
+type WebhookDelivery struct {
+	ID             uint                  `gorm:"primarykey"`
+	CreatedAt      time.Time             `gorm:"index:idx_webhook_delivery_subscription"`
+	SubscriptionID uint                  `gorm:"not null;uniqueIndex:idx_webhook_delivery_event;index:idx_webhook_delivery_subscription"`
+	EventID        uint                  `gorm:"not null;uniqueIndex:idx_webhook_delivery_event"`
+	EventType      EventType             `gorm:"size:64;not null"`
+	Payload        json.RawMessage       `gorm:"not null"`
+	Status         WebhookDeliveryStatus `gorm:"size:16;not null;index:idx_webhook_delivery_pending"`
+	Attempts       int                   `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time             `gorm:"not null;index:idx_webhook_delivery_pending"`
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+}
+
+// WebhookDeadLetter - доставка, не выполненная за WebhookConfig.MaxAttempts попыток
+type WebhookDeadLetter struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	DeliveryID     uint            `gorm:"not null;uniqueIndex"`
+	SubscriptionID uint            `gorm:"not null;index"`
+	EventID        uint            `gorm:"not null"`
+	EventType      EventType       `gorm:"size:64;not null"`
+	Payload        json.RawMessage `gorm:"not null"`
+	Attempts       int             `gorm:"not null"`
+	LastStatusCode int
+	LastError      string
+}
+
+// CreateWebhookSubscription создает подписку на события типов eventTypes (без типов - на все).
+// Если secret пуст, он генерируется
+func (s *AccessGoService) CreateWebhookSubscription(url, secret string, eventTypes ...EventType) (*WebhookSubscription, error) {
+	return s.CreateWebhookSubscriptionCtx(s.ctx, url, secret, eventTypes...)
+}
+
+// CreateWebhookSubscriptionCtx - CreateWebhookSubscription с контекстом ctx
+func (s *AccessGoService) CreateWebhookSubscriptionCtx(ctx context.Context, url, secret string, eventTypes ...EventType) (*WebhookSubscription, error) {
+	s = s.WithContext(ctx)
+	types, err := webhookEventTypes(url, eventTypes)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		if secret, err = randomToken(32); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &WebhookSubscription{URL: url, Secret: secret, EventTypes: types, Active: true}
+	err = s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Create(subscription).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditWebhookCreate, AuditTargetWebhook, subscription.ID, nil, webhookSnapshot(subscription))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// UpdateWebhookSubscription изменяет адрес, типы событий и активность подписки
+func (s *AccessGoService) UpdateWebhookSubscription(subscriptionID uint, url string, active bool, eventTypes ...EventType) (*WebhookSubscription, error) {
+	return s.UpdateWebhookSubscriptionCtx(s.ctx, subscriptionID, url, active, eventTypes...)
+}
+
+// UpdateWebhookSubscriptionCtx - UpdateWebhookSubscription с контекстом ctx
+func (s *AccessGoService) UpdateWebhookSubscriptionCtx(ctx context.Context, subscriptionID uint, url string, active bool, eventTypes ...EventType) (*WebhookSubscription, error) {
+	s = s.WithContext(ctx)
+	types, err := webhookEventTypes(url, eventTypes)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := s.GetWebhookSubscription(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	before := webhookSnapshot(subscription)
+
+	subscription.URL = url
+	subscription.EventTypes = types
+	subscription.Active = active
+	err = s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Save(subscription).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditWebhookUpdate, AuditTargetWebhook, subscription.ID, before, webhookSnapshot(subscription))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// DeleteWebhookSubscription удаляет подписку. Недоставленные события ей больше не отправляются
+func (s *AccessGoService) DeleteWebhookSubscription(subscriptionID uint) error {
+	return s.DeleteWebhookSubscriptionCtx(s.ctx, subscriptionID)
+}
+
+// DeleteWebhookSubscriptionCtx - DeleteWebhookSubscription с контекстом ctx
+func (s *AccessGoService) DeleteWebhookSubscriptionCtx(ctx context.Context, subscriptionID uint) error {
+	s = s.WithContext(ctx)
+	subscription, err := s.GetWebhookSubscription(subscriptionID)
+	if err != nil {
+		return err
+	}
+	return s.transaction(func(tx *AccessGoService) error {
+		if err := tx.db.Delete(subscription).Error; err != nil {
+			return err
+		}
+		return tx.audit(AuditWebhookDelete, AuditTargetWebhook, subscription.ID, webhookSnapshot(subscription), nil)
+	})
+}
+
+// GetWebhookSubscription возвращает подписку по ID
+func (s *AccessGoService) GetWebhookSubscription(subscriptionID uint) (*WebhookSubscription, error) {
+	return s.GetWebhookSubscriptionCtx(s.ctx, subscriptionID)
+}
+
+// GetWebhookSubscriptionCtx - GetWebhookSubscription с контекстом ctx
+func (s *AccessGoService) GetWebhookSubscriptionCtx(ctx context.Context, subscriptionID uint) (*WebhookSubscription, error) {
+	s = s.WithContext(ctx)
+	var subscription WebhookSubscription
+	if err := s.db.First(&subscription, subscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("подписка не найдена")
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ListWebhookSubscriptions возвращает все подписки
+func (s *AccessGoService) ListWebhookSubscriptions() ([]WebhookSubscription, error) {
+	return s.ListWebhookSubscriptionsCtx(s.ctx)
+}
+
+// ListWebhookSubscriptionsCtx - ListWebhookSubscriptions с контекстом ctx
+func (s *AccessGoService) ListWebhookSubscriptionsCtx(ctx context.Context) ([]WebhookSubscription, error) {
+	s = s.WithContext(ctx)
+	var subscriptions []WebhookSubscription
+	if err := s.db.Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// webhookEventTypes проверяет адрес и типы событий подписки и возвращает типы через запятую
+func webhookEventTypes(rawURL string, eventTypes []EventType) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", errors.New("неверный URL подписки")
+	}
+	types := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if _, ok := outboxDecoders[eventType]; !ok {
+			return "", fmt.Errorf("неизвестный тип события %q", eventType)
+		}
+		types = append(types, string(eventType))
+	}
+	return strings.Join(types, ","), nil
+}
+
+func webhookSnapshot(subscription *WebhookSubscription) map[string]interface{} {
+	return map[string]interface{}{
+		"url":         subscription.URL,
+		"event_types": subscription.EventTypes,
+		"active":      subscription.Active,
+	}
+}
+
+// WebhookConfig содержит настройки отправки событий подпискам
+type WebhookConfig struct {
+	// Client по умолчанию - клиент с таймаутом 10 секунд, который не подключается
+	// к loopback, частным и link-local адресам, кроме AllowedNetworks. Свой клиент
+	// эти адреса не проверяет
+	Client *http.Client
+	// AllowHTTP разрешает отправку на адреса http://. Без него доставки подпискам
+	// с такими адресами сразу становятся недоставленными
+	AllowHTTP bool
+	// AllowedNetworks - внутренние сети, в которые клиент по умолчанию может отправлять события
+	AllowedNetworks []netip.Prefix
+
+	BatchSize    int           // по умолчанию 100
+	PollInterval time.Duration // по умолчанию 5 секунд
+	MaxAttempts  int           // после них доставка попадает в WebhookDeadLetter, по умолчанию 10
+	MinBackoff   time.Duration // задержка после первой ошибки, по умолчанию 10 секунд
+	MaxBackoff   time.Duration // по умолчанию 1 час
+	// LeaseTimeout - время, на которое доставка закрепляется за экземпляром, по умолчанию 1 минута
+	LeaseTimeout time.Duration
+	// OnError вызывается при ошибке отправки или чтения доставок
+	OnError func(error)
+}
+
+// WebhookService отправляет события подпискам. Он является получателем
+// OutboxRelay: каждое событие раскладывается в доставки подходящим подпискам,
+// которые отправляются и повторяются независимо друг от друга
+type WebhookService struct {
+	svc    *AccessGoService
+	cfg    WebhookConfig
+	wake   chan struct{}
+	cancel context.CancelFunc
+}
+
+// NewWebhookService создает новый экземпляр WebhookService
+func NewWebhookService(svc *AccessGoService, cfg WebhookConfig) (*WebhookService, error) {
+	if err := svc.db.AutoMigrate(&WebhookDelivery{}, &WebhookDeadLetter{}); err != nil {
+		return nil, err
+	}
+	if cfg.Client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// Прокси обошел бы проверку адреса, к которому подключается клиент
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   webhookDialControl(cfg.AllowedNetworks),
+		}).DialContext
+		allowHTTP := cfg.AllowHTTP
+		cfg.Client = &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if !allowHTTP && req.URL.Scheme != "https" {
+					return errors.New("перенаправление без https запрещено")
+				}
+				if len(via) >= 10 {
+					return errors.New("слишком много перенаправлений")
+				}
+				return nil
+			},
+		}
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = 10 * time.Second
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	if cfg.LeaseTimeout == 0 {
+		cfg.LeaseTimeout = time.Minute
+	}
+	return &WebhookService{svc: svc, cfg: cfg, wake: make(chan struct{}, 1)}, nil
+}
+
+// Deliver создает доставки события активным подпискам на его тип.
+// Повторная передача того же события доставки не дублирует
+func (w *WebhookService) Deliver(ctx context.Context, event OutboxEvent) error {
+	db := w.svc.db.WithContext(ctx)
+	var subscriptions []WebhookSubscription
+	if err := db.Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+		return err
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// DeliverPending отправляет готовые доставки и возвращает число успешных
+func (w *WebhookService) DeliverPending(ctx context.Context) (int, error) {
+	db := w.svc.db.WithContext(ctx)
+	now := time.Now()
+	var deliveries []WebhookDelivery
+	if err := db.Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryPending, now).
+		Order("id").Limit(w.cfg.BatchSize).Find(&deliveries).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		// Закрепляем доставку, чтобы другие экземпляры не отправляли ее одновременно
+		claim := db.Model(&WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, WebhookDeliveryPending, now).
+			Update("next_attempt_at", now.Add(w.cfg.LeaseTimeout))
+		if claim.Error != nil {
+			return delivered, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		// Доставки удаленным, отключенным и недопустимым подпискам не повторяются
+		var subscription WebhookSubscription
+		err := db.First(&subscription, delivery.SubscriptionID).Error
+		statusCode, permanent := 0, true
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err = errors.New("подписка удалена")
+		case err != nil:
+			return delivered, err
+		case !subscription.Active:
+			err = errors.New("подписка отключена")
+		case !w.cfg.AllowHTTP && !isHTTPSURL(subscription.URL):
+			err = errors.New("подписка без https запрещена")
+		default:
+			statusCode, err = w.send(ctx, &subscription, &delivery)
+			permanent = false
+		}
+
+		if err := w.finish(db, &delivery, statusCode, err, permanent); err != nil {
+			return delivered, err
+		}
+		if delivery.Status == WebhookDeliveryDelivered {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// send отправляет доставку подписке и возвращает код ответа
+func (w *WebhookService) send(ctx context.Context, subscription *WebhookSubscription, delivery *WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeaderName, string(delivery.EventType))
+	req.Header.Set(WebhookDeliveryHeaderName, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeaderName, SignWebhookPayload(subscription.Secret, time.Now(), delivery.Payload))
+
+	resp, err := w.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("подписчик ответил %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// finish сохраняет результат попытки: доставлено, повтор с задержкой
+// или перенос в WebhookDeadLetter после MaxAttempts попыток, а при permanent -
+// сразу после ошибки
+func (w *WebhookService) finish(db *gorm.DB, delivery *WebhookDelivery, statusCode int, sendErr error, permanent bool) error {
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	if sendErr == nil {
+		now := time.Now()
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		return db.Save(delivery).Error
+	}
+
+	delivery.LastError = sendErr.Error()
+	if w.cfg.OnError != nil {
+		w.cfg.OnError(fmt.Errorf("доставка %d подписке %d: %w", delivery.ID, delivery.SubscriptionID, sendErr))
+	}
+	if !permanent && delivery.Attempts < w.cfg.MaxAttempts {
+		delivery.NextAttemptAt = time.Now().Add(retryBackoff(w.cfg.MinBackoff, w.cfg.MaxBackoff, delivery.Attempts))
+		return db.Save(delivery).Error
+	}
+
+	delivery.Status = WebhookDeliveryDead
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(delivery).Error; err != nil {
+			return err
+		}
+		return tx.Create(&WebhookDeadLetter{
+			DeliveryID:     delivery.ID,
+			SubscriptionID: delivery.SubscriptionID,
+			EventID:        delivery.EventID,
+			EventType:      delivery.EventType,
+			Payload:        delivery.Payload,
+			Attempts:       delivery.Attempts,
+			LastStatusCode: delivery.LastStatusCode,
+			LastError:      delivery.LastError,
+		}).Error
+	})
+}
+
+func isHTTPSURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && parsed.Scheme == "https"
+}
+
+// webhookDialControl запрещает подключение к loopback, частным, link-local
+// и групповым адресам, кроме сетей allowed
+func webhookDialControl(allowed []netip.Prefix) func(network, address string, conn syscall.RawConn) error {
+	return func(network, address string, conn syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		addr := addrPort.Addr().Unmap()
+		for _, prefix := range allowed {
+			if prefix.Contains(addr) {
+				return nil
+			}
+		}
+		if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() || addr.IsMulticast() {
+			return fmt.Errorf("адрес %s запрещен для подписок", addr)
+		}
+		return nil
+	}
+}
+
+// ListDeliveries возвращает последние limit доставок подписки, начиная с новых
+func (w *WebhookService) ListDeliveries(subscriptionID uint, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	query := w.svc.db.Where("subscription_id = ?", subscriptionID).Order("id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ListDeadLetters возвращает недоставленные события, начиная с новых
+func (w *WebhookService) ListDeadLetters() ([]WebhookDeadLetter, error) {
+	var letters []WebhookDeadLetter
+	if err := w.svc.db.Order("id DESC").Find(&letters).Error; err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// RetryDeadLetter возвращает недоставленное событие в очередь с новым счетчиком попыток
+func (w *WebhookService) RetryDeadLetter(deadLetterID uint) error {
+	return w.svc.db.Transaction(func(tx *gorm.DB) error {
+		var letter WebhookDeadLetter
+		if err := tx.First(&letter, deadLetterID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("недоставленное событие не найдено")
+			}
+			return err
+		}
+		if err := tx.Model(&WebhookDelivery{}).Where("id = ?", letter.DeliveryID).Updates(map[string]interface{}{
+			"status":          WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&letter).Error
+	})
+}
+
+// Start запускает отправку доставок до отмены контекста или вызова Stop
+func (w *WebhookService) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	go w.deliveryRoutine(ctx)
+}
+
+// Stop останавливает отправку доставок
+func (w *WebhookService) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+func (w *WebhookService) deliveryRoutine(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			delivered, err := w.DeliverPending(ctx)
+			if err != nil && ctx.Err() == nil && w.cfg.OnError != nil {
+				w.cfg.OnError(err)
+			}
+			if err != nil || delivered < w.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-w.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SignWebhookPayload возвращает значение заголовка X-AccessGo-Signature:
+// "t=<unix время>,v1=<hex HMAC-SHA256 от "<unix время>.<тело>">"
+func SignWebhookPayload(secret string, timestamp time.Time, payload []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + webhookSignature(secret, unix, payload)
+}
+
+// VerifyWebhookSignature проверяет заголовок X-AccessGo-Signature на стороне подписчика.
+// Подпись старше tolerance отклоняется для защиты от повторной отправки
+func VerifyWebhookSignature(secret, header string, payload []byte, tolerance time.Duration) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || signature == "" {
+		return errors.New("неверный формат подписи")
+	}
+	if tolerance > 0 && time.Since(time.Unix(seconds, 0)).Abs() > tolerance {
+		return errors.New("подпись устарела")
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookSignature(secret, unix, payload))) {
+		return errors.New("неверная подпись")
+	}
+	return nil
+}
+
+func webhookSignature(secret, unix string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,276 @@
+package accessgo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver - подписчик, проверяющий подпись входящих запросов
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	if err := VerifyWebhookSignature(r.secret, req.Header.Get(WebhookSignatureHeaderName), body, time.Minute); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+var loopbackNetworks = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+
+	_, err := service.CreateWebhookSubscription("ftp://example.com", "secret")
+	assert.EqualError(t, err, "неверный URL подписки")
+	_, err = service.CreateWebhookSubscription("https://example.com/hook", "secret", "user.exploded")
+	assert.EqualError(t, err, `неизвестный тип события "user.exploded"`)
+
+	subscription, err := service.CreateWebhookSubscription("https://example.com/hook", "", EventUserDeleted, EventUserUpdated)
+	require.NoError(t, err)
+	assert.Len(t, subscription.Secret, 64)
+	assert.True(t, subscription.Active)
+	assert.Equal(t, []EventType{EventUserDeleted, EventUserUpdated}, subscription.Types())
+	assert.True(t, subscription.Matches(EventUserDeleted))
+	assert.False(t, subscription.Matches(EventLoginFailed))
+
+	updated, err := service.UpdateWebhookSubscription(subscription.ID, "https://example.com/v2", false)
+	require.NoError(t, err)
+	assert.False(t, updated.Active)
+	assert.True(t, updated.Matches(EventLoginFailed))
+	assert.Equal(t, subscription.Secret, updated.Secret)
+
+	subscriptions, err := service.ListWebhookSubscriptions()
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, "https://example.com/v2", subscriptions[0].URL)
+
+	require.NoError(t, service.DeleteWebhookSubscription(subscription.ID))
+	assert.EqualError(t, service.DeleteWebhookSubscription(subscription.ID), "подписка не найдена")
+
+	events, err := service.QueryAuditLog(AuditQuery{TargetType: AuditTargetWebhook})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, AuditWebhookDelete, events[0].Action)
+	assert.NotContains(t, events[2].After, subscription.Secret)
+}
+
+func TestWebhookDeliverySigned(t *testing.T) {
+	db := setupTestDB(t)
+	service := newTestService(t, db)
+	receiver := &webhookReceiver{secret: "partner-secret", status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	deprovisioning, err := service.CreateWebhookSubscription(server.URL, "partner-secret", EventUserDeleted)
+	require.NoError(t, err)
+	all, err := service.CreateWebhookSubscription(server.URL, "partner-secret")
+	require.NoError(t, err)
+	disabled, err := service.CreateWebhookSubscription(server.URL+"/disabled", "partner-secret")
+	require.NoError(t, err)
+	_, err = service.UpdateWebhookSubscription(disabled.ID, disabled.URL, false)
+	require.NoError(t, err)
+
+	webhooks, err := NewWebhookService(service, WebhookConfig{AllowHTTP: true, AllowedNetworks: loopbackNetworks})
+	require.NoError(t, err)
+	relay, err := NewOutboxRelay(service, OutboxConfig{Sink: webhooks})
+	require.NoError(t, err)
+
+	user, err := service.CreateUser("bob@partner.com", "password", "Bob", UserTypeUser)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteUser(user.ID))
+	delivered, err := relay.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+
+	// Повторная передача события из outbox не создает новых доставок
+	var event OutboxEvent
+	require.NoError(t, db.Where("type = ?", EventUserDeleted).First(&event).Error)
+	require.NoError(t, webhooks.Deliver(context.Background(), event))
+
+	delivered, err = webhooks.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, delivered)
+
+	receiver.mu.Lock()
+	require.Len(t, receiver.requests, 3)
+	var deleted int
+	for i, req := range receiver.requests {
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.NotEmpty(t, req.Header.Get(WebhookDeliveryHeaderName))
+		if req.Header.Get(WebhookEventHeaderName) == string(EventUserDeleted) {
+			deleted++
+			assert.Contains(t, string(receiver.bodies[i]), "bob@partner.com")
+		}
+	}
+	receiver.mu.Unlock()
+	assert.Equal(t, 2, deleted)
+
+	log, err := webhooks.ListDeliveries(deprovisioning.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	assert.Equal(t, WebhookDeliveryDelivered, log[0].Status)
+	assert.Equal(t, http.StatusOK, log[0].LastStatusCode)
+	assert.Equal(t, 1, log[0].Attempts)
+	assert.NotNil(t, log[0].DeliveredAt)
+
+	log, err = webhooks.ListDeliveries(all.ID, 0)
+	require.NoError(t, err)
+	assert.Len(t, log, 2)
+	log, err = webhooks.ListDeliveries(disabled.ID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, log)
+}
+
+func TestWebhookRetryAndDeadLetter(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	receiver := &webhookReceiver{secret: "partner-secret", status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	subscription, err := service.CreateWebhookSubscription(server.URL, "partner-secret")
+	require.NoError(t, err)
+	var errs []error
+	webhooks, err := NewWebhookService(service, WebhookConfig{
+		AllowHTTP:       true,
+		AllowedNetworks: loopbackNetworks,
+		MaxAttempts:     2,
+		MinBackoff:      time.Millisecond,
+		OnError:         func(err error) { errs = append(errs, err) },
+	})
+	require.NoError(t, err)
+	require.NoError(t, webhooks.Deliver(context.Background(), OutboxEvent{ID: 1, Type: EventLoginFailed, Payload: []byte(`{}`)}))
+
+	delivered, err := webhooks.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+	log, err := webhooks.ListDeliveries(subscription.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	assert.Equal(t, WebhookDeliveryPending, log[0].Status)
+	assert.Equal(t, http.StatusInternalServerError, log[0].LastStatusCode)
+	assert.Equal(t, "подписчик ответил 500 Internal Server Error", log[0].LastError)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = webhooks.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, errs, 2)
+
+	letters, err := webhooks.ListDeadLetters()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, log[0].ID, letters[0].DeliveryID)
+	assert.Equal(t, 2, letters[0].Attempts)
+	assert.Equal(t, EventLoginFailed, letters[0].EventType)
+
+	// Недоставленное событие больше не отправляется
+	delivered, err = webhooks.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+
+	receiver.mu.Lock()
+	receiver.status = http.StatusNoContent
+	receiver.mu.Unlock()
+	require.NoError(t, webhooks.RetryDeadLetter(letters[0].ID))
+	assert.EqualError(t, webhooks.RetryDeadLetter(letters[0].ID), "недоставленное событие не найдено")
+	delivered, err = webhooks.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	letters, err = webhooks.ListDeadLetters()
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+	log, err = webhooks.ListDeliveries(subscription.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookDeliveryDelivered, log[0].Status)
+	assert.Empty(t, log[0].LastError)
+}
+
+func TestWebhookDeliveryRestrictions(t *testing.T) {
+	service := newTestService(t, setupTestDB(t))
+	receiver := &webhookReceiver{secret: "partner-secret", status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	ctx := context.Background()
+
+	// По умолчанию доставки подпискам без https сразу становятся недоставленными
+	plain, err := service.CreateWebhookSubscription(server.URL, "partner-secret")
+	require.NoError(t, err)
+	webhooks, err := NewWebhookService(service, WebhookConfig{})
+	require.NoError(t, err)
+	require.NoError(t, webhooks.Deliver(ctx, OutboxEvent{ID: 1, Type: EventLoginFailed, Payload: []byte(`{}`)}))
+	_, err = webhooks.DeliverPending(ctx)
+	require.NoError(t, err)
+	letters, err := webhooks.ListDeadLetters()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, 1, letters[0].Attempts)
+	assert.Equal(t, "подписка без https запрещена", letters[0].LastError)
+
+	// Клиент по умолчанию не подключается к внутренним адресам
+	webhooks, err = NewWebhookService(service, WebhookConfig{AllowHTTP: true, MinBackoff: time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, webhooks.Deliver(ctx, OutboxEvent{ID: 2, Type: EventLoginFailed, Payload: []byte(`{}`)}))
+	_, err = webhooks.DeliverPending(ctx)
+	require.NoError(t, err)
+	log, err := webhooks.ListDeliveries(plain.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookDeliveryPending, log[0].Status)
+	assert.Contains(t, log[0].LastError, "адрес 127.0.0.1 запрещен для подписок")
+
+	// Доставки удаленной и отключенной подпискам не повторяются
+	disabled, err := service.CreateWebhookSubscription(server.URL+"/disabled", "partner-secret")
+	require.NoError(t, err)
+	require.NoError(t, webhooks.Deliver(ctx, OutboxEvent{ID: 3, Type: EventLoginFailed, Payload: []byte(`{}`)}))
+	require.NoError(t, service.DeleteWebhookSubscription(plain.ID))
+	_, err = service.UpdateWebhookSubscription(disabled.ID, disabled.URL, false)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = webhooks.DeliverPending(ctx)
+	require.NoError(t, err)
+	letters, err = webhooks.ListDeadLetters()
+	require.NoError(t, err)
+	require.Len(t, letters, 4)
+	assert.Equal(t, "подписка отключена", letters[0].LastError)
+	assert.Equal(t, 1, letters[0].Attempts)
+	assert.Equal(t, "подписка удалена", letters[1].LastError)
+	assert.Equal(t, "подписка удалена", letters[2].LastError)
+	assert.Equal(t, 2, letters[2].Attempts)
+
+	receiver.mu.Lock()
+	assert.Empty(t, receiver.requests)
+	receiver.mu.Unlock()
+}
+
+func TestWebhookSignature(t *testing.T) {
+	payload := []byte(`{"id":1}`)
+	header := SignWebhookPayload("secret", time.Now(), payload)
+	assert.NoError(t, VerifyWebhookSignature("secret", header, payload, time.Minute))
+	assert.EqualError(t, VerifyWebhookSignature("other", header, payload, time.Minute), "неверная подпись")
+	assert.EqualError(t, VerifyWebhookSignature("secret", header, []byte(`{"id":2}`), time.Minute), "неверная подпись")
+	assert.EqualError(t, VerifyWebhookSignature("secret", "v1=abc", payload, time.Minute), "неверный формат подписи")
+
+	old := SignWebhookPayload("secret", time.Now().Add(-time.Hour), payload)
+	assert.EqualError(t, VerifyWebhookSignature("secret", old, payload, time.Minute), "подпись устарела")
+	assert.NoError(t, VerifyWebhookSignature("secret", old, payload, 0))
+}